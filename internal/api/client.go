@@ -10,13 +10,34 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/ihildy/magnit-vms-cli/internal/auth"
+	"github.com/ihildy/magnit-vms-cli/internal/redact"
 )
 
 type Client struct {
 	BaseURL string
 	HTTP    *http.Client
+	// Reauth, when set, is called once per request after the server reports
+	// an expired session; it must log in again on the same HTTP client.
+	Reauth func(ctx context.Context) error
+	// Logf, when set, receives verbose diagnostics such as retries.
+	Logf func(format string, args ...any)
+	// Retry controls retries of transient failures; the zero value never
+	// retries.
+	Retry RetryPolicy
+
+	// Fetch bounds concurrent requests made by FetchWeeks.
+	Fetch FetchOptions
+
+	// mu guards xsrfToken, which replaces the caller's token once a re-login
+	// rotated it, and generation, which counts re-logins. reauthMu lets only
+	// one request log in again at a time.
+	mu         sync.Mutex
+	xsrfToken  string
+	generation int
+	reauthMu   sync.Mutex
 }
 
 type Engagement struct {
@@ -93,18 +114,19 @@ func (c *Client) SaveBillingItems(ctx context.Context, payload map[string]any, x
 	}
 
 	endpoint := strings.TrimRight(c.BaseURL, "/") + "/wand2/api/billing/billing-items"
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
-	if err != nil {
-		return SaveBillingItemsResponse{}, fmt.Errorf("build save request: %w", err)
-	}
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Origin", strings.TrimRight(c.BaseURL, "/"))
-	req.Header.Set("Referer", strings.TrimRight(c.BaseURL, "/")+"/wand/app/worker/index.html")
-	req.Header.Set("x-xsrf-token", xsrfToken)
-	c.applyAuthHeader(req)
-
-	resp, err := c.HTTP.Do(req)
+	resp, err := c.do(ctx, "POST "+endpoint, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("build save request: %w", err)
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Origin", strings.TrimRight(c.BaseURL, "/"))
+		req.Header.Set("Referer", strings.TrimRight(c.BaseURL, "/")+"/wand/app/worker/index.html")
+		req.Header.Set("x-xsrf-token", c.currentXSRF(xsrfToken))
+		c.applyAuthHeader(req)
+		return req, nil
+	})
 	if err != nil {
 		return SaveBillingItemsResponse{}, fmt.Errorf("save request failed: %w", err)
 	}
@@ -112,7 +134,7 @@ func (c *Client) SaveBillingItems(ctx context.Context, payload map[string]any, x
 
 	if resp.StatusCode != http.StatusOK {
 		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
-		return SaveBillingItemsResponse{}, fmt.Errorf("save failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+		return SaveBillingItemsResponse{}, fmt.Errorf("save failed with status %d: %s", resp.StatusCode, redact.ErrorBody(resp.Header.Get("Content-Type"), data))
 	}
 
 	var out SaveBillingItemsResponse
@@ -123,14 +145,15 @@ func (c *Client) SaveBillingItems(ctx context.Context, payload map[string]any, x
 }
 
 func (c *Client) getJSON(ctx context.Context, endpoint string, out any) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
-	if err != nil {
-		return fmt.Errorf("build GET request: %w", err)
-	}
-	req.Header.Set("Accept", "application/json")
-	c.applyAuthHeader(req)
-
-	resp, err := c.HTTP.Do(req)
+	resp, err := c.do(ctx, "GET "+endpoint, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, fmt.Errorf("build GET request: %w", err)
+		}
+		req.Header.Set("Accept", "application/json")
+		c.applyAuthHeader(req)
+		return req, nil
+	})
 	if err != nil {
 		return fmt.Errorf("GET %s failed: %w", endpoint, err)
 	}
@@ -138,7 +161,7 @@ func (c *Client) getJSON(ctx context.Context, endpoint string, out any) error {
 
 	if resp.StatusCode != http.StatusOK {
 		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
-		return fmt.Errorf("GET %s returned status %d: %s", endpoint, resp.StatusCode, strings.TrimSpace(string(data)))
+		return fmt.Errorf("GET %s returned status %d: %s", endpoint, resp.StatusCode, redact.ErrorBody(resp.Header.Get("Content-Type"), data))
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FetchOptions bounds FetchWeeks: at most Concurrency requests in flight and,
+// when RateLimit is positive, at most RateLimit requests started per second.
+type FetchOptions struct {
+	Concurrency int
+	RateLimit   float64
+}
+
+// DefaultFetchOptions is used when neither config nor flags override it.
+var DefaultFetchOptions = FetchOptions{Concurrency: 4, RateLimit: 5}
+
+// WeekRequest identifies one weekly timecard; WeekStart is MM/DD/YYYY.
+type WeekRequest struct {
+	EngagementID int64
+	WeekStart    string
+}
+
+// FetchWeeks fetches the metadata for every request using a bounded worker
+// pool and returns it in request order. The first failure cancels the
+// requests still outstanding and is returned.
+func (c *Client) FetchWeeks(ctx context.Context, reqs []WeekRequest) ([]map[string]any, error) {
+	results := make([]map[string]any, len(reqs))
+	if len(reqs) == 0 {
+		return results, nil
+	}
+
+	workers := c.Fetch.Concurrency
+	if workers <= 0 {
+		workers = 1
+	}
+	if workers > len(reqs) {
+		workers = len(reqs)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	limiter := newRateLimiter(c.Fetch.RateLimit)
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := limiter.wait(ctx); err != nil {
+					fail(err)
+					continue
+				}
+				r := reqs[i]
+				metadata, err := c.GetMetadata(ctx, r.EngagementID, r.WeekStart)
+				if err != nil {
+					fail(fmt.Errorf("fetch week of %s (engagement %d): %w", r.WeekStart, r.EngagementID, err))
+					continue
+				}
+				results[i] = metadata
+			}
+		}()
+	}
+
+feed:
+	for i := range reqs {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return &rateLimiter{}
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l.interval == 0 {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.interval)
+	l.mu.Unlock()
+	return sleep(ctx, start.Sub(now))
+}
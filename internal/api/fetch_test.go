@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchWeeksKeepsOrderAndCapsConcurrency(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		// Later weeks answer first so ordering has to come from the fetcher.
+		if r.URL.Query().Get("selectedDate") < "02/16/2026" {
+			time.Sleep(20 * time.Millisecond)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"selectedDate": r.URL.Query().Get("selectedDate")})
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv)
+	client.Fetch = FetchOptions{Concurrency: 2}
+	weeks := []string{"01/19/2026", "01/26/2026", "02/02/2026", "02/09/2026", "02/16/2026", "02/23/2026"}
+	reqs := make([]WeekRequest, len(weeks))
+	for i, w := range weeks {
+		reqs[i] = WeekRequest{EngagementID: 1, WeekStart: w}
+	}
+
+	got, err := client.FetchWeeks(context.Background(), reqs)
+	if err != nil {
+		t.Fatalf("fetch weeks: %v", err)
+	}
+	for i, w := range weeks {
+		if got[i]["selectedDate"] != w {
+			t.Fatalf("result %d is %v, want %s", i, got[i]["selectedDate"], w)
+		}
+	}
+	if p := peak.Load(); p > 2 {
+		t.Fatalf("expected at most 2 requests in flight, saw %d", p)
+	}
+}
+
+func TestFetchWeeksCancelsOutstandingOnError(t *testing.T) {
+	var canceled atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("selectedDate") == "01/26/2026" {
+			http.Error(w, "bad week", http.StatusBadRequest)
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			canceled.Add(1)
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv)
+	client.Fetch = FetchOptions{Concurrency: 3}
+	reqs := []WeekRequest{
+		{EngagementID: 1, WeekStart: "01/19/2026"},
+		{EngagementID: 1, WeekStart: "01/26/2026"},
+		{EngagementID: 1, WeekStart: "02/02/2026"},
+		{EngagementID: 1, WeekStart: "02/09/2026"},
+	}
+
+	start := time.Now()
+	_, err := client.FetchWeeks(context.Background(), reqs)
+	if err == nil || !strings.Contains(err.Error(), "fetch week of 01/26/2026 (engagement 1)") {
+		t.Fatalf("expected the failing week's error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("outstanding requests were not canceled, took %s", elapsed)
+	}
+}
+
+func TestFetchWeeksSharesOneReauth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login.html" {
+			_, _ = w.Write([]byte("<form name=login></form>"))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv)
+	client.Fetch = FetchOptions{Concurrency: 4}
+	var mu sync.Mutex
+	reauths := 0
+	client.Reauth = func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		reauths++
+		u, _ := url.Parse(srv.URL + "/")
+		client.HTTP.Jar.SetCookies(u, []*http.Cookie{{Name: "productionaccess_token", Value: "fresh", Path: "/"}})
+		return nil
+	}
+
+	reqs := make([]WeekRequest, 4)
+	for i := range reqs {
+		reqs[i] = WeekRequest{EngagementID: int64(i + 1), WeekStart: "02/16/2026"}
+	}
+	if _, err := client.FetchWeeks(context.Background(), reqs); err != nil {
+		t.Fatalf("fetch weeks: %v", err)
+	}
+	if reauths != 1 {
+		t.Fatalf("expected one re-login, got %d", reauths)
+	}
+}
+
+func TestRateLimiterSpacesStarts(t *testing.T) {
+	var delays []time.Duration
+	orig := sleep
+	sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	defer func() { sleep = orig }()
+
+	limiter := newRateLimiter(10)
+	for i := 0; i < 3; i++ {
+		if err := limiter.wait(context.Background()); err != nil {
+			t.Fatalf("wait: %v", err)
+		}
+	}
+	if delays[0] > time.Millisecond || delays[2] < 150*time.Millisecond {
+		t.Fatalf("expected starts about 100ms apart, got delays %v", delays)
+	}
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls retries of transient failures. Retries is the number
+// of extra attempts after the first; zero disables retrying.
+type RetryPolicy struct {
+	Retries   int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultRetryPolicy is used when neither config nor flags override it.
+var DefaultRetryPolicy = RetryPolicy{Retries: 3, BaseDelay: 500 * time.Millisecond, MaxDelay: 10 * time.Second}
+
+// sleep waits for d or until ctx is done; tests replace it.
+var sleep = func(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// notSent reports whether err means the request never reached the server
+// (DNS failure or refused/failed dial), which makes even a POST safe to retry.
+func notSent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// Unreachable reports whether err means the server could not be reached at
+// all: a DNS failure, a failed dial or a network timeout.
+func Unreachable(err error) bool {
+	if notSent(err) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// backoff returns the delay before retry n (1-based): exponential from
+// BaseDelay, capped at MaxDelay, with the upper half jittered.
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < n && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// sendWithRetry sends the request built by build, retrying idempotent
+// requests on connection errors and 429/502/503/504, and any request whose
+// connection was never established. Retry-After is honored up to MaxDelay.
+func (c *Client) sendWithRetry(ctx context.Context, label string, build func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := build()
+		if err != nil {
+			return nil, err
+		}
+		idempotent := req.Method == http.MethodGet || req.Method == http.MethodHead
+
+		resp, err := c.HTTP.Do(req)
+		var reason string
+		var delay time.Duration
+		switch {
+		case err != nil:
+			if ctx.Err() != nil || !(idempotent || notSent(err)) {
+				return nil, err
+			}
+			reason = err.Error()
+		case idempotent && retryableStatus(resp.StatusCode):
+			reason = fmt.Sprintf("status %d", resp.StatusCode)
+			if d, ok := retryAfter(resp, time.Now()); ok {
+				delay = d
+			}
+		default:
+			return resp, nil
+		}
+
+		if attempt >= c.Retry.Retries {
+			if err != nil {
+				return nil, err
+			}
+			return resp, nil
+		}
+		if resp != nil {
+			drainAndClose(resp)
+		}
+		if backoff := c.Retry.backoff(attempt + 1); delay < backoff {
+			delay = backoff
+		}
+		if c.Retry.MaxDelay > 0 && delay > c.Retry.MaxDelay {
+			delay = c.Retry.MaxDelay
+		}
+		c.logf("%s: %s, retry %d of %d in %s", label, reason, attempt+1, c.Retry.Retries, delay.Round(time.Millisecond))
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func stubSleep(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var delays []time.Duration
+	orig := sleep
+	sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	t.Cleanup(func() { sleep = orig })
+	return &delays
+}
+
+func TestGetRetriesTransientStatusAndHonorsRetryAfter(t *testing.T) {
+	delays := stubSleep(t)
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case 2:
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			_, _ = w.Write([]byte(`{"billingItemDetails":[]}`))
+		}
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv)
+	client.Retry = RetryPolicy{Retries: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: 5 * time.Second}
+	if _, err := client.GetMetadata(context.Background(), 1, "02/16/2026"); err != nil {
+		t.Fatalf("get metadata: %v", err)
+	}
+	if calls != 3 || len(*delays) != 2 {
+		t.Fatalf("expected 3 calls and 2 sleeps, got %d calls, delays %v", calls, *delays)
+	}
+	if d := (*delays)[0]; d < 50*time.Millisecond || d > 100*time.Millisecond {
+		t.Fatalf("first backoff %s outside jitter range", d)
+	}
+	if (*delays)[1] != 3*time.Second {
+		t.Fatalf("expected Retry-After delay of 3s, got %s", (*delays)[1])
+	}
+}
+
+func TestGetGivesUpAfterRetries(t *testing.T) {
+	delays := stubSleep(t)
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusGatewayTimeout)
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv)
+	client.Retry = RetryPolicy{Retries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	if _, err := client.GetMetadata(context.Background(), 1, "02/16/2026"); err == nil {
+		t.Fatal("expected error after exhausting retries")
+	}
+	if calls != 3 || len(*delays) != 2 {
+		t.Fatalf("expected 3 calls and 2 sleeps, got %d calls, delays %v", calls, *delays)
+	}
+}
+
+func TestSaveIsOnlyRetriedWhenRequestWasNotSent(t *testing.T) {
+	delays := stubSleep(t)
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv)
+	client.Retry = RetryPolicy{Retries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	if _, err := client.SaveBillingItems(context.Background(), map[string]any{}, "xsrf"); err == nil {
+		t.Fatal("expected save error")
+	}
+	if calls != 1 || len(*delays) != 0 {
+		t.Fatalf("POST with a server response must not be retried: %d calls, delays %v", calls, *delays)
+	}
+
+	// A refused connection never reached the server, so the POST is retried.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	client.BaseURL = "http://" + addr
+	client.HTTP = &http.Client{}
+	if _, err := client.SaveBillingItems(context.Background(), map[string]any{}, "xsrf"); err == nil {
+		t.Fatal("expected dial error")
+	}
+	if len(*delays) != 3 {
+		t.Fatalf("expected 3 retries of an unsent POST, got delays %v", *delays)
+	}
+}
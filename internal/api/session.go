@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ihildy/magnit-vms-cli/internal/auth"
+)
+
+// ErrSessionExpired reports a 401/403 or a redirect to the login page.
+var ErrSessionExpired = errors.New("session expired")
+
+// do sends the request built by build. If the server says the session has
+// expired and Reauth is set, it logs in again, refreshes the XSRF token and
+// retries the request once.
+func (c *Client) do(ctx context.Context, label string, build func() (*http.Request, error)) (*http.Response, error) {
+	generation := c.authGeneration()
+	resp, err := c.sendWithRetry(ctx, label, build)
+	if err != nil {
+		return nil, err
+	}
+	if !sessionExpired(resp) {
+		return resp, nil
+	}
+	drainAndClose(resp)
+
+	if c.Reauth == nil {
+		return nil, ErrSessionExpired
+	}
+	if err := c.reauth(ctx, label, resp.StatusCode, generation); err != nil {
+		return nil, err
+	}
+
+	c.logf("%s: retrying after re-login", label)
+	resp, err = c.sendWithRetry(ctx, label, build)
+	if err != nil {
+		return nil, err
+	}
+	if sessionExpired(resp) {
+		drainAndClose(resp)
+		return nil, fmt.Errorf("%w even after re-login", ErrSessionExpired)
+	}
+	return resp, nil
+}
+
+// reauth logs in again unless another request already did so after
+// generation was observed; concurrent requests then share one re-login.
+func (c *Client) reauth(ctx context.Context, label string, status int, generation int) error {
+	c.reauthMu.Lock()
+	defer c.reauthMu.Unlock()
+	if c.authGeneration() != generation {
+		c.logf("%s: session expired (status %d), already logged in again", label, status)
+		return nil
+	}
+
+	c.logf("%s: session expired (status %d), logging in again", label, status)
+	if err := c.Reauth(ctx); err != nil {
+		return fmt.Errorf("%w; re-login failed: %v", ErrSessionExpired, err)
+	}
+	token, tokenErr := auth.ExtractXSRFToken(c.HTTP, c.BaseURL)
+	c.mu.Lock()
+	if tokenErr == nil {
+		c.xsrfToken = token
+	}
+	c.generation++
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Client) authGeneration() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// currentXSRF prefers the token captured after a re-login over the one the
+// caller extracted before it.
+func (c *Client) currentXSRF(callerToken string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.xsrfToken != "" {
+		return c.xsrfToken
+	}
+	return callerToken
+}
+
+func (c *Client) logf(format string, args ...any) {
+	if c.Logf != nil {
+		c.Logf(format, args...)
+	}
+}
+
+// sessionExpired treats 401/403 and requests that ended up on /login.html
+// after redirects as an expired session.
+func sessionExpired(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return true
+	}
+	if resp.Request != nil && resp.Request.URL != nil && strings.EqualFold(resp.Request.URL.Path, "/login.html") {
+		return true
+	}
+	return false
+}
+
+func drainAndClose(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newTestClient(t *testing.T, srv *httptest.Server) *Client {
+	t.Helper()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("create cookie jar: %v", err)
+	}
+	return &Client{BaseURL: srv.URL, HTTP: &http.Client{Transport: srv.Client().Transport, Jar: jar}}
+}
+
+func TestClientReauthenticatesOnceAndRetries(t *testing.T) {
+	var gotXSRF string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login.html" {
+			_, _ = w.Write([]byte("<form name=login></form>"))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			http.Redirect(w, r, "/login.html", http.StatusFound)
+			return
+		}
+		if r.Method == http.MethodPost {
+			gotXSRF = r.Header.Get("x-xsrf-token")
+			_, _ = w.Write([]byte(`{"billingItemId":9}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"billingItemDetails":[]}`))
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv)
+	var reauths int
+	var logs []string
+	client.Logf = func(format string, args ...any) { logs = append(logs, format) }
+	client.Reauth = func(ctx context.Context) error {
+		reauths++
+		u, _ := url.Parse(srv.URL + "/")
+		client.HTTP.Jar.SetCookies(u, []*http.Cookie{
+			{Name: "productionaccess_token", Value: "fresh", Path: "/"},
+			{Name: "XSRF-TOKEN", Value: "new-xsrf", Path: "/"},
+		})
+		return nil
+	}
+
+	if _, err := client.GetMetadata(context.Background(), 1, "02/16/2026"); err != nil {
+		t.Fatalf("get metadata: %v", err)
+	}
+	if reauths != 1 || len(logs) != 2 {
+		t.Fatalf("expected one re-login with two log lines, got %d re-logins, logs %v", reauths, logs)
+	}
+
+	resp, err := client.SaveBillingItems(context.Background(), map[string]any{}, "stale-xsrf")
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if resp.BillingItemID != 9 || gotXSRF != "new-xsrf" {
+		t.Fatalf("save used xsrf %q, response %+v", gotXSRF, resp)
+	}
+}
+
+func TestClientReportsExpiredSessionWithoutReauth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv)
+	_, err := client.GetMetadata(context.Background(), 1, "02/16/2026")
+	if !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("expected ErrSessionExpired, got %v", err)
+	}
+
+	client.Reauth = func(ctx context.Context) error { return nil }
+	_, err = client.GetMetadata(context.Background(), 1, "02/16/2026")
+	if !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("expected ErrSessionExpired after failed retry, got %v", err)
+	}
+}
+
+func TestErrorBodyIsRedacted(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"message":"bad request","accessToken":"leaked-token"}`))
+	}))
+	defer srv.Close()
+
+	_, err := newTestClient(t, srv).GetMetadata(context.Background(), 1, "02/16/2026")
+	if err == nil || strings.Contains(err.Error(), "leaked-token") || !strings.Contains(err.Error(), "bad request") {
+		t.Fatalf("expected a redacted error, got %v", err)
+	}
+}
@@ -11,27 +11,73 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/redact"
 )
 
 type Authenticator struct {
 	BaseURL string
 	Client  *http.Client
+	// MFACode supplies a one-time code when login asks for a second
+	// factor. Nil fails such logins with ErrMFARequired.
+	MFACode func(ctx context.Context) (string, error)
+}
+
+// DefaultTimeout bounds each request when no timeout is configured.
+const DefaultTimeout = 45 * time.Second
+
+// HTTPOptions customizes the client built by NewHTTPClientWithOptions. A nil
+// Transport is built from Network; zero Timeout and empty UserAgent use the
+// defaults.
+type HTTPOptions struct {
+	Transport http.RoundTripper
+	Network   NetworkOptions
+	Timeout   time.Duration
+	UserAgent string
 }
 
 func NewHTTPClient() (*http.Client, error) {
+	return NewHTTPClientWithOptions(HTTPOptions{})
+}
+
+func NewHTTPClientWithOptions(opts HTTPOptions) (*http.Client, error) {
 	jar, err := cookiejar.New(nil)
 	if err != nil {
 		return nil, fmt.Errorf("create cookie jar: %w", err)
 	}
+	transport := opts.Transport
+	if transport == nil {
+		network, err := NewTransport(opts.Network)
+		if err != nil {
+			return nil, err
+		}
+		transport = network
+	}
+	userAgent := strings.TrimSpace(opts.UserAgent)
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
 	return &http.Client{
-		Jar:     jar,
-		Timeout: 45 * time.Second,
+		Jar:       jar,
+		Transport: &userAgentTransport{next: transport, userAgent: userAgent},
+		Timeout:   timeout,
 	}, nil
 }
 
 func (a *Authenticator) Login(ctx context.Context, username, password string) error {
+	_, err := a.LoginUser(ctx, username, password)
+	return err
+}
+
+// LoginUser logs in and returns the current user fetched to validate the
+// session, so callers do not need a second users/current request.
+func (a *Authenticator) LoginUser(ctx context.Context, username, password string) (map[string]any, error) {
 	if username == "" || password == "" {
-		return fmt.Errorf("username and password are required")
+		return nil, fmt.Errorf("username and password are required")
 	}
 
 	form := url.Values{}
@@ -40,31 +86,38 @@ func (a *Authenticator) Login(ctx context.Context, username, password string) er
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(a.BaseURL, "/")+"/login.html", strings.NewReader(form.Encode()))
 	if err != nil {
-		return fmt.Errorf("build login request: %w", err)
+		return nil, fmt.Errorf("build login request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("User-Agent", "magnit-vms-cli/1.0")
 
 	resp, err := a.Client.Do(req)
 	if err != nil {
-		return fmt.Errorf("login request failed: %w", err)
+		return nil, fmt.Errorf("login request failed: %w", err)
 	}
 	defer resp.Body.Close()
 	body, _ := io.ReadAll(io.LimitReader(resp.Body, 256*1024))
 
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf("login failed with status %d", resp.StatusCode)
+		return nil, fmt.Errorf("login failed with status %d", resp.StatusCode)
 	}
 	if err := validateLoginResponse(resp, body); err != nil {
-		return err
+		return nil, err
+	}
+	if isSSORedirect(resp, a.BaseURL) {
+		return nil, ErrSSORequired
+	}
+	if form, ok := findMFAForm(body); ok {
+		if _, _, err := a.submitMFA(ctx, resp.Request.URL, form); err != nil {
+			return nil, err
+		}
 	}
 
-	_, err = a.CurrentUser(ctx)
+	user, err := a.CurrentUser(ctx)
 	if err != nil {
-		return fmt.Errorf("login validation failed: %w", err)
+		return nil, fmt.Errorf("login validation failed: %w", err)
 	}
 
-	return nil
+	return user, nil
 }
 
 func (a *Authenticator) CurrentUser(ctx context.Context) (map[string]any, error) {
@@ -86,7 +139,7 @@ func (a *Authenticator) CurrentUser(ctx context.Context) (map[string]any, error)
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
-		return nil, fmt.Errorf("users/current failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		return nil, fmt.Errorf("users/current failed with status %d: %s", resp.StatusCode, redact.ErrorBody(resp.Header.Get("Content-Type"), body))
 	}
 
 	var out map[string]any
@@ -106,7 +159,7 @@ func validateLoginResponse(resp *http.Response, body []byte) error {
 		if strings.EqualFold(resp.Request.URL.Path, "/login.html") &&
 			bytes.Contains(lowerBody, []byte("name=\"password_login\"")) &&
 			bytes.Contains(lowerBody, []byte("please log in to your account below")) {
-			return fmt.Errorf("login did not establish an authenticated session; verify credentials or whether your account requires interactive SSO/MFA")
+			return fmt.Errorf("login did not establish an authenticated session; verify credentials or whether your account requires interactive SSO/MFA (see `magnit auth mfa` and `magnit auth sso`)")
 		}
 	}
 
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseCookieFile reads cookies for baseURL from a Netscape cookies.txt file
+// (as written by curl and browser export extensions) or a HAR export from the
+// browser's developer tools. Cookies for other hosts and expired cookies are
+// dropped; later cookies with the same name and path replace earlier ones.
+func ParseCookieFile(data []byte, baseURL string) ([]*http.Cookie, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil || base.Hostname() == "" {
+		return nil, fmt.Errorf("invalid base url %q", baseURL)
+	}
+	var cookies []*http.Cookie
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		cookies, err = parseHARCookies(trimmed, base.Hostname())
+	} else {
+		cookies, err = parseNetscapeCookies(data, base.Hostname(), time.Now())
+	}
+	if err != nil {
+		return nil, err
+	}
+	cookies = dedupeCookies(cookies)
+	if len(cookies) == 0 {
+		return nil, fmt.Errorf("no cookies for %s found", base.Hostname())
+	}
+	return cookies, nil
+}
+
+func parseNetscapeCookies(data []byte, host string, now time.Time) ([]*http.Cookie, error) {
+	var cookies []*http.Cookie
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		// curl marks HttpOnly cookies with a prefix on an otherwise
+		// commented-out line.
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("cookies.txt line %d: expected 7 tab-separated fields, got %d", i+1, len(fields))
+		}
+		domain, path, expires, name, value := fields[0], fields[2], fields[4], fields[5], fields[6]
+		if !domainMatches(host, domain) {
+			continue
+		}
+		if unix, err := strconv.ParseInt(expires, 10, 64); err == nil && unix > 0 && time.Unix(unix, 0).Before(now) {
+			continue
+		}
+		cookies = append(cookies, &http.Cookie{Name: name, Value: value, Path: path})
+	}
+	return cookies, nil
+}
+
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				URL     string      `json:"url"`
+				Cookies []harCookie `json:"cookies"`
+			} `json:"request"`
+			Response struct {
+				Cookies []harCookie `json:"cookies"`
+			} `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+type harCookie struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Path   string `json:"path"`
+	Domain string `json:"domain"`
+}
+
+// parseHARCookies collects the cookies sent to and set by host, in request
+// order, so the newest value of each cookie wins.
+func parseHARCookies(data []byte, host string) ([]*http.Cookie, error) {
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("parse HAR: %w", err)
+	}
+	var cookies []*http.Cookie
+	for _, entry := range har.Log.Entries {
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil || !strings.EqualFold(u.Hostname(), host) {
+			continue
+		}
+		for _, c := range append(entry.Request.Cookies, entry.Response.Cookies...) {
+			if c.Name == "" || (c.Domain != "" && !domainMatches(host, c.Domain)) {
+				continue
+			}
+			path := c.Path
+			if path == "" {
+				path = "/"
+			}
+			cookies = append(cookies, &http.Cookie{Name: c.Name, Value: c.Value, Path: path})
+		}
+	}
+	return cookies, nil
+}
+
+func domainMatches(host, domain string) bool {
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+	host = strings.ToLower(host)
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+func dedupeCookies(cookies []*http.Cookie) []*http.Cookie {
+	index := map[string]int{}
+	var out []*http.Cookie
+	for _, c := range cookies {
+		key := c.Name + "\x00" + c.Path
+		if i, ok := index[key]; ok {
+			out[i] = c
+			continue
+		}
+		index[key] = len(out)
+		out = append(out, c)
+	}
+	return out
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCookieFileNetscape(t *testing.T) {
+	data := strings.Join([]string{
+		"# Netscape HTTP Cookie File",
+		"#HttpOnly_.vms.example.com\tTRUE\t/\tTRUE\t0\tproductionaccess_token\ttok-1",
+		"vms.example.com\tFALSE\t/wand\tTRUE\t4102444800\tXSRF-TOKEN\tx%2By",
+		"vms.example.com\tFALSE\t/\tTRUE\t946684800\texpired\tgone",
+		"other.example.com\tFALSE\t/\tFALSE\t0\tforeign\tnope",
+		"",
+	}, "\r\n")
+	cookies, err := ParseCookieFile([]byte(data), "https://vms.example.com")
+	if err != nil {
+		t.Fatalf("parse cookies.txt: %v", err)
+	}
+	if len(cookies) != 2 || cookies[0].Value != "tok-1" || cookies[1].Path != "/wand" {
+		t.Fatalf("unexpected cookies %+v", cookies)
+	}
+
+	if _, err := ParseCookieFile([]byte("vms.example.com\tFALSE\t/\n"), "https://vms.example.com"); err == nil {
+		t.Fatalf("expected an error for a malformed line")
+	}
+}
+
+func TestParseCookieFileHARImportsIntoJar(t *testing.T) {
+	har := `{"log":{"entries":[
+	  {"request":{"url":"https://vms.example.com/login.html","cookies":[]},
+	   "response":{"cookies":[{"name":"productionaccess_token","value":"old","path":"/"}]}},
+	  {"request":{"url":"https://idp.example.net/saml","cookies":[{"name":"idp","value":"x"}]},"response":{"cookies":[]}},
+	  {"request":{"url":"https://vms.example.com/wand2/api/users/current","cookies":[{"name":"productionaccess_token","value":"new"}]},
+	   "response":{"cookies":[{"name":"XSRF-TOKEN","value":"xsrf-1","path":"/wand","domain":"vms.example.com"}]}}
+	]}}`
+	cookies, err := ParseCookieFile([]byte(har), "https://vms.example.com")
+	if err != nil {
+		t.Fatalf("parse HAR: %v", err)
+	}
+	if len(cookies) != 2 {
+		t.Fatalf("unexpected cookies %+v", cookies)
+	}
+
+	client, err := NewHTTPClient()
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	if err := ImportCookies(client, "https://vms.example.com", cookies); err != nil {
+		t.Fatalf("import cookies: %v", err)
+	}
+	if token, err := ExtractAccessToken(client, "https://vms.example.com"); err != nil || token != "new" {
+		t.Fatalf("access token %q, %v", token, err)
+	}
+	if token, err := ExtractXSRFToken(client, "https://vms.example.com"); err != nil || token != "xsrf-1" {
+		t.Fatalf("xsrf token %q, %v", token, err)
+	}
+}
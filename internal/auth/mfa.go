@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrMFARequired means the server asked for a one-time code and no code
+	// source is configured.
+	ErrMFARequired = errors.New("account requires a one-time code; store its TOTP secret with `magnit auth mfa set-totp` or sign in with `magnit auth sso`")
+	// ErrSSORequired means login was handed off to an identity provider the
+	// CLI cannot drive.
+	ErrSSORequired = errors.New("account signs in through SSO; run `magnit auth sso` to import a browser session")
+)
+
+var (
+	formPattern  = regexp.MustCompile(`(?is)<form\b([^>]*)>(.*?)</form>`)
+	inputPattern = regexp.MustCompile(`(?is)<input\b([^>]*)>`)
+	attrPattern  = regexp.MustCompile(`(?is)([a-z_:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	codeNames    = []string{"otp", "totp", "mfa", "passcode", "verification", "code", "token"}
+)
+
+type mfaForm struct {
+	Action    string
+	CodeField string
+	Fields    url.Values
+}
+
+// findMFAForm looks for a form with a visible code-like input such as otp,
+// verificationCode or passcode. Hidden inputs are kept so they are posted
+// back; the password form itself is never treated as a code form.
+func findMFAForm(body []byte) (mfaForm, bool) {
+	for _, form := range formPattern.FindAllSubmatch(body, -1) {
+		found := mfaForm{Action: htmlAttrs(string(form[1]))["action"], Fields: url.Values{}}
+		isLogin := false
+		for _, input := range inputPattern.FindAllSubmatch(form[2], -1) {
+			attrs := htmlAttrs(string(input[1]))
+			name, kind := attrs["name"], strings.ToLower(attrs["type"])
+			switch {
+			case name == "":
+			case name == "password_login":
+				isLogin = true
+			case kind == "hidden":
+				found.Fields.Set(name, attrs["value"])
+			case kind == "submit" || kind == "button" || kind == "checkbox":
+			case found.CodeField == "" && isCodeName(name):
+				found.CodeField = name
+			}
+		}
+		if !isLogin && found.CodeField != "" {
+			return found, true
+		}
+	}
+	return mfaForm{}, false
+}
+
+func isCodeName(name string) bool {
+	lower := strings.ToLower(name)
+	for _, part := range codeNames {
+		if strings.Contains(lower, part) {
+			return true
+		}
+	}
+	return false
+}
+
+func htmlAttrs(tag string) map[string]string {
+	attrs := map[string]string{}
+	for _, m := range attrPattern.FindAllStringSubmatch(tag, -1) {
+		value := m[2] + m[3] + m[4]
+		attrs[strings.ToLower(m[1])] = strings.ReplaceAll(value, "&amp;", "&")
+	}
+	return attrs
+}
+
+func (a *Authenticator) submitMFA(ctx context.Context, pageURL *url.URL, form mfaForm) (*http.Response, []byte, error) {
+	if a.MFACode == nil {
+		return nil, nil, ErrMFARequired
+	}
+	code, err := a.MFACode(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	action, err := pageURL.Parse(form.Action)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse MFA form action: %w", err)
+	}
+	values := url.Values{}
+	for name, v := range form.Fields {
+		values[name] = append([]string(nil), v...)
+	}
+	values.Set(form.CodeField, code)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, action.String(), strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("build MFA request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("MFA request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 256*1024))
+	if resp.StatusCode >= 400 {
+		return nil, nil, fmt.Errorf("MFA verification failed with status %d", resp.StatusCode)
+	}
+	if _, again := findMFAForm(body); again {
+		return nil, nil, errors.New("one-time code was rejected; check the TOTP secret and the system clock")
+	}
+	return resp, body, nil
+}
+
+// isSSORedirect reports whether the login ended on another host, which is
+// how an identity provider takeover looks from the CLI.
+func isSSORedirect(resp *http.Response, baseURL string) bool {
+	if resp == nil || resp.Request == nil || resp.Request.URL == nil {
+		return false
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil || base.Host == "" {
+		return false
+	}
+	return !strings.EqualFold(resp.Request.URL.Host, base.Host)
+}
+
+// ParseCookieHeader parses cookies pasted from a browser: a Cookie request
+// header with or without the "Cookie:" prefix, e.g.
+// "productionaccess_token=abc; XSRF-TOKEN=def".
+func ParseCookieHeader(header string) []*http.Cookie {
+	header = strings.TrimSpace(header)
+	if name, rest, ok := strings.Cut(header, ":"); ok && strings.EqualFold(strings.TrimSpace(name), "cookie") {
+		header = strings.TrimSpace(rest)
+	}
+	var cookies []*http.Cookie
+	for _, part := range strings.Split(header, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			continue
+		}
+		cookies = append(cookies, &http.Cookie{Name: name, Value: strings.Trim(strings.TrimSpace(value), `"`), Path: "/"})
+	}
+	return cookies
+}
+
+// ImportCookies adds cookies for baseURL to the client's jar. Cookies without
+// a path get "/", so they are sent to every endpoint the client calls.
+func ImportCookies(client *http.Client, baseURL string, cookies []*http.Cookie) error {
+	if client == nil || client.Jar == nil {
+		return errors.New("http cookie jar is not configured")
+	}
+	if len(cookies) == 0 {
+		return errors.New("no cookies to import")
+	}
+	for _, c := range cookies {
+		if c.Path == "" {
+			c.Path = "/"
+		}
+	}
+	u, err := url.Parse(strings.TrimRight(baseURL, "/") + "/")
+	if err != nil {
+		return fmt.Errorf("parse base url: %w", err)
+	}
+	client.Jar.SetCookies(u, cookies)
+	return nil
+}
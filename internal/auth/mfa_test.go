@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFindMFAFormSkipsLoginForm(t *testing.T) {
+	page := []byte(`<form method="post" action="/login.html"><input name="username"><input type="password" name="password_login"></form>
+<form method='post' action="verify?step=2&amp;x=1">
+<input type="hidden" name="state" value="abc">
+<input type="text" name="verificationCode">
+<input type="submit" name="go" value="Verify">
+</form>`)
+	form, ok := findMFAForm(page)
+	if !ok {
+		t.Fatalf("expected an MFA form")
+	}
+	if form.Action != "verify?step=2&x=1" || form.CodeField != "verificationCode" || form.Fields.Get("state") != "abc" || form.Fields.Has("go") {
+		t.Fatalf("unexpected form %+v", form)
+	}
+	if _, ok := findMFAForm([]byte(`<form action="/login.html"><input name="username"><input name="password_login"></form>`)); ok {
+		t.Fatalf("login form must not be treated as an MFA form")
+	}
+}
+
+func TestLoginAnswersMFAPrompt(t *testing.T) {
+	var gotCode, gotState string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login.html":
+			fmt.Fprint(w, `<form method="post" action="/mfa/verify"><input type="hidden" name="state" value="s1"><input name="otp"></form>`)
+		case "/mfa/verify":
+			gotCode, gotState = r.FormValue("otp"), r.FormValue("state")
+			http.SetCookie(w, &http.Cookie{Name: "productionaccess_token", Value: "tok", Path: "/"})
+			fmt.Fprint(w, "ok")
+		case "/wand2/api/users/current":
+			fmt.Fprint(w, `{"userId":1}`)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewHTTPClient()
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	authn := &Authenticator{BaseURL: srv.URL, Client: client}
+	if _, err := authn.LoginUser(context.Background(), "me", "pw"); !errors.Is(err, ErrMFARequired) {
+		t.Fatalf("expected ErrMFARequired without a code source, got %v", err)
+	}
+
+	authn.MFACode = func(context.Context) (string, error) { return "123456", nil }
+	if _, err := authn.LoginUser(context.Background(), "me", "pw"); err != nil {
+		t.Fatalf("login with MFA: %v", err)
+	}
+	if gotCode != "123456" || gotState != "s1" {
+		t.Fatalf("MFA form posted code %q state %q", gotCode, gotState)
+	}
+}
+
+func TestParseCookieHeader(t *testing.T) {
+	cookies := ParseCookieHeader(`Cookie: productionaccess_token=abc; XSRF-TOKEN="x%2By"; junk`)
+	if len(cookies) != 2 || cookies[0].Name != "productionaccess_token" || cookies[0].Value != "abc" || cookies[1].Value != "x%2By" {
+		t.Fatalf("unexpected cookies %+v", cookies)
+	}
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Session is a serializable snapshot of the cookies an authenticated client
+// holds for one base URL.
+type Session struct {
+	BaseURL string          `json:"base_url"`
+	SavedAt time.Time       `json:"saved_at"`
+	Cookies []SessionCookie `json:"cookies"`
+}
+
+// SessionCookie is a cookie together with the path it was visible at.
+type SessionCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Path  string `json:"path"`
+}
+
+// ExportSession captures the client's cookies for baseURL. The cookie jar
+// does not expose cookie attributes, so each cookie is recorded with the
+// first lookup path it is visible at, which is enough to send it back to the
+// same endpoints.
+func ExportSession(client *http.Client, baseURL string) (Session, error) {
+	if client == nil || client.Jar == nil {
+		return Session{}, fmt.Errorf("http cookie jar is not configured")
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return Session{}, fmt.Errorf("parse base url: %w", err)
+	}
+
+	s := Session{BaseURL: strings.TrimRight(baseURL, "/"), SavedAt: time.Now().UTC()}
+	seen := map[string]struct{}{}
+	for _, cookieURL := range cookieLookupURLs(u) {
+		for _, c := range client.Jar.Cookies(cookieURL) {
+			if c == nil || c.Name == "" {
+				continue
+			}
+			key := c.Name + "\x00" + c.Value
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			s.Cookies = append(s.Cookies, SessionCookie{Name: c.Name, Value: c.Value, Path: cookieURL.Path})
+		}
+	}
+	if len(s.Cookies) == 0 {
+		return Session{}, fmt.Errorf("no session cookies to save")
+	}
+	return s, nil
+}
+
+// RestoreSession loads a saved session into the client's cookie jar.
+func RestoreSession(client *http.Client, s Session) error {
+	if client == nil || client.Jar == nil {
+		return fmt.Errorf("http cookie jar is not configured")
+	}
+	u, err := url.Parse(s.BaseURL)
+	if err != nil {
+		return fmt.Errorf("parse session base url: %w", err)
+	}
+	for _, c := range s.Cookies {
+		target := *u
+		target.Path = c.Path
+		client.Jar.SetCookies(&target, []*http.Cookie{{Name: c.Name, Value: c.Value, Path: c.Path}})
+	}
+	return nil
+}
+
+// EncodeSession serializes s for the credential store.
+func EncodeSession(s Session) (string, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return "", fmt.Errorf("encode session: %w", err)
+	}
+	return string(data), nil
+}
+
+// DecodeSession parses a session written by EncodeSession.
+func DecodeSession(data string) (Session, error) {
+	var s Session
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return Session{}, fmt.Errorf("decode session: %w", err)
+	}
+	return s, nil
+}
+
+// MatchesBaseURL reports whether the session was captured for baseURL.
+func (s Session) MatchesBaseURL(baseURL string) bool {
+	return strings.EqualFold(strings.TrimRight(s.BaseURL, "/"), strings.TrimRight(baseURL, "/"))
+}
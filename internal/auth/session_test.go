@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"testing"
+)
+
+func TestExportRestoreSessionRoundTrip(t *testing.T) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("create cookie jar: %v", err)
+	}
+	client := &http.Client{Jar: jar}
+	baseURL := "https://example.com"
+	u, _ := url.Parse(baseURL + "/wand2/api/users/current")
+	jar.SetCookies(u, []*http.Cookie{{Name: "productionaccess_token", Value: "abc123", Path: "/wand2"}})
+	root, _ := url.Parse(baseURL + "/")
+	jar.SetCookies(root, []*http.Cookie{{Name: "XSRF-TOKEN", Value: "x%2By", Path: "/"}})
+
+	session, err := ExportSession(client, baseURL)
+	if err != nil {
+		t.Fatalf("export session: %v", err)
+	}
+	encoded, err := EncodeSession(session)
+	if err != nil {
+		t.Fatalf("encode session: %v", err)
+	}
+	decoded, err := DecodeSession(encoded)
+	if err != nil {
+		t.Fatalf("decode session: %v", err)
+	}
+	if !decoded.MatchesBaseURL(baseURL+"/") || decoded.MatchesBaseURL("https://other.example.com") {
+		t.Fatalf("unexpected base URL match for %q", decoded.BaseURL)
+	}
+
+	freshJar, _ := cookiejar.New(nil)
+	fresh := &http.Client{Jar: freshJar}
+	if err := RestoreSession(fresh, decoded); err != nil {
+		t.Fatalf("restore session: %v", err)
+	}
+	token, err := ExtractAccessToken(fresh, baseURL)
+	if err != nil || token != "abc123" {
+		t.Fatalf("access token after restore = %q, %v", token, err)
+	}
+	xsrf, err := ExtractXSRFToken(fresh, baseURL)
+	if err != nil || xsrf != "x+y" {
+		t.Fatalf("xsrf token after restore = %q, %v", xsrf, err)
+	}
+}
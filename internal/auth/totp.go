@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	totpDigits = 6
+	totpPeriod = 30 * time.Second
+)
+
+// NormalizeTOTPSecret accepts a base32 secret as shown by authenticator
+// setup screens (any case, with spaces or dashes) or an otpauth:// URI and
+// returns the bare upper-case secret. Only the common SHA1, 6 digit, 30
+// second variant is supported.
+func NormalizeTOTPSecret(input string) (string, error) {
+	secret := strings.TrimSpace(input)
+	if strings.HasPrefix(strings.ToLower(secret), "otpauth://") {
+		u, err := url.Parse(secret)
+		if err != nil {
+			return "", fmt.Errorf("parse otpauth URI: %w", err)
+		}
+		q := u.Query()
+		if alg := q.Get("algorithm"); alg != "" && !strings.EqualFold(alg, "SHA1") {
+			return "", fmt.Errorf("unsupported TOTP algorithm %q (only SHA1)", alg)
+		}
+		if digits := q.Get("digits"); digits != "" && digits != "6" {
+			return "", fmt.Errorf("unsupported TOTP digits %q (only 6)", digits)
+		}
+		if period := q.Get("period"); period != "" && period != "30" {
+			return "", fmt.Errorf("unsupported TOTP period %q (only 30)", period)
+		}
+		secret = q.Get("secret")
+	}
+	secret = strings.ToUpper(strings.NewReplacer(" ", "", "-", "", "=", "").Replace(secret))
+	if secret == "" {
+		return "", fmt.Errorf("TOTP secret is empty")
+	}
+	if _, err := decodeTOTPSecret(secret); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// TOTP returns the RFC 6238 code for secret at the given time.
+func TOTP(secret string, at time.Time) (string, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(at.Unix()/int64(totpPeriod/time.Second)))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1_000_000), nil
+}
+
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(strings.ToUpper(secret), "="))
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("TOTP secret is not valid base32")
+	}
+	return key, nil
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTOTPMatchesRFC6238Vectors(t *testing.T) {
+	// base32 of the RFC 6238 SHA1 key "12345678901234567890"; the RFC lists
+	// 8-digit codes, whose last 6 digits are the 6-digit codes.
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range vectors {
+		got, err := TOTP(secret, time.Unix(unix, 0))
+		if err != nil {
+			t.Fatalf("TOTP at %d: %v", unix, err)
+		}
+		if got != want {
+			t.Fatalf("TOTP at %d: got %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestNormalizeTOTPSecret(t *testing.T) {
+	for _, input := range []string{
+		"gezd gnbv gy3t qojq gezd gnbv gy3t qojq",
+		"otpauth://totp/VMS:worker?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&issuer=VMS&digits=6",
+	} {
+		got, err := NormalizeTOTPSecret(input)
+		if err != nil || got != "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" {
+			t.Fatalf("normalize %q: %q, %v", input, got, err)
+		}
+	}
+	for _, input := range []string{"", "not-base32!", "otpauth://totp/x?secret=GEZDGNBV&algorithm=SHA256"} {
+		if _, err := NormalizeTOTPSecret(input); err == nil {
+			t.Fatalf("expected an error for %q", input)
+		}
+	}
+}
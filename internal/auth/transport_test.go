@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestNewTransportTrustsCAFile(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	if _, err := (&http.Client{Transport: mustTransport(t, NetworkOptions{})}).Get(srv.URL); err == nil {
+		t.Fatalf("expected the test server's certificate to be untrusted by default")
+	}
+
+	caFile := writePEM(t, "ca.pem", "CERTIFICATE", srv.Certificate().Raw)
+	resp, err := (&http.Client{Transport: mustTransport(t, NetworkOptions{CAFile: caFile})}).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request with CA file: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestNewTransportPresentsClientCertificate(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "worker"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	certFile := writePEM(t, "client.pem", "CERTIFICATE", certDER)
+	keyFile := writePEM(t, "client-key.pem", "EC PRIVATE KEY", keyDER)
+
+	var subject string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subject = r.TLS.PeerCertificates[0].Subject.CommonName
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+	caFile := writePEM(t, "ca.pem", "CERTIFICATE", srv.Certificate().Raw)
+
+	if _, err := NewTransport(NetworkOptions{ClientCertFile: certFile}); err == nil {
+		t.Fatalf("expected an error for a certificate without a key")
+	}
+	opts := NetworkOptions{CAFile: caFile, ClientCertFile: certFile, ClientKeyFile: keyFile}
+	resp, err := (&http.Client{Transport: mustTransport(t, opts)}).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("mTLS request: %v", err)
+	}
+	resp.Body.Close()
+	if subject != "worker" {
+		t.Fatalf("expected client certificate worker, got %q", subject)
+	}
+}
+
+func TestNewTransportUsesExplicitProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	if _, err := NewTransport(NetworkOptions{ProxyURL: "not a url"}); err == nil {
+		t.Fatalf("expected an error for an invalid proxy URL")
+	}
+	client := &http.Client{Transport: mustTransport(t, NetworkOptions{ProxyURL: proxy.URL})}
+	resp, err := client.Get("http://vms.example.invalid/wand2/api/users/current")
+	if err != nil {
+		t.Fatalf("request through proxy: %v", err)
+	}
+	resp.Body.Close()
+	if proxied != "http://vms.example.invalid/wand2/api/users/current" {
+		t.Fatalf("proxy saw %q", proxied)
+	}
+}
+
+func TestHTTPClientSetsUserAgent(t *testing.T) {
+	var agents []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents = append(agents, r.UserAgent())
+	}))
+	defer srv.Close()
+
+	for _, userAgent := range []string{"", "corp-tool/2.0"} {
+		client, err := NewHTTPClientWithOptions(HTTPOptions{UserAgent: userAgent})
+		if err != nil {
+			t.Fatalf("new client: %v", err)
+		}
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		resp.Body.Close()
+	}
+	if len(agents) != 2 || agents[0] != DefaultUserAgent || agents[1] != "corp-tool/2.0" {
+		t.Fatalf("unexpected user agents %q", agents)
+	}
+}
+
+func mustTransport(t *testing.T, opts NetworkOptions) *http.Transport {
+	t.Helper()
+	transport, err := NewTransport(opts)
+	if err != nil {
+		t.Fatalf("new transport: %v", err)
+	}
+	return transport
+}
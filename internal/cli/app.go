@@ -6,26 +6,59 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/ihildy/magnit-vms-cli/internal/api"
 	"github.com/ihildy/magnit-vms-cli/internal/auth"
 	"github.com/ihildy/magnit-vms-cli/internal/config"
 	"github.com/ihildy/magnit-vms-cli/internal/keyring"
+	"github.com/ihildy/magnit-vms-cli/internal/output"
+	"github.com/ihildy/magnit-vms-cli/internal/recorder"
+	"github.com/ihildy/magnit-vms-cli/internal/trace"
 
 	"golang.org/x/term"
 )
 
 type App struct {
+	// Cfg is the effective config of the active profile; fileCfg is the
+	// config file as loaded, which SaveConfig folds Cfg back into.
 	Cfg             config.Config
 	CfgPath         string
+	ProfileFlag     string
+	Profile         string
 	JSONOutput      bool
+	OutputFlag      string
+	FormatTemplate  string
+	Output          output.Options
 	BaseURLOverride string
+	Verbose         bool
+	Trace           bool
+	RetriesFlag     int
+	RetryBaseFlag   string
+	RetryMaxFlag    string
+	Retry           api.RetryPolicy
+	ConcurrencyFlag int
+	RateLimitFlag   float64
+	Fetch           api.FetchOptions
+	RecordDir       string
+	ReplayDir       string
 	Stdout          io.Writer
 	Stderr          io.Writer
 	Stdin           io.Reader
+
+	fileCfg config.Config
+
+	// transport is shared by every HTTP client of one run so a recording
+	// covers the whole command.
+	transport http.RoundTripper
+	// logMu keeps concurrent diagnostics, e.g. from parallel week fetches,
+	// on separate lines.
+	logMu sync.Mutex
 }
 
 func NewApp() *App {
@@ -36,18 +69,215 @@ func NewApp() *App {
 	}
 }
 
-func (a *App) LoadConfig() error {
+// LoadConfig reads the config file and resolves the active profile. With
+// allowUnknownProfile, a profile that is not configured yet falls back to the
+// top-level settings so that it can be added while already selected.
+func (a *App) LoadConfig(allowUnknownProfile bool) error {
 	cfg, path, err := config.Load()
 	if err != nil {
 		return err
 	}
-	a.Cfg = cfg
+	profile := cfg.ActiveProfile(a.ProfileFlag)
+	effective := cfg
+	if !allowUnknownProfile || cfg.HasProfile(profile) {
+		effective, err = cfg.ForProfile(profile)
+		if err != nil {
+			return err
+		}
+	}
+	a.fileCfg = cfg
+	a.Cfg = effective
 	a.CfgPath = path
+	a.Profile = profile
+	return nil
+}
+
+// ResolveOutput picks the output format from --format, --json, --output and
+// the configured default, in that order.
+func (a *App) ResolveOutput(outputFlagSet bool) error {
+	if a.FormatTemplate != "" {
+		if a.JSONOutput || outputFlagSet {
+			return fmt.Errorf("--format cannot be combined with --json or --output")
+		}
+		tmpl, err := output.ParseTemplate(a.FormatTemplate)
+		if err != nil {
+			return err
+		}
+		a.Output = output.Options{Template: tmpl}
+		return nil
+	}
+
+	if a.JSONOutput {
+		if outputFlagSet && output.Format(strings.ToLower(strings.TrimSpace(a.OutputFlag))) != output.FormatJSON {
+			return fmt.Errorf("--json conflicts with --output %s", a.OutputFlag)
+		}
+		a.Output = output.Options{Format: output.FormatJSON}
+		return nil
+	}
+
+	value := a.OutputFlag
+	if !outputFlagSet {
+		value = config.DefaultOutputFormat(a.Cfg)
+	}
+	format, err := output.ParseFormat(value)
+	if err != nil {
+		return err
+	}
+	a.Output = output.Options{Format: format}
 	return nil
 }
 
+// ResolveRetry builds the retry policy from --retries/--retry-base-delay/
+// --retry-max-delay, then the http section of the config, then defaults.
+func (a *App) ResolveRetry(retriesFlagSet bool) error {
+	policy := api.DefaultRetryPolicy
+	if a.Cfg.HTTP.Retries != nil {
+		policy.Retries = *a.Cfg.HTTP.Retries
+	}
+	if retriesFlagSet {
+		policy.Retries = a.RetriesFlag
+	}
+	if policy.Retries < 0 {
+		return fmt.Errorf("retries must not be negative")
+	}
+
+	durations := []struct {
+		name      string
+		flagValue string
+		cfgValue  string
+		dest      *time.Duration
+	}{
+		{"retry-base-delay", a.RetryBaseFlag, a.Cfg.HTTP.RetryBaseDelay, &policy.BaseDelay},
+		{"retry-max-delay", a.RetryMaxFlag, a.Cfg.HTTP.RetryMaxDelay, &policy.MaxDelay},
+	}
+	for _, d := range durations {
+		value := strings.TrimSpace(d.flagValue)
+		if value == "" {
+			value = strings.TrimSpace(d.cfgValue)
+		}
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return fmt.Errorf("invalid %s %q", d.name, value)
+		}
+		*d.dest = parsed
+	}
+	if policy.MaxDelay < policy.BaseDelay {
+		return fmt.Errorf("retry-max-delay must be at least retry-base-delay")
+	}
+	a.Retry = policy
+	return nil
+}
+
+// ResolveFetch builds the week fetch limits from --concurrency/--rate-limit,
+// then the http section of the config, then defaults.
+func (a *App) ResolveFetch(concurrencyFlagSet, rateLimitFlagSet bool) error {
+	opts := api.DefaultFetchOptions
+	if a.Cfg.HTTP.Concurrency != 0 {
+		opts.Concurrency = a.Cfg.HTTP.Concurrency
+	}
+	if a.Cfg.HTTP.RateLimit != nil {
+		opts.RateLimit = *a.Cfg.HTTP.RateLimit
+	}
+	if concurrencyFlagSet {
+		opts.Concurrency = a.ConcurrencyFlag
+	}
+	if rateLimitFlagSet {
+		opts.RateLimit = a.RateLimitFlag
+	}
+	if opts.Concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1")
+	}
+	if opts.RateLimit < 0 {
+		return fmt.Errorf("rate-limit must not be negative")
+	}
+	a.Fetch = opts
+	return nil
+}
+
+// ResolveRecording checks --record/--replay. Replayed responses never change,
+// so retrying them is pointless.
+func (a *App) ResolveRecording() error {
+	if a.RecordDir != "" && a.ReplayDir != "" {
+		return fmt.Errorf("--record cannot be combined with --replay")
+	}
+	if a.ReplayDir != "" {
+		a.Retry.Retries = 0
+	}
+	return nil
+}
+
+func (a *App) newHTTPClient() (*http.Client, error) {
+	opts, err := httpOptions(a.Cfg.HTTP)
+	if err != nil {
+		return nil, err
+	}
+	if a.transport == nil {
+		var transport http.RoundTripper
+		if a.ReplayDir != "" {
+			replayer, err := recorder.NewReplayer(a.ReplayDir)
+			if err != nil {
+				return nil, err
+			}
+			transport = replayer
+		} else {
+			network, err := auth.NewTransport(opts.Network)
+			if err != nil {
+				return nil, err
+			}
+			transport = network
+			if a.RecordDir != "" {
+				rec, err := recorder.NewRecorder(a.RecordDir, network)
+				if err != nil {
+					return nil, err
+				}
+				transport = rec
+			}
+		}
+		if a.Verbose {
+			transport = &trace.Transport{Next: transport, Logf: a.Logf, Bodies: a.Trace}
+		}
+		a.transport = transport
+	}
+	opts.Transport = a.transport
+	return auth.NewHTTPClientWithOptions(opts)
+}
+
+func httpOptions(cfg config.HTTPConfig) (auth.HTTPOptions, error) {
+	opts := auth.HTTPOptions{
+		Network: auth.NetworkOptions{
+			ProxyURL:       cfg.Proxy,
+			CAFile:         cfg.CAFile,
+			ClientCertFile: cfg.ClientCert,
+			ClientKeyFile:  cfg.ClientKey,
+		},
+		UserAgent: cfg.UserAgent,
+	}
+	if value := strings.TrimSpace(cfg.Timeout); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return auth.HTTPOptions{}, fmt.Errorf("invalid http timeout %q", value)
+		}
+		opts.Timeout = timeout
+	}
+	return opts, nil
+}
+
+// loadCredentials returns the stored credentials. A replay needs none: the
+// recorded login answers whatever is sent.
+func (a *App) loadCredentials() (keyring.Credentials, error) {
+	if a.ReplayDir != "" {
+		return keyring.Credentials{Username: "replay", Password: "replay"}, nil
+	}
+	return a.credentials().LoadCredentials()
+}
+
+// SaveConfig writes changes to Cfg into the active profile.
 func (a *App) SaveConfig() error {
-	return config.Save(a.Cfg, a.CfgPath)
+	a.fileCfg = a.fileCfg.WithProfile(a.Profile, a.Cfg)
+	return config.Save(a.fileCfg, a.CfgPath)
 }
 
 func (a *App) BaseURL() string {
@@ -57,32 +287,143 @@ func (a *App) BaseURL() string {
 	return strings.TrimRight(a.Cfg.BaseURL, "/")
 }
 
+// NewAuthedClient reuses the saved session when the server still accepts it
+// and otherwise logs in with the stored credentials, saving the new session
+// for the next run. Recording and replaying always start with a login so
+// every recording can be replayed on its own.
 func (a *App) NewAuthedClient(ctx context.Context) (*api.Client, map[string]any, *httpContext, error) {
-	creds, err := keyring.LoadCredentialsWithStore(a.CredentialStore())
+	if a.RecordDir == "" && a.ReplayDir == "" {
+		if authenticator, user, ok := a.resumeSession(ctx); ok {
+			a.Logf("reusing saved session")
+			return a.newAPIClient(authenticator), user, &httpContext{Auth: authenticator}, nil
+		}
+	}
+
+	creds, err := a.loadCredentials()
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("credentials unavailable, run `magnit auth login` first: %w", err)
+		return nil, nil, nil, fmt.Errorf("credentials unavailable, run `magnit auth login` or `magnit auth sso` first: %w", err)
 	}
 
-	httpClient, err := auth.NewHTTPClient()
+	httpClient, err := a.newHTTPClient()
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
-	authenticator := &auth.Authenticator{
+	authenticator := a.newAuthenticator(httpClient)
+	user, err := authenticator.LoginUser(ctx, creds.Username, creds.Password)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("login failed using stored credentials: %w", err)
+	}
+	a.saveSession(httpClient)
+
+	return a.newAPIClient(authenticator), user, &httpContext{Auth: authenticator}, nil
+}
+
+// newAuthenticator returns an authenticator that answers MFA prompts with a
+// code generated from the stored TOTP secret.
+func (a *App) newAuthenticator(httpClient *http.Client) *auth.Authenticator {
+	return &auth.Authenticator{BaseURL: a.BaseURL(), Client: httpClient, MFACode: a.totpCode}
+}
+
+// totpCode generates the current one-time code. A replay answers with a
+// placeholder because recorded exchanges are matched without their bodies.
+func (a *App) totpCode(ctx context.Context) (string, error) {
+	if a.ReplayDir != "" {
+		return "000000", nil
+	}
+	secret, err := a.credentials().LoadTOTPSecret()
+	if errors.Is(err, keyring.ErrTOTPSecretNotFound) {
+		return "", auth.ErrMFARequired
+	}
+	if err != nil {
+		return "", err
+	}
+	a.Logf("answering MFA prompt with a TOTP code")
+	return auth.TOTP(secret, time.Now())
+}
+
+// newAPIClient wires an API client that logs in again with the stored
+// credentials when the server reports an expired session mid-run.
+func (a *App) newAPIClient(authenticator *auth.Authenticator) *api.Client {
+	return &api.Client{
 		BaseURL: a.BaseURL(),
-		Client:  httpClient,
+		HTTP:    authenticator.Client,
+		Logf:    a.Logf,
+		Retry:   a.Retry,
+		Fetch:   a.Fetch,
+		Reauth: func(ctx context.Context) error {
+			creds, err := a.loadCredentials()
+			if err != nil {
+				return fmt.Errorf("session expired and credentials unavailable, run `magnit auth login` or `magnit auth sso`: %w", err)
+			}
+			if _, err := authenticator.LoginUser(ctx, creds.Username, creds.Password); err != nil {
+				return err
+			}
+			a.saveSession(authenticator.Client)
+			return nil
+		},
 	}
-	if err := authenticator.Login(ctx, creds.Username, creds.Password); err != nil {
-		return nil, nil, nil, fmt.Errorf("login failed using stored credentials: %w", err)
+}
+
+// Logf prints a diagnostic line to stderr when --verbose is set.
+func (a *App) Logf(format string, args ...any) {
+	if !a.Verbose || a.Stderr == nil {
+		return
 	}
+	a.logMu.Lock()
+	defer a.logMu.Unlock()
+	fmt.Fprintf(a.Stderr, "magnit: "+format+"\n", args...)
+}
 
+// resumeSession restores the saved session into a fresh client and checks it
+// with users/current. Any failure means a normal login is needed.
+func (a *App) resumeSession(ctx context.Context) (*auth.Authenticator, map[string]any, bool) {
+	data, err := a.credentials().LoadSession()
+	if err != nil {
+		return nil, nil, false
+	}
+	session, err := auth.DecodeSession(data)
+	if err != nil || !session.MatchesBaseURL(a.BaseURL()) {
+		return nil, nil, false
+	}
+	httpClient, err := a.newHTTPClient()
+	if err != nil {
+		return nil, nil, false
+	}
+	if err := auth.RestoreSession(httpClient, session); err != nil {
+		return nil, nil, false
+	}
+	authenticator := a.newAuthenticator(httpClient)
 	user, err := authenticator.CurrentUser(ctx)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("current user check failed: %w", err)
+		return nil, nil, false
 	}
+	return authenticator, user, true
+}
 
-	client := &api.Client{BaseURL: a.BaseURL(), HTTP: httpClient}
-	return client, user, &httpContext{Auth: authenticator}, nil
+// saveSession persists the client's cookies after a password login; failing
+// to save only costs a login next time, so it is reported as a warning.
+func (a *App) saveSession(httpClient *http.Client) {
+	if err := a.storeSession(httpClient); err != nil {
+		fmt.Fprintf(a.Stderr, "warning: could not save session: %v\n", err)
+	}
+}
+
+// storeSession persists the client's cookies. Replayed cookies are redacted
+// and never saved.
+func (a *App) storeSession(httpClient *http.Client) error {
+	if a.ReplayDir != "" {
+		return nil
+	}
+	session, err := auth.ExportSession(httpClient, a.BaseURL())
+	if err != nil {
+		return err
+	}
+	data, err := auth.EncodeSession(session)
+	if err != nil {
+		return err
+	}
+	return a.credentials().SaveSession(data)
 }
 
 func (a *App) CredentialStore() string {
@@ -92,6 +433,38 @@ func (a *App) CredentialStore() string {
 	return strings.TrimSpace(a.Cfg.CredentialStore)
 }
 
+func (a *App) credentials() keyring.Store {
+	return keyring.Store{Backend: a.CredentialStore(), Profile: a.Profile, Prompt: a.promptPassphrase, Helper: a.Cfg.CredentialHelper}
+}
+
+func (a *App) promptPassphrase(label string, confirm bool) ([]byte, error) {
+	stdinFile, ok := a.Stdin.(*os.File)
+	if !ok || !term.IsTerminal(int(stdinFile.Fd())) {
+		return nil, fmt.Errorf("encrypted-file credential store needs a passphrase; set %s or %s when non-interactive", keyring.PassphraseEnvVar, keyring.PassphraseFDEnvVar)
+	}
+	read := func(prompt string) ([]byte, error) {
+		fmt.Fprintf(a.Stderr, "%s: ", prompt)
+		value, err := term.ReadPassword(int(stdinFile.Fd()))
+		fmt.Fprintln(a.Stderr)
+		if err != nil {
+			return nil, fmt.Errorf("read passphrase: %w", err)
+		}
+		return value, nil
+	}
+	passphrase, err := read(label)
+	if err != nil || !confirm {
+		return passphrase, err
+	}
+	again, err := read("Repeat " + strings.ToLower(label[:1]) + label[1:])
+	if err != nil {
+		return nil, err
+	}
+	if string(again) != string(passphrase) {
+		return nil, errors.New("passphrases do not match")
+	}
+	return passphrase, nil
+}
+
 type httpContext struct {
 	Auth *auth.Authenticator
 }
@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ihildy/magnit-vms-cli/internal/api"
+	"github.com/ihildy/magnit-vms-cli/internal/auth"
+	"github.com/ihildy/magnit-vms-cli/internal/fakevms"
+	"github.com/ihildy/magnit-vms-cli/internal/keyring"
+)
+
+func TestNewAuthedClientReusesSavedSession(t *testing.T) {
+	isolateHome(t, keyring.StoreFile)
+
+	var logins, currentUserCalls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login.html":
+			logins++
+			http.SetCookie(w, &http.Cookie{Name: "productionaccess_token", Value: "tok", Path: "/"})
+			http.SetCookie(w, &http.Cookie{Name: "XSRF-TOKEN", Value: "xsrf", Path: "/"})
+			_, _ = w.Write([]byte("ok"))
+		case "/wand2/api/users/current":
+			currentUserCalls++
+			if r.Header.Get("Authorization") != "Bearer tok" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"userId":1}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	if err := keyring.SaveCredentialsWithStore(keyring.Credentials{Username: "user@example.com", Password: "secret"}, ""); err != nil {
+		t.Fatalf("save credentials: %v", err)
+	}
+	app := &App{BaseURLOverride: srv.URL, Stdout: io.Discard, Stderr: io.Discard}
+
+	for i := 0; i < 2; i++ {
+		if _, _, _, err := app.NewAuthedClient(context.Background()); err != nil {
+			t.Fatalf("run %d: %v", i+1, err)
+		}
+	}
+	if logins != 1 {
+		t.Fatalf("expected one password login, got %d", logins)
+	}
+	if currentUserCalls != 2 {
+		t.Fatalf("expected one users/current call per run, got %d", currentUserCalls)
+	}
+
+	// A rejected session falls back to a password login.
+	if err := keyring.SaveSessionWithStore(`{"base_url":"`+srv.URL+`","cookies":[{"name":"productionaccess_token","value":"stale","path":"/"}]}`, ""); err != nil {
+		t.Fatalf("save stale session: %v", err)
+	}
+	if _, _, _, err := app.NewAuthedClient(context.Background()); err != nil {
+		t.Fatalf("stale session run: %v", err)
+	}
+	if logins != 2 {
+		t.Fatalf("expected fallback login, got %d logins", logins)
+	}
+}
+
+func TestRecordedSessionReplaysWithoutNetworkOrCredentials(t *testing.T) {
+	isolateHome(t, keyring.StoreFile)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login.html":
+			http.SetCookie(w, &http.Cookie{Name: "productionaccess_token", Value: "tok", Path: "/"})
+			_, _ = w.Write([]byte("ok"))
+		case "/wand2/api/users/current":
+			_, _ = w.Write([]byte(`{"userId":1}`))
+		default:
+			_, _ = w.Write([]byte(`{"selectedDate":"` + r.URL.Query().Get("selectedDate") + `"}`))
+		}
+	}))
+
+	if err := keyring.SaveCredentialsWithStore(keyring.Credentials{Username: "user@example.com", Password: "secret"}, ""); err != nil {
+		t.Fatalf("save credentials: %v", err)
+	}
+	dir := filepath.Join(t.TempDir(), "rec")
+	recording := &App{BaseURLOverride: srv.URL, RecordDir: dir, Stdout: io.Discard, Stderr: io.Discard}
+	client, _, _, err := recording.NewAuthedClient(context.Background())
+	if err != nil {
+		t.Fatalf("record login: %v", err)
+	}
+	if _, err := client.GetMetadata(context.Background(), 7, "02/16/2026"); err != nil {
+		t.Fatalf("record metadata: %v", err)
+	}
+	srv.Close()
+	if err := keyring.DeleteCredentialsWithStore(""); err != nil {
+		t.Fatalf("delete credentials: %v", err)
+	}
+
+	replaying := &App{BaseURLOverride: "http://replay.invalid", ReplayDir: dir, Stdout: io.Discard, Stderr: io.Discard}
+	client, user, _, err := replaying.NewAuthedClient(context.Background())
+	if err != nil {
+		t.Fatalf("replay login: %v", err)
+	}
+	metadata, err := client.GetMetadata(context.Background(), 7, "02/16/2026")
+	if err != nil {
+		t.Fatalf("replay metadata: %v", err)
+	}
+	if user["userId"] != float64(1) || metadata["selectedDate"] != "02/16/2026" {
+		t.Fatalf("unexpected replay: user %v, metadata %v", user, metadata)
+	}
+	if _, err := keyring.LoadSessionWithStore(""); err == nil {
+		t.Fatalf("replay must not save the redacted session")
+	}
+}
+
+func TestTraceLogsRequestsWithoutSecrets(t *testing.T) {
+	isolateHome(t, keyring.StoreFile)
+
+	srv := httptest.NewServer(fakevms.New("user@example.com", "hunter2").Handler())
+	defer srv.Close()
+	if err := keyring.SaveCredentialsWithStore(keyring.Credentials{Username: "user@example.com", Password: "hunter2"}, ""); err != nil {
+		t.Fatalf("save credentials: %v", err)
+	}
+
+	var stderr bytes.Buffer
+	app := &App{BaseURLOverride: srv.URL, Verbose: true, Trace: true, Stdout: io.Discard, Stderr: &stderr}
+	client, _, httpCtx, err := app.NewAuthedClient(context.Background())
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if _, err := client.GetMetadata(context.Background(), 1001, "02/16/2026"); err != nil {
+		t.Fatalf("get metadata: %v", err)
+	}
+	xsrf, _ := auth.ExtractXSRFToken(httpCtx.Auth.Client, srv.URL)
+	access, _ := auth.ExtractAccessToken(httpCtx.Auth.Client, srv.URL)
+
+	log := stderr.String()
+	for _, secret := range []string{"hunter2", xsrf, access} {
+		if secret == "" || strings.Contains(log, secret) {
+			t.Fatalf("trace leaks %q:\n%s", secret, log)
+		}
+	}
+	for _, want := range []string{"POST " + srv.URL + "/login.html -> 302 Found", "GET " + srv.URL + "/wand2/api/billing/billing-items/0/metadata?", "password_login=REDACTED"} {
+		if !strings.Contains(log, want) {
+			t.Fatalf("trace is missing %q:\n%s", want, log)
+		}
+	}
+}
+
+func TestResolveFetchHonorsZeroRateLimitFromConfig(t *testing.T) {
+	zero := 0.0
+	app := &App{}
+	app.Cfg.HTTP.RateLimit = &zero
+	if err := app.ResolveFetch(false, false); err != nil {
+		t.Fatalf("resolve fetch: %v", err)
+	}
+	if app.Fetch.RateLimit != 0 {
+		t.Fatalf("expected rate_limit: 0 to disable the limit, got %v", app.Fetch.RateLimit)
+	}
+
+	app.Cfg.HTTP.RateLimit = nil
+	if err := app.ResolveFetch(false, false); err != nil {
+		t.Fatalf("resolve fetch: %v", err)
+	}
+	if app.Fetch.RateLimit != api.DefaultFetchOptions.RateLimit {
+		t.Fatalf("expected the default rate limit when unset, got %v", app.Fetch.RateLimit)
+	}
+}
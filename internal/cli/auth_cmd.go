@@ -9,7 +9,6 @@ import (
 	"os"
 	"strings"
 
-	"github.com/ihildy/magnit-vms-cli/internal/auth"
 	"github.com/ihildy/magnit-vms-cli/internal/keyring"
 	"github.com/ihildy/magnit-vms-cli/internal/output"
 
@@ -25,6 +24,10 @@ func newAuthCmd(app *App) *cobra.Command {
 	cmd.AddCommand(newAuthLoginCmd(app))
 	cmd.AddCommand(newAuthStatusCmd(app))
 	cmd.AddCommand(newAuthLogoutCmd(app))
+	cmd.AddCommand(newAuthMFACmd(app))
+	cmd.AddCommand(newAuthSSOCmd(app))
+	cmd.AddCommand(newAuthImportSessionCmd(app))
+	cmd.AddCommand(newAuthRekeyCmd(app))
 	return cmd
 }
 
@@ -61,23 +64,22 @@ func newAuthLoginCmd(app *App) *cobra.Command {
 				return err
 			}
 
-			httpClient, err := auth.NewHTTPClient()
+			httpClient, err := app.newHTTPClient()
 			if err != nil {
 				return err
 			}
-			authn := &auth.Authenticator{BaseURL: app.BaseURL(), Client: httpClient}
-			if err := authn.Login(ctx, username, password); err != nil {
-				return err
-			}
-
-			user, err := authn.CurrentUser(ctx)
+			authn := app.newAuthenticator(httpClient)
+			user, err := authn.LoginUser(ctx, username, password)
 			if err != nil {
 				return err
 			}
 
-			if err := keyring.SaveCredentialsWithStore(keyring.Credentials{Username: username, Password: password}, app.CredentialStore()); err != nil {
-				return err
+			if app.ReplayDir == "" {
+				if err := app.credentials().SaveCredentials(keyring.Credentials{Username: username, Password: password}); err != nil {
+					return err
+				}
 			}
+			app.saveSession(httpClient)
 
 			payload := map[string]any{
 				"ok":        true,
@@ -90,7 +92,7 @@ func newAuthLoginCmd(app *App) *cobra.Command {
 				},
 			}
 			human := fmt.Sprintf("Login successful for %s", username)
-			return output.Write(app.Stdout, app.JSONOutput, human, payload)
+			return output.Write(app.Stdout, app.Output, human, payload)
 		},
 	}
 	cmd.Flags().StringVar(&username, "username", "", "Account username (email)")
@@ -106,26 +108,36 @@ func newAuthStatusCmd(app *App) *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := context.Background()
 
-			creds, err := keyring.LoadCredentialsWithStore(app.CredentialStore())
+			creds, err := app.loadCredentials()
 			if err != nil {
+				// SSO accounts have no password, only an imported session.
+				if _, user, ok := app.resumeSession(ctx); ok {
+					payload := map[string]any{
+						"ok":            true,
+						"operation":     "auth_status",
+						"authenticated": true,
+						"method":        "session",
+						"user": map[string]any{
+							"userId":   user["userId"],
+							"fullName": user["fullName"],
+							"email":    user["email"],
+						},
+					}
+					return output.Write(app.Stdout, app.Output, "Authenticated with a saved session", payload)
+				}
 				payload := map[string]any{"ok": true, "operation": "auth_status", "authenticated": false}
-				return output.Write(app.Stdout, app.JSONOutput, "No stored credentials", payload)
+				return output.Write(app.Stdout, app.Output, "No stored credentials", payload)
 			}
 
-			httpClient, err := auth.NewHTTPClient()
+			httpClient, err := app.newHTTPClient()
 			if err != nil {
 				return err
 			}
-			authn := &auth.Authenticator{BaseURL: app.BaseURL(), Client: httpClient}
-			if err := authn.Login(ctx, creds.Username, creds.Password); err != nil {
-				payload := map[string]any{"ok": true, "operation": "auth_status", "authenticated": false, "reason": err.Error()}
-				return output.Write(app.Stdout, app.JSONOutput, "Stored credentials are invalid", payload)
-			}
-
-			user, err := authn.CurrentUser(ctx)
+			authn := app.newAuthenticator(httpClient)
+			user, err := authn.LoginUser(ctx, creds.Username, creds.Password)
 			if err != nil {
 				payload := map[string]any{"ok": true, "operation": "auth_status", "authenticated": false, "reason": err.Error()}
-				return output.Write(app.Stdout, app.JSONOutput, "Stored credentials are invalid", payload)
+				return output.Write(app.Stdout, app.Output, "Stored credentials are invalid", payload)
 			}
 
 			payload := map[string]any{
@@ -139,7 +151,7 @@ func newAuthStatusCmd(app *App) *cobra.Command {
 					"email":    user["email"],
 				},
 			}
-			return output.Write(app.Stdout, app.JSONOutput, "Authenticated", payload)
+			return output.Write(app.Stdout, app.Output, "Authenticated", payload)
 		},
 	}
 }
@@ -147,54 +159,106 @@ func newAuthStatusCmd(app *App) *cobra.Command {
 func newAuthLogoutCmd(app *App) *cobra.Command {
 	return &cobra.Command{
 		Use:   "logout",
-		Short: "Delete stored credentials",
+		Short: "Delete stored credentials, the TOTP secret and the saved session",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := keyring.DeleteCredentialsWithStore(app.CredentialStore()); err != nil {
+			if err := app.credentials().DeleteCredentials(); err != nil {
 				return err
 			}
 			payload := map[string]any{"ok": true, "operation": "auth_logout"}
-			return output.Write(app.Stdout, app.JSONOutput, "Credentials removed", payload)
+			return output.Write(app.Stdout, app.Output, "Credentials removed", payload)
 		},
 	}
 }
 
+func newAuthRekeyCmd(app *App) *cobra.Command {
+	var fromStdin bool
+	cmd := &cobra.Command{
+		Use:   "rekey [--new-passphrase-stdin]",
+		Short: "Re-encrypt the encrypted-file credential store under a new passphrase",
+		Long: `Re-encrypt the encrypted-file credential store under a new passphrase.
+
+The current passphrase comes from MAGNIT_CREDENTIAL_PASSPHRASE,
+MAGNIT_CREDENTIAL_PASSPHRASE_FD or a prompt. The new one is read from stdin
+with --new-passphrase-stdin, otherwise prompted for twice.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if store := keyring.NormalizeCredentialStore(app.CredentialStore()); store != keyring.StoreEncryptedFile {
+				return fmt.Errorf("rekey needs the %s credential store, the active one is %s", keyring.StoreEncryptedFile, store)
+			}
+			credentials := app.credentials()
+			// Read with the current passphrase before asking for the new
+			// one, so a wrong passphrase fails early.
+			if _, err := credentials.LoadCredentials(); err != nil && !errors.Is(err, keyring.ErrCredentialsNotFound) {
+				return err
+			}
+
+			var next []byte
+			if fromStdin {
+				data, err := io.ReadAll(app.Stdin)
+				if err != nil {
+					return fmt.Errorf("read new passphrase from stdin: %w", err)
+				}
+				next = []byte(strings.TrimRight(string(data), "\r\n"))
+			} else {
+				var err error
+				if next, err = app.promptPassphrase("New passphrase", true); err != nil {
+					return err
+				}
+			}
+			if len(next) == 0 {
+				return fmt.Errorf("new passphrase is required")
+			}
+			if err := credentials.Rekey(next); err != nil {
+				return err
+			}
+			payload := map[string]any{"ok": true, "operation": "auth_rekey", "profile": app.Profile}
+			return output.Write(app.Stdout, app.Output, "Credentials re-encrypted with the new passphrase", payload)
+		},
+	}
+	cmd.Flags().BoolVar(&fromStdin, "new-passphrase-stdin", false, "Read the new passphrase from stdin")
+	return cmd
+}
+
 func resolvePassword(app *App, provided string, providedSet bool, fromStdin bool) (string, error) {
+	return resolveSecret(app, "password", provided, providedSet, fromStdin)
+}
+
+func resolveSecret(app *App, name string, provided string, providedSet bool, fromStdin bool) (string, error) {
 	if providedSet && fromStdin {
-		return "", fmt.Errorf("use only one of --password or --password-stdin")
+		return "", fmt.Errorf("use only one of --%s or --%s-stdin", name, name)
 	}
 
 	if fromStdin {
-		password, err := io.ReadAll(app.Stdin)
+		secret, err := io.ReadAll(app.Stdin)
 		if err != nil {
-			return "", fmt.Errorf("read password from stdin: %w", err)
+			return "", fmt.Errorf("read %s from stdin: %w", name, err)
 		}
-		value := strings.TrimRight(string(password), "\r\n")
+		value := strings.TrimRight(string(secret), "\r\n")
 		if value == "" {
-			return "", fmt.Errorf("password is required")
+			return "", fmt.Errorf("%s is required", name)
 		}
 		return value, nil
 	}
 
 	if providedSet {
 		if provided == "" {
-			return "", fmt.Errorf("password is required")
+			return "", fmt.Errorf("%s is required", name)
 		}
 		return provided, nil
 	}
 
 	stdinFile, ok := app.Stdin.(*os.File)
 	if !ok || !term.IsTerminal(int(stdinFile.Fd())) {
-		return "", fmt.Errorf("password is required; pass --password or --password-stdin when non-interactive")
+		return "", fmt.Errorf("%s is required; pass --%s or --%s-stdin when non-interactive", name, name, name)
 	}
-	fmt.Fprint(app.Stderr, "Password: ")
+	fmt.Fprintf(app.Stderr, "%s%s: ", strings.ToUpper(name[:1]), name[1:])
 	bytes, err := term.ReadPassword(int(stdinFile.Fd()))
 	fmt.Fprintln(app.Stderr)
 	if err != nil {
-		return "", fmt.Errorf("read password: %w", err)
+		return "", fmt.Errorf("read %s: %w", name, err)
 	}
-	password := string(bytes)
-	if password == "" {
-		return "", fmt.Errorf("password is required")
+	secret := string(bytes)
+	if secret == "" {
+		return "", fmt.Errorf("%s is required", name)
 	}
-	return password, nil
+	return secret, nil
 }
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/auth"
+	"github.com/ihildy/magnit-vms-cli/internal/output"
+
+	"github.com/spf13/cobra"
+)
+
+func newAuthMFACmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "mfa",
+		Short: "Manage the second factor used at login",
+	}
+	cmd.AddCommand(newAuthMFASetTOTPCmd(app))
+	cmd.AddCommand(newAuthMFARemoveCmd(app))
+	return cmd
+}
+
+func newAuthMFASetTOTPCmd(app *App) *cobra.Command {
+	var secret string
+	cmd := &cobra.Command{
+		Use:   "set-totp [--secret BASE32 | --secret-stdin]",
+		Short: "Store the TOTP secret used to answer MFA prompts at login",
+		Long: `Store the TOTP secret used to answer MFA prompts at login.
+
+The secret is the base32 key (or otpauth:// URI) shown when the authenticator
+app was set up. It is kept in the credential store next to the password, and
+each login generates the current 6-digit code locally. Compare the printed
+code with your authenticator app to check the secret.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fromStdin, err := cmd.Flags().GetBool("secret-stdin")
+			if err != nil {
+				return err
+			}
+			raw, err := resolveSecret(app, "secret", secret, cmd.Flags().Changed("secret"), fromStdin)
+			if err != nil {
+				return err
+			}
+			normalized, err := auth.NormalizeTOTPSecret(raw)
+			if err != nil {
+				return err
+			}
+			code, err := auth.TOTP(normalized, time.Now())
+			if err != nil {
+				return err
+			}
+			if err := app.credentials().SaveTOTPSecret(normalized); err != nil {
+				return err
+			}
+
+			payload := map[string]any{
+				"ok":           true,
+				"operation":    "auth_mfa_set_totp",
+				"current_code": code,
+			}
+			human := fmt.Sprintf("TOTP secret saved; current code %s should match your authenticator app", code)
+			return output.Write(app.Stdout, app.Output, human, payload)
+		},
+	}
+	cmd.Flags().StringVar(&secret, "secret", "", "Base32 TOTP secret or otpauth:// URI (avoid shell history leaks)")
+	cmd.Flags().Bool("secret-stdin", false, "Read the TOTP secret from stdin")
+	return cmd
+}
+
+func newAuthMFARemoveCmd(app *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove",
+		Short: "Delete the stored TOTP secret",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := app.credentials().DeleteTOTPSecret(); err != nil {
+				return err
+			}
+			payload := map[string]any{"ok": true, "operation": "auth_mfa_remove"}
+			return output.Write(app.Stdout, app.Output, "TOTP secret removed", payload)
+		},
+	}
+}
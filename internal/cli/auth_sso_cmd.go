@@ -0,0 +1,330 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/auth"
+	"github.com/ihildy/magnit-vms-cli/internal/output"
+
+	"github.com/spf13/cobra"
+)
+
+func newAuthSSOCmd(app *App) *cobra.Command {
+	var cookie string
+	var cookieStdin bool
+	var listen string
+	var noBrowser bool
+	var timeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "sso [--cookie HEADER | --cookie-stdin | --listen 127.0.0.1:8765] [--no-browser]",
+		Short: "Sign in through the browser and import its session",
+		Long: `Sign in through the browser and import its session.
+
+For accounts that log in through SSO or an MFA method the CLI cannot answer.
+The login page is opened in the browser; after signing in, copy the Cookie
+request header of any request to the site from the browser's developer tools
+(Network tab) and paste it when prompted, or pass it with --cookie or
+--cookie-stdin. With --listen, a local page at that loopback address accepts
+the pasted header instead. The session is checked with users/current and
+saved like a password login's session. It cannot be renewed without a
+password, so run this command again when it expires. To import a cookies.txt
+or HAR export instead, use auth import-session.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			if n := countTrue(cmd.Flags().Changed("cookie"), cookieStdin, listen != ""); n > 1 {
+				return fmt.Errorf("use only one of --cookie, --cookie-stdin or --listen")
+			}
+			loginURL := app.BaseURL() + "/login.html"
+
+			method := "cookie"
+			var user map[string]any
+			var err error
+			switch {
+			case cmd.Flags().Changed("cookie"):
+				user, err = app.importSSOSession(ctx, cookie)
+			case cookieStdin:
+				data, readErr := io.ReadAll(app.Stdin)
+				if readErr != nil {
+					return fmt.Errorf("read cookie from stdin: %w", readErr)
+				}
+				user, err = app.importSSOSession(ctx, string(data))
+			case listen != "":
+				method = "callback"
+				user, err = app.ssoCallback(ctx, listen, loginURL, !noBrowser, timeout)
+			default:
+				app.openLoginPage(loginURL, !noBrowser)
+				fmt.Fprint(app.Stderr, "After signing in, paste the Cookie header of a request to the site: ")
+				line, readErr := bufio.NewReader(app.Stdin).ReadString('\n')
+				if readErr != nil && !errors.Is(readErr, io.EOF) {
+					return readErr
+				}
+				user, err = app.importSSOSession(ctx, line)
+			}
+			if err != nil {
+				return err
+			}
+
+			payload := map[string]any{
+				"ok":        true,
+				"operation": "auth_sso",
+				"method":    method,
+				"user": map[string]any{
+					"userId":   user["userId"],
+					"fullName": user["fullName"],
+					"email":    user["email"],
+				},
+			}
+			human := fmt.Sprintf("Session imported for %v", user["email"])
+			return output.Write(app.Stdout, app.Output, human, payload)
+		},
+	}
+	cmd.Flags().StringVar(&cookie, "cookie", "", "Cookie header copied from the browser")
+	cmd.Flags().BoolVar(&cookieStdin, "cookie-stdin", false, "Read the Cookie header from stdin")
+	cmd.Flags().StringVar(&listen, "listen", "", "Serve a local page at this loopback address, e.g. 127.0.0.1:8765, that accepts the pasted Cookie header")
+	cmd.Flags().BoolVar(&noBrowser, "no-browser", false, "Print the login URL instead of opening the browser")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "How long --listen waits for a session")
+	return cmd
+}
+
+func (a *App) importSSOSession(ctx context.Context, header string) (map[string]any, error) {
+	cookies := auth.ParseCookieHeader(header)
+	if len(cookies) == 0 {
+		return nil, fmt.Errorf("no cookies found; paste the value of the Cookie request header")
+	}
+	return a.importBrowserSession(ctx, cookies)
+}
+
+// importBrowserSession loads browser cookies into a fresh client, checks
+// them with users/current and saves the session. The session is the only
+// credential here, so failing to save it is an error.
+func (a *App) importBrowserSession(ctx context.Context, cookies []*http.Cookie) (map[string]any, error) {
+	httpClient, err := a.newHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	if err := auth.ImportCookies(httpClient, a.BaseURL(), cookies); err != nil {
+		return nil, err
+	}
+	user, err := a.newAuthenticator(httpClient).CurrentUser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("browser session was not accepted: %w", err)
+	}
+	if err := a.storeSession(httpClient); err != nil {
+		return nil, fmt.Errorf("save session: %w", err)
+	}
+	return user, nil
+}
+
+// ssoCallback serves a local page that accepts the pasted Cookie header and
+// returns once one of them is accepted or the timeout passes.
+func (a *App) ssoCallback(ctx context.Context, listen, loginURL string, openBrowser bool, timeout time.Duration) (map[string]any, error) {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil || !isLoopbackHost(host) {
+		return nil, fmt.Errorf("--listen must be a loopback address such as 127.0.0.1:8765, got %q", listen)
+	}
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", listen, err)
+	}
+	results := make(chan map[string]any, 1)
+	server := &http.Server{Handler: a.ssoCallbackHandler(loginURL, token, results), ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+	defer server.Close()
+
+	a.openLoginPage(loginURL, openBrowser)
+	fmt.Fprintf(a.Stderr, "Then open http://%s/ and paste the Cookie header there.\n", listener.Addr())
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	select {
+	case user := <-results:
+		return user, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("no session received within %s", timeout)
+	}
+}
+
+const ssoPage = `<!doctype html>
+<html><body>
+<h1>magnit: import browser session</h1>
+<p>Sign in at <a href="%[1]s" target="_blank">%[1]s</a>, open the developer tools
+Network tab, select any request to the site and copy its <code>Cookie</code> request header.</p>
+%[2]s
+<form method="post" action="/callback">
+<input type="hidden" name="token" value="%[3]s">
+<textarea name="cookie" rows="6" cols="80"></textarea><br>
+<button type="submit">Import session</button>
+</form>
+</body></html>`
+
+// ssoCallbackHandler serves the paste form and sends each accepted user on
+// results. Rejected cookies are reported on the page so the user can retry.
+// Requests must name a loopback host, which defeats DNS rebinding, and posts
+// must come from the page itself with its per-run token, so other sites
+// cannot plant a session of their own.
+func (a *App) ssoCallbackHandler(loginURL, token string, results chan<- map[string]any) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		if !isLoopbackHost(hostOnly(r.Host)) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, ssoPage, html.EscapeString(loginURL), "", token)
+	})
+	mux.HandleFunc("POST /callback", func(w http.ResponseWriter, r *http.Request) {
+		if !isLoopbackHost(hostOnly(r.Host)) || !sameOrigin(r) || subtle.ConstantTimeCompare([]byte(r.FormValue("token")), []byte(token)) != 1 {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		user, err := a.importSSOSession(r.Context(), r.FormValue("cookie"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, ssoPage, html.EscapeString(loginURL), "<p><strong>"+html.EscapeString(err.Error())+"</strong></p>", token)
+			return
+		}
+		fmt.Fprint(w, "<!doctype html><html><body><p>Session imported. You can close this tab.</p></body></html>")
+		select {
+		case results <- user:
+		default:
+		}
+	})
+	return mux
+}
+
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func hostOnly(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return host
+	}
+	return hostport
+}
+
+// sameOrigin accepts posts without an Origin header, which browsers only omit
+// for same-origin navigations, and those whose Origin is the page itself.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Scheme == "http" && u.Host == r.Host
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate callback token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// openLoginPage opens url in the default browser when asked to and always
+// prints it, since opening fails silently on headless machines.
+func (a *App) openLoginPage(url string, open bool) {
+	fmt.Fprintf(a.Stderr, "Sign in at %s\n", url)
+	if !open {
+		return
+	}
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		a.Logf("could not open browser: %v", err)
+		return
+	}
+	go cmd.Wait()
+}
+
+func countTrue(values ...bool) int {
+	n := 0
+	for _, v := range values {
+		if v {
+			n++
+		}
+	}
+	return n
+}
+
+func newAuthImportSessionCmd(app *App) *cobra.Command {
+	var file string
+	cmd := &cobra.Command{
+		Use:   "import-session --file cookies.txt|session.har",
+		Short: "Authenticate with cookies exported from the browser",
+		Long: `Authenticate with cookies exported from the browser.
+
+Reads a Netscape cookies.txt file (curl, browser export extensions) or a HAR
+file saved from the developer tools Network tab after signing in. Only cookies
+for the configured base URL's host are used. The session is checked with
+users/current and saved in the credential store; no password is stored, so
+import a fresh export when the session expires.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if strings.TrimSpace(file) == "" {
+				return fmt.Errorf("--file is required")
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("read session file: %w", err)
+			}
+			cookies, err := auth.ParseCookieFile(data, app.BaseURL())
+			if err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+			user, err := app.importBrowserSession(context.Background(), cookies)
+			if err != nil {
+				return err
+			}
+
+			payload := map[string]any{
+				"ok":        true,
+				"operation": "auth_import_session",
+				"file":      file,
+				"cookies":   len(cookies),
+				"user": map[string]any{
+					"userId":   user["userId"],
+					"fullName": user["fullName"],
+					"email":    user["email"],
+				},
+			}
+			human := fmt.Sprintf("Imported %d cookies from %s; session saved for %v", len(cookies), file, user["email"])
+			return output.Write(app.Stdout, app.Output, human, payload)
+		},
+	}
+	cmd.Flags().StringVar(&file, "file", "", "Netscape cookies.txt or HAR file")
+	return cmd
+}
@@ -0,0 +1,227 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/api"
+	"github.com/ihildy/magnit-vms-cli/internal/auth"
+	"github.com/ihildy/magnit-vms-cli/internal/output"
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+	"github.com/ihildy/magnit-vms-cli/internal/txn"
+)
+
+type weekPlan struct {
+	EngagementID int64
+	WeekStart    time.Time
+	Original     map[string]any
+	Patched      map[string]any
+	Changes      []timecard.DayChange
+}
+
+type weekResult struct {
+	EngagementID  int64                `json:"engagement_id"`
+	WeekStart     string               `json:"week_start"`
+	Changes       []timecard.DayChange `json:"changes"`
+	BillingItemID int64                `json:"billing_item_id,omitempty"`
+	Status        string               `json:"status,omitempty"`
+}
+
+type bulkOptions struct {
+	Operation    string
+	EngagementID int64
+	DryRun       bool
+	Yes          bool
+	Extra        map[string]any
+}
+
+// runBulkEdits fetches each affected week once, applies all of its day edits,
+// shows the combined diff, asks once before replacing existing entries and
+// saves each week with a single request.
+func runBulkEdits(ctx context.Context, app *App, edits []timecard.DayEdit, opts bulkOptions) error {
+	if len(edits) == 0 {
+		return fmt.Errorf("no days to apply")
+	}
+
+	client, _, httpCtx, err := app.NewAuthedClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := fillDefaultEngagement(ctx, app, client, edits, opts.EngagementID); err != nil {
+		return err
+	}
+
+	plans, err := planWeekEdits(ctx, client, edits)
+	if err != nil {
+		return err
+	}
+
+	if err := confirmBulkConflicts(app, plans, opts.Yes); err != nil {
+		return err
+	}
+
+	results := make([]weekResult, 0, len(plans))
+	for _, p := range plans {
+		results = append(results, weekResult{
+			EngagementID: p.EngagementID,
+			WeekStart:    timecard.FormatMDY(p.WeekStart),
+			Changes:      p.Changes,
+		})
+	}
+
+	payload := map[string]any{
+		"ok":        true,
+		"operation": opts.Operation,
+		"dry_run":   opts.DryRun,
+		"days":      len(edits),
+	}
+	for k, v := range opts.Extra {
+		payload[k] = v
+	}
+
+	if opts.DryRun {
+		payload["weeks"] = results
+		human := "Dry run complete\n" + formatWeekPlansHuman(plans)
+		return output.Write(app.Stdout, app.Output, human, payload)
+	}
+
+	outcomes, err := saveWeekPlans(ctx, app, client, httpCtx, plans)
+	if err != nil {
+		return err
+	}
+	for i, o := range outcomes {
+		results[i].BillingItemID = o.BillingItemID
+		results[i].Status = o.Status
+	}
+
+	payload["weeks"] = results
+	human := fmt.Sprintf("Saved %d day(s) across %d week(s)\n%s", len(edits), len(plans), formatWeekPlansHuman(plans))
+	return output.Write(app.Stdout, app.Output, human, payload)
+}
+
+// saveWeekPlans saves the weeks as one transaction: if any week fails, the
+// weeks already saved are rolled back and the error lists every week's fate.
+func saveWeekPlans(ctx context.Context, app *App, client *api.Client, httpCtx *httpContext, plans []weekPlan) ([]txn.Outcome, error) {
+	xsrf, err := auth.ExtractXSRFToken(httpCtx.Auth.Client, app.BaseURL())
+	if err != nil {
+		return nil, err
+	}
+	weeks := make([]txn.Week, 0, len(plans))
+	for _, p := range plans {
+		weeks = append(weeks, txn.Week{
+			EngagementID: p.EngagementID,
+			WeekStart:    p.WeekStart,
+			Original:     p.Original,
+			Patched:      p.Patched,
+		})
+	}
+	return txn.Save(ctx, client, xsrf, weeks)
+}
+
+// fillDefaultEngagement assigns the --engagement override or configured
+// default to edits whose input did not name an engagement.
+func fillDefaultEngagement(ctx context.Context, app *App, client *api.Client, edits []timecard.DayEdit, override int64) error {
+	var resolved int64
+	for i := range edits {
+		if edits[i].EngagementID > 0 {
+			continue
+		}
+		if resolved == 0 {
+			id, err := app.ResolveEngagementID(ctx, client, override)
+			if err != nil {
+				return err
+			}
+			resolved = id
+		}
+		edits[i].EngagementID = resolved
+	}
+	return nil
+}
+
+func planWeekEdits(ctx context.Context, client *api.Client, edits []timecard.DayEdit) ([]weekPlan, error) {
+	groups, err := timecard.GroupEditsByWeek(edits)
+	if err != nil {
+		return nil, err
+	}
+
+	reqs := make([]api.WeekRequest, len(groups))
+	for i, g := range groups {
+		reqs[i] = api.WeekRequest{EngagementID: g.EngagementID, WeekStart: timecard.FormatMDY(g.WeekStart)}
+	}
+	metadata, err := client.FetchWeeks(ctx, reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	plans := make([]weekPlan, 0, len(groups))
+	for i, g := range groups {
+		plan, err := applyWeekEdits(metadata[i], g)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
+
+func applyWeekEdits(metadata map[string]any, g timecard.WeekEdits) (weekPlan, error) {
+	plan := weekPlan{EngagementID: g.EngagementID, WeekStart: g.WeekStart, Original: metadata}
+	current := metadata
+	for _, edit := range g.Edits {
+		patched, change, err := timecard.ApplyDayEdit(current, edit)
+		if err != nil {
+			if edit.Source != "" {
+				return weekPlan{}, fmt.Errorf("%s: %w", edit.Source, err)
+			}
+			return weekPlan{}, err
+		}
+		current = patched
+		plan.Changes = append(plan.Changes, change)
+	}
+	plan.Patched = current
+	return plan, nil
+}
+
+func confirmBulkConflicts(app *App, plans []weekPlan, yes bool) error {
+	if yes {
+		return nil
+	}
+	conflicts := 0
+	for _, p := range plans {
+		for _, c := range p.Changes {
+			if c.HadExisting {
+				conflicts++
+			}
+		}
+	}
+	if conflicts == 0 {
+		return nil
+	}
+	fmt.Fprintln(app.Stderr, formatWeekPlansHuman(plans))
+	ok, err := app.PromptConfirm(fmt.Sprintf("%d day(s) already have entries. Replace them?", conflicts))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("aborted by user")
+	}
+	return nil
+}
+
+func formatWeekPlansHuman(plans []weekPlan) string {
+	var b strings.Builder
+	for i, p := range plans {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "Week of %s (engagement %d):", timecard.FormatMDY(p.WeekStart), p.EngagementID)
+		for _, c := range p.Changes {
+			b.WriteString("\n  ")
+			b.WriteString(strings.ReplaceAll(formatDayChangeHuman(c), "\n", "\n  "))
+		}
+	}
+	return b.String()
+}
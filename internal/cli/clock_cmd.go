@@ -0,0 +1,233 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/clock"
+	"github.com/ihildy/magnit-vms-cli/internal/config"
+	"github.com/ihildy/magnit-vms-cli/internal/output"
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+
+	"github.com/spf13/cobra"
+)
+
+func newClockCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clock",
+		Short: "Punch in and out locally, then commit a day to the timecard",
+		Long: `Punch in and out locally, then commit a day to the timecard.
+
+Punches are kept next to the config file in clock.json, or in
+clock-<profile>.json for a named profile. "clock break" ends the current labor
+block and starts a lunch; "clock in" ends the break. "clock commit" turns a
+day's punches into labor and lunch spans, saves them and removes the committed
+punches.`,
+	}
+	cmd.AddCommand(newClockPunchCmd(app, clock.PunchIn, "Clock in, or end a break"))
+	cmd.AddCommand(newClockPunchCmd(app, clock.PunchOut, "Clock out"))
+	cmd.AddCommand(newClockPunchCmd(app, clock.PunchBreak, "Start a break"))
+	cmd.AddCommand(newClockStatusCmd(app))
+	cmd.AddCommand(newClockCommitCmd(app))
+	return cmd
+}
+
+func newClockPunchCmd(app *App, kind, short string) *cobra.Command {
+	var at string
+
+	cmd := &cobra.Command{
+		Use:   kind,
+		Short: short,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			loc, err := config.ResolveTimezone(app.Cfg)
+			if err != nil {
+				return err
+			}
+			when, err := parsePunchTime(at, time.Now().In(loc), loc)
+			if err != nil {
+				return err
+			}
+
+			path, state, err := loadClockState(app)
+			if err != nil {
+				return err
+			}
+			if err := state.Record(kind, when); err != nil {
+				return err
+			}
+			if err := clock.Save(path, state); err != nil {
+				return err
+			}
+
+			status, _ := state.Status()
+			payload := map[string]any{
+				"ok":        true,
+				"operation": "clock_" + kind,
+				"at":        when.Format(time.RFC3339),
+				"status":    status,
+			}
+			human := fmt.Sprintf("%s at %s", punchVerb(kind), when.Format("2006-01-02 15:04"))
+			return output.Write(app.Stdout, app.Output, human, payload)
+		},
+	}
+
+	cmd.Flags().StringVar(&at, "at", "", "Punch time as HH:MM (today) or YYYY-MM-DD HH:MM (default now)")
+	return cmd
+}
+
+func newClockStatusCmd(app *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show the current clock state and uncommitted punches",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			loc, err := config.ResolveTimezone(app.Cfg)
+			if err != nil {
+				return err
+			}
+			now := time.Now().In(loc)
+
+			_, state, err := loadClockState(app)
+			if err != nil {
+				return err
+			}
+			status, since := state.Status()
+			_, problems := state.Analyze(loc)
+			worked := state.WorkedHours(now, now, loc)
+
+			punches := make([]map[string]any, 0, len(state.Punches))
+			for _, p := range state.Punches {
+				punches = append(punches, map[string]any{"kind": p.Kind, "at": p.At.In(loc).Format(time.RFC3339)})
+			}
+			payload := map[string]any{
+				"ok":           true,
+				"operation":    "clock_status",
+				"status":       status,
+				"worked_today": worked,
+				"pending_days": state.Days(loc),
+				"punches":      punches,
+				"problems":     problems,
+			}
+			if !since.IsZero() {
+				payload["since"] = since.In(loc).Format(time.RFC3339)
+			}
+
+			var b strings.Builder
+			switch status {
+			case clock.StatusWorking:
+				fmt.Fprintf(&b, "Working since %s", since.In(loc).Format("15:04"))
+			case clock.StatusOnBreak:
+				fmt.Fprintf(&b, "On break since %s", since.In(loc).Format("15:04"))
+			default:
+				b.WriteString("Clocked out")
+			}
+			fmt.Fprintf(&b, "\nWorked today: %s", timecard.FormatDuration(worked))
+			if days := state.Days(loc); len(days) > 0 {
+				fmt.Fprintf(&b, "\nUncommitted days: %s", strings.Join(days, ", "))
+			}
+			for _, p := range problems {
+				fmt.Fprintf(&b, "\nProblem: %s: %s", p.Date, p.Message)
+			}
+			return output.Write(app.Stdout, app.Output, b.String(), payload)
+		},
+	}
+}
+
+func newClockCommitCmd(app *App) *cobra.Command {
+	var date string
+	var engagementID int64
+	var dryRun bool
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "commit [--date YYYY-MM-DD]",
+		Short: "Save a day's punches as labor and lunch spans",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			loc, err := config.ResolveTimezone(app.Cfg)
+			if err != nil {
+				return err
+			}
+			targetDate := time.Now().In(loc)
+			if date != "" {
+				targetDate, err = timecard.ParseDateYYYYMMDD(date, loc)
+				if err != nil {
+					return err
+				}
+			}
+
+			path, state, err := loadClockState(app)
+			if err != nil {
+				return err
+			}
+			intervals, err := state.DayIntervals(targetDate, loc)
+			if err != nil {
+				return err
+			}
+			edits, err := timecard.IntervalsToEdits(intervals, loc, timecard.IntervalOptions{})
+			if err != nil {
+				return err
+			}
+
+			if err := runBulkEdits(context.Background(), app, edits, bulkOptions{
+				Operation:    "clock_commit",
+				EngagementID: engagementID,
+				DryRun:       dryRun,
+				Yes:          yes,
+				Extra:        map[string]any{"date": targetDate.Format("2006-01-02")},
+			}); err != nil {
+				return err
+			}
+			if dryRun {
+				return nil
+			}
+			state.Prune(targetDate, loc)
+			return clock.Save(path, state)
+		},
+	}
+
+	cmd.Flags().StringVar(&date, "date", "", "Day to commit in YYYY-MM-DD (default today)")
+	cmd.Flags().Int64Var(&engagementID, "engagement", 0, "Engagement ID override")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Validate and show the diff without saving")
+	cmd.Flags().BoolVar(&yes, "yes", false, "Skip interactive conflict confirmation")
+	return cmd
+}
+
+func loadClockState(app *App) (string, clock.State, error) {
+	path, err := clock.StatePath(app.Profile)
+	if err != nil {
+		return "", clock.State{}, err
+	}
+	state, err := clock.Load(path)
+	if err != nil {
+		return "", clock.State{}, err
+	}
+	return path, state, nil
+}
+
+// parsePunchTime accepts "", HH:MM on now's date, or YYYY-MM-DD HH:MM.
+func parsePunchTime(value string, now time.Time, loc *time.Location) (time.Time, error) {
+	v := strings.TrimSpace(value)
+	if v == "" {
+		return now.Truncate(time.Minute), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", v, loc); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --at %q, expected HH:MM or YYYY-MM-DD HH:MM", value)
+	}
+	return time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, loc), nil
+}
+
+func punchVerb(kind string) string {
+	switch kind {
+	case clock.PunchIn:
+		return "Clocked in"
+	case clock.PunchOut:
+		return "Clocked out"
+	default:
+		return "Break started"
+	}
+}
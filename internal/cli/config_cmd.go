@@ -2,8 +2,11 @@ package cli
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/ihildy/magnit-vms-cli/internal/auth"
 	"github.com/ihildy/magnit-vms-cli/internal/keyring"
 	"github.com/ihildy/magnit-vms-cli/internal/output"
 
@@ -18,6 +21,9 @@ func newConfigCmd(app *App) *cobra.Command {
 	cmd.AddCommand(newConfigSetDefaultEngagementCmd(app))
 	cmd.AddCommand(newConfigSetTimezoneCmd(app))
 	cmd.AddCommand(newConfigSetCredentialStoreCmd(app))
+	cmd.AddCommand(newConfigSetOutputCmd(app))
+	cmd.AddCommand(newConfigSetHTTPCmd(app))
+	cmd.AddCommand(newConfigProfileCmd(app))
 	return cmd
 }
 
@@ -36,7 +42,7 @@ func newConfigSetDefaultEngagementCmd(app *App) *cobra.Command {
 			}
 			payload := map[string]any{"ok": true, "operation": "config_set_default_engagement", "default_engagement_id": engagementID, "config_path": app.CfgPath}
 			human := fmt.Sprintf("Default engagement set to %d", engagementID)
-			return output.Write(app.Stdout, app.JSONOutput, human, payload)
+			return output.Write(app.Stdout, app.Output, human, payload)
 		},
 	}
 	cmd.Flags().Int64Var(&engagementID, "id", 0, "Engagement ID")
@@ -62,7 +68,7 @@ func newConfigSetTimezoneCmd(app *App) *cobra.Command {
 			}
 			payload := map[string]any{"ok": true, "operation": "config_set_timezone", "timezone": timezone, "config_path": app.CfgPath}
 			human := fmt.Sprintf("Timezone set to %s", timezone)
-			return output.Write(app.Stdout, app.JSONOutput, human, payload)
+			return output.Write(app.Stdout, app.Output, human, payload)
 		},
 	}
 	cmd.Flags().StringVar(&timezone, "tz", "", "IANA timezone, e.g. America/Los_Angeles")
@@ -71,25 +77,155 @@ func newConfigSetTimezoneCmd(app *App) *cobra.Command {
 }
 
 func newConfigSetCredentialStoreCmd(app *App) *cobra.Command {
-	var store string
+	var store, helper string
 	cmd := &cobra.Command{
-		Use:   "set-credential-store --store <auto|keyring|file>",
+		Use:   "set-credential-store --store <auto|keyring|file|encrypted-file|command> [--helper CMD]",
 		Short: "Set credential storage backend",
+		Long: `Set credential storage backend.
+
+The command store runs an external helper instead of storing secrets itself:
+--helper names the executable and any arguments, and the CLI appends get,
+store or erase and exchanges JSON on stdin and stdout (see the README).`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			store = keyring.NormalizeCredentialStore(store)
 			if err := keyring.ValidateCredentialStore(store); err != nil {
 				return err
 			}
+			next := app.Cfg.CredentialHelper
+			if cmd.Flags().Changed("helper") {
+				next = strings.TrimSpace(helper)
+			}
+			if store == keyring.StoreCommand && next == "" {
+				return fmt.Errorf("--store %s needs --helper", keyring.StoreCommand)
+			}
 			app.Cfg.CredentialStore = store
+			app.Cfg.CredentialHelper = next
 			if err := app.SaveConfig(); err != nil {
 				return err
 			}
-			payload := map[string]any{"ok": true, "operation": "config_set_credential_store", "credential_store": store, "config_path": app.CfgPath}
+			payload := map[string]any{"ok": true, "operation": "config_set_credential_store", "credential_store": store, "credential_helper": next, "config_path": app.CfgPath}
 			human := fmt.Sprintf("Credential store set to %s", store)
-			return output.Write(app.Stdout, app.JSONOutput, human, payload)
+			if store == keyring.StoreCommand {
+				human += fmt.Sprintf(" (helper: %s)", next)
+			}
+			return output.Write(app.Stdout, app.Output, human, payload)
 		},
 	}
-	cmd.Flags().StringVar(&store, "store", keyring.StoreAuto, "Credential store backend: auto, keyring, file")
+	cmd.Flags().StringVar(&store, "store", keyring.StoreAuto, "Credential store backend: auto, keyring, file, encrypted-file, command")
+	cmd.Flags().StringVar(&helper, "helper", "", "Helper command line for the command store, e.g. \"magnit-pass-helper --vault work\"")
 	_ = cmd.MarkFlagRequired("store")
 	return cmd
 }
+
+func newConfigSetOutputCmd(app *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "set-output <" + strings.ReplaceAll(output.FormatNames(), ", ", "|") + ">",
+		Short: "Set default output format",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			parsed, err := output.ParseFormat(args[0])
+			if err != nil {
+				return err
+			}
+			app.Cfg.Output.Format = string(parsed)
+			app.Cfg.Output.JSONDefault = false
+			if err := app.SaveConfig(); err != nil {
+				return err
+			}
+			payload := map[string]any{"ok": true, "operation": "config_set_output", "output_format": parsed, "config_path": app.CfgPath}
+			human := fmt.Sprintf("Output format set to %s", parsed)
+			return output.Write(app.Stdout, app.Output, human, payload)
+		},
+	}
+}
+
+func newConfigSetHTTPCmd(app *App) *cobra.Command {
+	var proxy, caFile, clientCert, clientKey, timeout, userAgent string
+	cmd := &cobra.Command{
+		Use:   "set-http [--proxy URL] [--ca-file PEM] [--client-cert PEM --client-key PEM] [--timeout 45s] [--user-agent UA]",
+		Short: "Set proxy, TLS, timeout and User-Agent options for API requests",
+		Long: `Set proxy, TLS, timeout and User-Agent options for API requests.
+
+Only the flags given are changed; pass an empty value (e.g. --proxy "") to
+clear a setting. Without --proxy the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+environment variables apply. --ca-file adds a PEM bundle to the system roots,
+e.g. for a TLS-intercepting corporate proxy.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			next := app.Cfg.HTTP
+			changed := 0
+			paths := []struct {
+				flag  string
+				value string
+				dest  *string
+			}{
+				{"ca-file", caFile, &next.CAFile},
+				{"client-cert", clientCert, &next.ClientCert},
+				{"client-key", clientKey, &next.ClientKey},
+			}
+			for _, p := range paths {
+				if !cmd.Flags().Changed(p.flag) {
+					continue
+				}
+				changed++
+				*p.dest = ""
+				if value := strings.TrimSpace(p.value); value != "" {
+					abs, err := filepath.Abs(value)
+					if err != nil {
+						return fmt.Errorf("resolve --%s: %w", p.flag, err)
+					}
+					*p.dest = abs
+				}
+			}
+			values := []struct {
+				flag  string
+				value string
+				dest  *string
+			}{
+				{"proxy", proxy, &next.Proxy},
+				{"timeout", timeout, &next.Timeout},
+				{"user-agent", userAgent, &next.UserAgent},
+			}
+			for _, v := range values {
+				if cmd.Flags().Changed(v.flag) {
+					changed++
+					*v.dest = strings.TrimSpace(v.value)
+				}
+			}
+			if changed == 0 {
+				return fmt.Errorf("pass at least one of --proxy, --ca-file, --client-cert, --client-key, --timeout, --user-agent")
+			}
+
+			opts, err := httpOptions(next)
+			if err != nil {
+				return err
+			}
+			if _, err := auth.NewTransport(opts.Network); err != nil {
+				return err
+			}
+			app.Cfg.HTTP = next
+			if err := app.SaveConfig(); err != nil {
+				return err
+			}
+			payload := map[string]any{
+				"ok":          true,
+				"operation":   "config_set_http",
+				"proxy":       next.Proxy,
+				"ca_file":     next.CAFile,
+				"client_cert": next.ClientCert,
+				"client_key":  next.ClientKey,
+				"timeout":     next.Timeout,
+				"user_agent":  next.UserAgent,
+				"config_path": app.CfgPath,
+			}
+			human := "HTTP settings updated"
+			return output.Write(app.Stdout, app.Output, human, payload)
+		},
+	}
+	cmd.Flags().StringVar(&proxy, "proxy", "", "Proxy URL, e.g. http://proxy.corp:3128 (default from environment)")
+	cmd.Flags().StringVar(&caFile, "ca-file", "", "PEM bundle of extra trusted CA certificates")
+	cmd.Flags().StringVar(&clientCert, "client-cert", "", "PEM client certificate for mutual TLS")
+	cmd.Flags().StringVar(&clientKey, "client-key", "", "PEM private key for --client-cert")
+	cmd.Flags().StringVar(&timeout, "timeout", "", "Per-request timeout, e.g. 45s (default "+auth.DefaultTimeout.String()+")")
+	cmd.Flags().StringVar(&userAgent, "user-agent", "", "User-Agent header (default "+auth.DefaultUserAgent+")")
+	return cmd
+}
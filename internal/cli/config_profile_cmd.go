@@ -0,0 +1,224 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/clock"
+	"github.com/ihildy/magnit-vms-cli/internal/config"
+	"github.com/ihildy/magnit-vms-cli/internal/keyring"
+	"github.com/ihildy/magnit-vms-cli/internal/output"
+
+	"github.com/spf13/cobra"
+)
+
+func newConfigProfileCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage profiles for multiple accounts or tenants",
+		Long: `Manage profiles for multiple accounts or tenants.
+
+A profile scopes the base URL, default engagement, timezone, credential store
+and stored credentials. The settings at the top level of the config form the
+"default" profile. Select a profile per command with --profile or
+MAGNIT_PROFILE, or make one current with config profile use. Other config
+set-* commands change the selected profile.`,
+	}
+	cmd.AddCommand(newConfigProfileAddCmd(app))
+	cmd.AddCommand(newConfigProfileListCmd(app))
+	cmd.AddCommand(newConfigProfileUseCmd(app))
+	cmd.AddCommand(newConfigProfileRemoveCmd(app))
+	return cmd
+}
+
+func newConfigProfileAddCmd(app *App) *cobra.Command {
+	var profile config.Profile
+	var use bool
+	cmd := &cobra.Command{
+		Use:   "add <name> [--url URL] [--engagement ID] [--tz TZ] [--credential-store auto|keyring|file|encrypted-file] [--use]",
+		Short: "Add a profile",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if err := config.ValidateProfileName(name); err != nil {
+				return err
+			}
+			if app.fileCfg.HasProfile(name) {
+				return fmt.Errorf("profile %q already exists", name)
+			}
+			profile.BaseURL = strings.TrimRight(strings.TrimSpace(profile.BaseURL), "/")
+			if profile.BaseURL != "" {
+				if u, err := url.Parse(profile.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+					return fmt.Errorf("invalid base URL %q", profile.BaseURL)
+				}
+			}
+			if profile.DefaultEngagementID < 0 {
+				return fmt.Errorf("--engagement must be > 0")
+			}
+			if profile.Timezone != "" {
+				if _, err := time.LoadLocation(profile.Timezone); err != nil {
+					return fmt.Errorf("invalid timezone %q: %w", profile.Timezone, err)
+				}
+			}
+			if profile.CredentialStore != "" {
+				profile.CredentialStore = keyring.NormalizeCredentialStore(profile.CredentialStore)
+				if err := keyring.ValidateCredentialStore(profile.CredentialStore); err != nil {
+					return err
+				}
+			}
+			profile.CredentialHelper = strings.TrimSpace(profile.CredentialHelper)
+			if profile.CredentialStore == keyring.StoreCommand && profile.CredentialHelper == "" && app.fileCfg.CredentialHelper == "" {
+				return fmt.Errorf("--credential-store %s needs --credential-helper", keyring.StoreCommand)
+			}
+
+			if app.fileCfg.Profiles == nil {
+				app.fileCfg.Profiles = map[string]config.Profile{}
+			}
+			app.fileCfg.Profiles[name] = profile
+			if use {
+				app.fileCfg.CurrentProfile = name
+			}
+			if err := config.Save(app.fileCfg, app.CfgPath); err != nil {
+				return err
+			}
+			payload := map[string]any{
+				"ok":          true,
+				"operation":   "config_profile_add",
+				"profile":     name,
+				"current":     use,
+				"config_path": app.CfgPath,
+			}
+			human := fmt.Sprintf("Profile %s added; log in with `magnit --profile %s auth login`", name, name)
+			return output.Write(app.Stdout, app.Output, human, payload)
+		},
+	}
+	cmd.Flags().StringVar(&profile.BaseURL, "url", "", "API base URL (default from the top-level config)")
+	cmd.Flags().Int64Var(&profile.DefaultEngagementID, "engagement", 0, "Default engagement ID")
+	cmd.Flags().StringVar(&profile.Timezone, "tz", "", "IANA timezone (default from the top-level config)")
+	cmd.Flags().StringVar(&profile.CredentialStore, "credential-store", "", "Credential store backend: auto, keyring, file, encrypted-file, command (default from the top-level config)")
+	cmd.Flags().StringVar(&profile.CredentialHelper, "credential-helper", "", "Helper command for the command credential store (default from the top-level config)")
+	cmd.Flags().BoolVar(&use, "use", false, "Make the new profile current")
+	return cmd
+}
+
+func newConfigProfileListCmd(app *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List profiles and their settings",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rows := make([]map[string]any, 0, len(app.fileCfg.Profiles)+1)
+			lines := make([]string, 0, cap(rows))
+			for _, name := range app.fileCfg.ProfileNames() {
+				effective, err := app.fileCfg.ForProfile(name)
+				if err != nil {
+					return err
+				}
+				rows = append(rows, map[string]any{
+					"name":                  name,
+					"active":                name == app.Profile,
+					"base_url":              effective.BaseURL,
+					"default_engagement_id": effective.DefaultEngagementID,
+					"timezone":              effective.Timezone,
+					"credential_store":      keyring.NormalizeCredentialStore(effective.CredentialStore),
+					"credential_helper":     effective.CredentialHelper,
+				})
+				marker := " "
+				if name == app.Profile {
+					marker = "*"
+				}
+				line := fmt.Sprintf("%s %s  %s", marker, name, effective.BaseURL)
+				if effective.DefaultEngagementID > 0 {
+					line += fmt.Sprintf("  engagement %d", effective.DefaultEngagementID)
+				}
+				lines = append(lines, line)
+			}
+			payload := map[string]any{"ok": true, "operation": "config_profile_list", "active": app.Profile, "profiles": rows}
+			return output.Write(app.Stdout, app.Output, strings.Join(lines, "\n"), payload)
+		},
+	}
+}
+
+func newConfigProfileUseCmd(app *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "use <name>",
+		Short: "Make a profile current for later commands",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if !app.fileCfg.HasProfile(name) {
+				return fmt.Errorf("unknown profile %q", name)
+			}
+			app.fileCfg.CurrentProfile = name
+			if name == config.DefaultProfile {
+				app.fileCfg.CurrentProfile = ""
+			}
+			if err := config.Save(app.fileCfg, app.CfgPath); err != nil {
+				return err
+			}
+			payload := map[string]any{"ok": true, "operation": "config_profile_use", "profile": name, "config_path": app.CfgPath}
+			return output.Write(app.Stdout, app.Output, fmt.Sprintf("Using profile %s", name), payload)
+		},
+	}
+}
+
+func newConfigProfileRemoveCmd(app *App) *cobra.Command {
+	var keepCredentials bool
+	cmd := &cobra.Command{
+		Use:   "remove <name> [--keep-credentials]",
+		Short: "Remove a profile and its stored credentials",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if name == config.DefaultProfile {
+				return fmt.Errorf("the default profile cannot be removed")
+			}
+			if !app.fileCfg.HasProfile(name) {
+				return fmt.Errorf("unknown profile %q", name)
+			}
+			clockPath, err := clock.StatePath(name)
+			if err != nil {
+				return err
+			}
+			state, err := clock.Load(clockPath)
+			if err != nil {
+				return err
+			}
+			if len(state.Punches) > 0 {
+				return fmt.Errorf("profile %q has %d uncommitted punches; commit them with `magnit --profile %s clock commit` first", name, len(state.Punches), name)
+			}
+			if !keepCredentials {
+				effective, err := app.fileCfg.ForProfile(name)
+				if err != nil {
+					return err
+				}
+				if err := (keyring.Store{Backend: effective.CredentialStore, Profile: name, Prompt: app.promptPassphrase, Helper: effective.CredentialHelper}).DeleteCredentials(); err != nil {
+					return err
+				}
+			}
+			delete(app.fileCfg.Profiles, name)
+			if app.fileCfg.CurrentProfile == name {
+				app.fileCfg.CurrentProfile = ""
+			}
+			if err := config.Save(app.fileCfg, app.CfgPath); err != nil {
+				return err
+			}
+			if err := os.Remove(clockPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("remove %s: %w", clockPath, err)
+			}
+			payload := map[string]any{
+				"ok":                  true,
+				"operation":           "config_profile_remove",
+				"profile":             name,
+				"credentials_removed": !keepCredentials,
+				"config_path":         app.CfgPath,
+			}
+			return output.Write(app.Stdout, app.Output, fmt.Sprintf("Profile %s removed", name), payload)
+		},
+	}
+	cmd.Flags().BoolVar(&keepCredentials, "keep-credentials", false, "Leave the profile's stored credentials in place")
+	return cmd
+}
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/auth"
+	"github.com/ihildy/magnit-vms-cli/internal/fakevms"
+	"github.com/ihildy/magnit-vms-cli/internal/output"
+
+	"github.com/spf13/cobra"
+)
+
+func newDevCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dev",
+		Short: "Tools for developing and testing the CLI",
+	}
+	cmd.AddCommand(newDevFakeServerCmd(app))
+	return cmd
+}
+
+func newDevFakeServerCmd(app *App) *cobra.Command {
+	var addr string
+	var username string
+	var password string
+	var totpSecret string
+
+	cmd := &cobra.Command{
+		Use:   "fake-server",
+		Short: "Run an in-memory fake VMS server until interrupted",
+		Long: `Run an in-memory fake VMS server until interrupted.
+
+The server implements login, users/current, engagement-items, weekly metadata,
+worker/totalhours and saving billing items, with session cookies and XSRF
+checks. State is lost when it stops. Point the CLI at it with
+--base-url http://<addr> and log in with the fake credentials.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listener, err := net.Listen("tcp", addr)
+			if err != nil {
+				return fmt.Errorf("listen on %s: %w", addr, err)
+			}
+			fake := fakevms.New(username, password)
+			if totpSecret != "" {
+				if fake.TOTPSecret, err = auth.NormalizeTOTPSecret(totpSecret); err != nil {
+					listener.Close()
+					return err
+				}
+			}
+			server := &http.Server{Handler: fake.Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+			baseURL := "http://" + listener.Addr().String()
+			payload := map[string]any{
+				"ok":            true,
+				"operation":     "dev_fake_server",
+				"base_url":      baseURL,
+				"username":      username,
+				"engagement_id": fake.Engagements[0].ID,
+			}
+			human := fmt.Sprintf("Fake VMS listening on %s (username %s, engagement %d); press Ctrl-C to stop", baseURL, username, fake.Engagements[0].ID)
+			if err := output.Write(app.Stdout, app.Output, human, payload); err != nil {
+				listener.Close()
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				_ = server.Shutdown(shutdownCtx)
+			}()
+			if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&addr, "addr", "127.0.0.1:8089", "Address to listen on")
+	cmd.Flags().StringVar(&username, "username", "worker@example.com", "Username the fake server accepts")
+	cmd.Flags().StringVar(&password, "password", "password", "Password the fake server accepts")
+	cmd.Flags().StringVar(&totpSecret, "totp-secret", "", "Ask for a TOTP code generated from this base32 secret after the password")
+	return cmd
+}
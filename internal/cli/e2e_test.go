@@ -0,0 +1,537 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/auth"
+	"github.com/ihildy/magnit-vms-cli/internal/clock"
+	"github.com/ihildy/magnit-vms-cli/internal/fakevms"
+	"github.com/ihildy/magnit-vms-cli/internal/keyring"
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+)
+
+// runCLI runs one magnit invocation against a fresh App, as a separate
+// process would, and returns its stdout.
+func runCLI(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	app := &App{Stdout: &stdout, Stderr: &stderr, Stdin: strings.NewReader(stdin)}
+	cmd := newRootCmd(app)
+	cmd.SetArgs(args)
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	err := cmd.Execute()
+	return stdout.String(), err
+}
+
+func decodeOutput(t *testing.T, out string) map[string]any {
+	t.Helper()
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(out), &payload); err != nil {
+		t.Fatalf("decode output %q: %v", out, err)
+	}
+	return payload
+}
+
+// isolateHome points HOME and the config directory at a fresh directory and
+// selects the credential store, so each test starts like a new installation.
+func isolateHome(t *testing.T, store string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv(keyring.CredentialStoreEnvVar, store)
+	return home
+}
+
+type fakeEnv struct {
+	Home string
+	Fake *fakevms.Server
+	URL  string
+	// Base holds --base-url and --json for runCLI.
+	Base []string
+}
+
+// startFakeVMS isolates the home directory and serves a fake VMS that accepts
+// worker@example.com with password pw.
+func startFakeVMS(t *testing.T, store string) fakeEnv {
+	t.Helper()
+	home := isolateHome(t, store)
+	fake := fakevms.New("worker@example.com", "pw")
+	srv := httptest.NewServer(fake.Handler())
+	t.Cleanup(srv.Close)
+	return fakeEnv{Home: home, Fake: fake, URL: srv.URL, Base: []string{"--base-url", srv.URL, "--json"}}
+}
+
+func TestEndToEndAgainstFakeServer(t *testing.T) {
+	env := startFakeVMS(t, keyring.StoreFile)
+	fake, base := env.Fake, env.Base
+
+	if _, err := runCLI(t, "wrong\n", append(base, "auth", "login", "--username", "worker@example.com", "--password-stdin")...); err == nil {
+		t.Fatalf("expected login with a wrong password to fail")
+	}
+	if _, err := runCLI(t, "pw\n", append(base, "auth", "login", "--username", "worker@example.com", "--password-stdin")...); err != nil {
+		t.Fatalf("auth login: %v", err)
+	}
+	if _, err := runCLI(t, "", append(base, "config", "set-default-engagement", "--id", "1001")...); err != nil {
+		t.Fatalf("set default engagement: %v", err)
+	}
+
+	out, err := runCLI(t, "", append(base, "set", "--date", "2026-02-18", "--span", "labor:09:00-12:00", "--span", "lunch:12:00-12:30", "--span", "labor:12:30-17:00", "--yes")...)
+	if err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if payload := decodeOutput(t, out); payload["billing_item_id"] == float64(0) {
+		t.Fatalf("set did not report a billing item: %v", payload)
+	}
+
+	// An expired session is renewed transparently with the stored password.
+	fake.ExpireSessions()
+	if _, err := runCLI(t, "", append(base, "mark-dnw", "--date", "2026-02-19", "--yes")...); err != nil {
+		t.Fatalf("mark-dnw after expiry: %v", err)
+	}
+
+	out, err = runCLI(t, "", append(base, "show", "--date", "2026-02-18")...)
+	if err != nil {
+		t.Fatalf("show: %v", err)
+	}
+	if !strings.Contains(out, `"12:30"`) || !strings.Contains(out, `"lunch"`) {
+		t.Fatalf("show did not return the saved spans: %s", out)
+	}
+
+	dnwDay, _ := time.Parse("2006-01-02", "2026-02-19")
+	summary, err := timecard.FindDaySummary(fake.Week(1001, dnwDay), dnwDay)
+	if err != nil || !summary.DidNotWork {
+		t.Fatalf("expected 02/19 saved as did-not-work, got %+v, %v", summary, err)
+	}
+}
+
+func TestOfflineQueueThenSync(t *testing.T) {
+	isolateHome(t, keyring.StoreFile)
+
+	fake := fakevms.New("worker@example.com", "pw")
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	server := &http.Server{Handler: fake.Handler()}
+	go server.Serve(listener)
+	base := []string{"--base-url", "http://" + addr, "--json"}
+
+	if _, err := runCLI(t, "pw\n", append(base, "auth", "login", "--username", "worker@example.com", "--password-stdin")...); err != nil {
+		t.Fatalf("auth login: %v", err)
+	}
+	if _, err := runCLI(t, "", append(base, "config", "set-default-engagement", "--id", "1001")...); err != nil {
+		t.Fatalf("set default engagement: %v", err)
+	}
+	server.Close()
+
+	// Offline: both edits are queued instead of failing.
+	for _, args := range [][]string{
+		{"set", "--date", "2026-02-17", "--span", "labor:09:00-17:00", "--queue-offline"},
+		{"set", "--date", "2026-02-18", "--span", "labor:08:00-16:00", "--queue-offline"},
+	} {
+		out, err := runCLI(t, "", append(base, args...)...)
+		if err != nil {
+			t.Fatalf("%v while offline: %v", args, err)
+		}
+		if payload := decodeOutput(t, out); payload["queued"] != true {
+			t.Fatalf("expected the edit to be queued: %v", payload)
+		}
+	}
+	if _, err := runCLI(t, "", append(base, "set", "--date", "2026-02-19", "--span", "labor:09:00-17:00")...); err == nil {
+		t.Fatalf("expected set without --queue-offline to fail while offline")
+	}
+
+	listener, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("relisten: %v", err)
+	}
+	server = &http.Server{Handler: fake.Handler()}
+	go server.Serve(listener)
+	defer server.Close()
+
+	// Someone else filled 02/18 in the meantime. The edit was queued without
+	// a fetch, so there is no baseline to check the day against.
+	if _, err := runCLI(t, "", append(base, "set", "--date", "2026-02-18", "--span", "labor:10:00-12:00", "--yes")...); err != nil {
+		t.Fatalf("set while online: %v", err)
+	}
+
+	out, err := runCLI(t, "", append(base, "sync")...)
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	payload := decodeOutput(t, out)
+	if len(payload["synced"].([]any)) != 1 || len(payload["unverified"].([]any)) != 1 || len(payload["conflicts"].([]any)) != 0 {
+		t.Fatalf("expected one synced and one unverified change: %v", payload)
+	}
+	day, _ := time.Parse("2006-01-02", "2026-02-17")
+	if summary, _ := timecard.FindDaySummary(fake.Week(1001, day), day); len(summary.Spans) != 1 {
+		t.Fatalf("queued change was not saved: %+v", summary)
+	}
+
+	out, err = runCLI(t, "", append(base, "queue", "list")...)
+	if err != nil {
+		t.Fatalf("queue list: %v", err)
+	}
+	if entries := decodeOutput(t, out)["entries"].([]any); len(entries) != 1 {
+		t.Fatalf("expected the conflict to stay queued, got %v", entries)
+	}
+
+	out, err = runCLI(t, "", append(base, "sync", "--overwrite")...)
+	if err != nil {
+		t.Fatalf("sync --overwrite: %v", err)
+	}
+	if payload := decodeOutput(t, out); len(payload["synced"].([]any)) != 1 {
+		t.Fatalf("expected the conflict to be overwritten: %v", payload)
+	}
+	out, _ = runCLI(t, "", append(base, "queue", "list")...)
+	if entries := decodeOutput(t, out)["entries"].([]any); len(entries) != 0 {
+		t.Fatalf("expected an empty queue, got %v", entries)
+	}
+}
+
+func TestLoginWithTOTPAndSSOSession(t *testing.T) {
+	env := startFakeVMS(t, keyring.StoreFile)
+	fake, base := env.Fake, env.Base
+	fake.TOTPSecret = "JBSWY3DPEHPK3PXP"
+
+	_, err := runCLI(t, "pw\n", append(base, "auth", "login", "--username", "worker@example.com", "--password-stdin")...)
+	if err == nil || !strings.Contains(err.Error(), "auth mfa set-totp") {
+		t.Fatalf("expected login to ask for a TOTP secret, got %v", err)
+	}
+	out, err := runCLI(t, "jbsw y3dp ehpk 3pxp\n", append(base, "auth", "mfa", "set-totp", "--secret-stdin")...)
+	if err != nil {
+		t.Fatalf("set-totp: %v", err)
+	}
+	if code, _ := auth.TOTP(fake.TOTPSecret, time.Now()); decodeOutput(t, out)["current_code"] != code {
+		t.Fatalf("unexpected set-totp output %s", out)
+	}
+	if _, err := runCLI(t, "pw\n", append(base, "auth", "login", "--username", "worker@example.com", "--password-stdin")...); err != nil {
+		t.Fatalf("login with TOTP: %v", err)
+	}
+
+	// SSO: no password stored, only the session copied from the browser.
+	if _, err := runCLI(t, "", append(base, "auth", "logout")...); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+	if _, err := runCLI(t, "", append(base, "auth", "sso", "--cookie", "productionaccess_token=bogus")...); err == nil {
+		t.Fatalf("expected an unknown session to be rejected")
+	}
+	access, xsrf := fake.IssueSession()
+	cookie := "Cookie: productionaccess_token=" + access + "; XSRF-TOKEN=" + xsrf
+	if _, err := runCLI(t, cookie, append(base, "auth", "sso", "--cookie-stdin")...); err != nil {
+		t.Fatalf("auth sso: %v", err)
+	}
+	if out, err := runCLI(t, "", append(base, "auth", "status")...); err != nil || decodeOutput(t, out)["authenticated"] != true {
+		t.Fatalf("status after sso: %s, %v", out, err)
+	}
+	if _, err := runCLI(t, "", append(base, "set", "--engagement", "1001", "--date", "2026-02-18", "--span", "labor:09:00-17:00", "--yes")...); err != nil {
+		t.Fatalf("set with imported session: %v", err)
+	}
+}
+
+func TestSSOCallbackAcceptsPastedCookie(t *testing.T) {
+	env := startFakeVMS(t, keyring.StoreFile)
+	fake := env.Fake
+
+	var stderr bytes.Buffer
+	app := &App{Stdout: &bytes.Buffer{}, Stderr: &stderr, BaseURLOverride: env.URL}
+	for _, listen := range []string{":8765", "0.0.0.0:8765", "192.0.2.1:8765"} {
+		if _, err := app.ssoCallback(context.Background(), listen, env.URL, false, time.Second); err == nil || !strings.Contains(err.Error(), "loopback") {
+			t.Fatalf("expected --listen %s to be refused, got %v", listen, err)
+		}
+	}
+
+	results := make(chan map[string]any, 1)
+	callback := httptest.NewServer(app.ssoCallbackHandler(env.URL+"/login.html", "run-token", results))
+	defer callback.Close()
+
+	resp, err := http.Get(callback.URL + "/")
+	if err != nil {
+		t.Fatalf("get form: %v", err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(page), `value="run-token"`) {
+		t.Fatalf("form does not carry the token:\n%s", page)
+	}
+
+	post := func(form url.Values, origin string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, callback.URL+"/callback", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	access, xsrf := fake.IssueSession()
+	cookie := "productionaccess_token=" + access + "; XSRF-TOKEN=" + xsrf
+	if code := post(url.Values{"cookie": {cookie}}, ""); code != http.StatusForbidden {
+		t.Fatalf("expected a post without the token to be refused, got %d", code)
+	}
+	if code := post(url.Values{"cookie": {cookie}, "token": {"run-token"}}, "https://evil.example"); code != http.StatusForbidden {
+		t.Fatalf("expected a cross-origin post to be refused, got %d", code)
+	}
+	if code := post(url.Values{"cookie": {"productionaccess_token=bogus"}, "token": {"run-token"}}, callback.URL); code != http.StatusBadRequest || len(results) != 0 {
+		t.Fatalf("expected the bogus cookie to be rejected, got %d", code)
+	}
+
+	if code := post(url.Values{"cookie": {cookie}, "token": {"run-token"}}, callback.URL); code != http.StatusOK {
+		t.Fatalf("post cookie: %d", code)
+	}
+	select {
+	case user := <-results:
+		if user["email"] != "worker@example.com" {
+			t.Fatalf("unexpected user %v", user)
+		}
+	default:
+		t.Fatalf("expected the session to be accepted")
+	}
+	if _, err := keyring.LoadSessionWithStore(keyring.StoreFile); err != nil {
+		t.Fatalf("session was not saved: %v", err)
+	}
+}
+
+func TestSSOFailsWhenSessionCannotBeSaved(t *testing.T) {
+	env := startFakeVMS(t, keyring.StoreFile)
+	// A directory where the credentials file belongs makes every save fail.
+	if err := os.MkdirAll(filepath.Join(env.Home, ".config", "magnit-vms-cli", "credentials.yaml"), 0o700); err != nil {
+		t.Fatalf("block credentials file: %v", err)
+	}
+
+	access, xsrf := env.Fake.IssueSession()
+	_, err := runCLI(t, "", append(env.Base, "auth", "sso", "--cookie", "productionaccess_token="+access+"; XSRF-TOKEN="+xsrf)...)
+	if err == nil || !strings.Contains(err.Error(), "save session") {
+		t.Fatalf("expected auth sso to fail when the session cannot be saved, got %v", err)
+	}
+}
+
+func TestImportSessionFromCookiesFile(t *testing.T) {
+	env := startFakeVMS(t, keyring.StoreFile)
+	base := env.Base
+
+	access, xsrf := env.Fake.IssueSession()
+	file := filepath.Join(env.Home, "cookies.txt")
+	data := "# Netscape HTTP Cookie File\n" +
+		"#HttpOnly_127.0.0.1\tFALSE\t/\tFALSE\t0\tproductionaccess_token\t" + access + "\n" +
+		"127.0.0.1\tFALSE\t/\tFALSE\t0\tXSRF-TOKEN\t" + xsrf + "\n"
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+		t.Fatalf("write cookies: %v", err)
+	}
+
+	out, err := runCLI(t, "", append(base, "auth", "import-session", "--file", file)...)
+	if err != nil {
+		t.Fatalf("import-session: %v", err)
+	}
+	if payload := decodeOutput(t, out); payload["cookies"] != float64(2) {
+		t.Fatalf("unexpected import output %v", payload)
+	}
+	if _, err := keyring.LoadCredentialsWithStore(keyring.StoreFile); err == nil {
+		t.Fatalf("import-session must not store a password")
+	}
+	if _, err := runCLI(t, "", append(base, "set", "--engagement", "1001", "--date", "2026-02-18", "--span", "labor:09:00-17:00", "--yes")...); err != nil {
+		t.Fatalf("set with imported session: %v", err)
+	}
+}
+
+func TestProfilesKeepAccountsApart(t *testing.T) {
+	isolateHome(t, keyring.StoreFile)
+	t.Setenv("MAGNIT_PROFILE", "")
+
+	first := fakevms.New("first@example.com", "pw1")
+	firstSrv := httptest.NewServer(first.Handler())
+	defer firstSrv.Close()
+	second := fakevms.New("second@example.com", "pw2")
+	second.Engagements[0].ID = 2002
+	secondSrv := httptest.NewServer(second.Handler())
+	defer secondSrv.Close()
+
+	// The default profile points at the first server through the config.
+	if _, err := runCLI(t, "", "--json", "config", "profile", "add", "first", "--url", firstSrv.URL, "--engagement", "1001", "--use"); err != nil {
+		t.Fatalf("profile add first: %v", err)
+	}
+	if _, err := runCLI(t, "", "--json", "config", "profile", "add", "second", "--url", secondSrv.URL); err != nil {
+		t.Fatalf("profile add second: %v", err)
+	}
+	if _, err := runCLI(t, "", "--json", "config", "profile", "add", "second"); err == nil {
+		t.Fatalf("expected a duplicate profile to be rejected")
+	}
+
+	if _, err := runCLI(t, "pw1\n", "--json", "auth", "login", "--username", "first@example.com", "--password-stdin"); err != nil {
+		t.Fatalf("login first: %v", err)
+	}
+	if _, err := runCLI(t, "pw2\n", "--json", "--profile", "second", "auth", "login", "--username", "second@example.com", "--password-stdin"); err != nil {
+		t.Fatalf("login second: %v", err)
+	}
+	t.Setenv("MAGNIT_PROFILE", "second")
+	if _, err := runCLI(t, "", "--json", "config", "set-default-engagement", "--id", "2002"); err != nil {
+		t.Fatalf("set engagement for second: %v", err)
+	}
+	t.Setenv("MAGNIT_PROFILE", "")
+
+	for _, tc := range []struct {
+		args []string
+		fake *fakevms.Server
+		id   int64
+	}{
+		{[]string{"--json", "set", "--date", "2026-02-18", "--span", "labor:09:00-17:00", "--yes"}, first, 1001},
+		{[]string{"--json", "--profile", "second", "set", "--date", "2026-02-18", "--span", "labor:08:00-12:00", "--yes"}, second, 2002},
+	} {
+		if _, err := runCLI(t, "", tc.args...); err != nil {
+			t.Fatalf("%v: %v", tc.args, err)
+		}
+		day, _ := time.Parse("2006-01-02", "2026-02-18")
+		if summary, err := timecard.FindDaySummary(tc.fake.Week(tc.id, day), day); err != nil || len(summary.Spans) != 1 {
+			t.Fatalf("%v was not saved to its own account: %+v, %v", tc.args, summary, err)
+		}
+	}
+
+	out, err := runCLI(t, "", "--json", "config", "profile", "list")
+	if err != nil {
+		t.Fatalf("profile list: %v", err)
+	}
+	payload := decodeOutput(t, out)
+	if payload["active"] != "first" || len(payload["profiles"].([]any)) != 3 {
+		t.Fatalf("unexpected profile list %v", payload)
+	}
+
+	planPath := filepath.Join(t.TempDir(), "plan.json")
+	if _, err := runCLI(t, "", "--json", "plan", "--date", "2026-02-19", "--span", "labor:09:00-17:00", "--out", planPath); err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if _, err := runCLI(t, "", "--json", "--profile", "second", "--base-url", firstSrv.URL, "apply", planPath); err == nil || !strings.Contains(err.Error(), "profile first") {
+		t.Fatalf("expected a plan for another profile to be refused, got %v", err)
+	}
+
+	if _, err := runCLI(t, "", "--json", "--profile", "second", "clock", "in"); err != nil {
+		t.Fatalf("clock in: %v", err)
+	}
+	if _, err := runCLI(t, "", "--json", "config", "profile", "remove", "second"); err == nil || !strings.Contains(err.Error(), "uncommitted punches") {
+		t.Fatalf("expected remove to refuse pending punches, got %v", err)
+	}
+	clockPath, err := clock.StatePath("second")
+	if err != nil {
+		t.Fatalf("clock path: %v", err)
+	}
+	if err := clock.Save(clockPath, clock.State{}); err != nil {
+		t.Fatalf("clear punches: %v", err)
+	}
+	if _, err := runCLI(t, "", "--json", "config", "profile", "remove", "second"); err != nil {
+		t.Fatalf("profile remove: %v", err)
+	}
+	if _, err := os.Stat(clockPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the removed profile's punch log to be deleted, got %v", err)
+	}
+	if _, err := (keyring.Store{Backend: keyring.StoreFile, Profile: "second"}).LoadCredentials(); err == nil {
+		t.Fatalf("expected the removed profile's credentials to be deleted")
+	}
+	if _, err := (keyring.Store{Backend: keyring.StoreFile, Profile: "first"}).LoadCredentials(); err != nil {
+		t.Fatalf("first profile credentials: %v", err)
+	}
+	if _, err := runCLI(t, "", "--json", "--profile", "second", "engagement", "list"); err == nil || !strings.Contains(err.Error(), "unknown profile") {
+		t.Fatalf("expected an unknown profile error, got %v", err)
+	}
+}
+
+func TestProfileSelectedThroughEnvCanBeAdded(t *testing.T) {
+	isolateHome(t, keyring.StoreFile)
+	t.Setenv("MAGNIT_PROFILE", "work")
+
+	if _, err := runCLI(t, "", "--json", "engagement", "list"); err == nil || !strings.Contains(err.Error(), "unknown profile") {
+		t.Fatalf("expected an unknown profile error, got %v", err)
+	}
+	if _, err := runCLI(t, "", "--json", "config", "profile", "add", "work", "--url", "https://work.example.com"); err != nil {
+		t.Fatalf("profile add: %v", err)
+	}
+	out, err := runCLI(t, "", "--json", "config", "profile", "list")
+	if err != nil {
+		t.Fatalf("profile list: %v", err)
+	}
+	if payload := decodeOutput(t, out); payload["active"] != "work" || len(payload["profiles"].([]any)) != 2 {
+		t.Fatalf("unexpected profile list %v", payload)
+	}
+}
+
+func TestEncryptedFileStoreLoginAndRekey(t *testing.T) {
+	base := startFakeVMS(t, keyring.StoreEncryptedFile).Base
+	t.Setenv(keyring.PassphraseFDEnvVar, "")
+	t.Setenv(keyring.PassphraseEnvVar, "old passphrase")
+
+	if _, err := runCLI(t, "pw\n", append(base, "auth", "login", "--username", "worker@example.com", "--password-stdin")...); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if _, err := runCLI(t, "new passphrase\n", append(base, "auth", "rekey", "--new-passphrase-stdin")...); err != nil {
+		t.Fatalf("rekey: %v", err)
+	}
+	t.Setenv(keyring.PassphraseEnvVar, "new passphrase")
+	if out, err := runCLI(t, "", append(base, "auth", "status")...); err != nil || decodeOutput(t, out)["authenticated"] != true {
+		t.Fatalf("status with the new passphrase: %s, %v", out, err)
+	}
+}
+
+func TestCommandCredentialStoreKeepsSecretsOutOfConfigDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("helper script needs a POSIX shell")
+	}
+	env := startFakeVMS(t, "")
+	base := env.Base
+	t.Setenv(keyring.CredentialHelperEnvVar, "")
+
+	// The helper logs each request and knows no items, so every get misses.
+	vault := t.TempDir()
+	log := filepath.Join(vault, "requests.log")
+	script := filepath.Join(vault, "helper.sh")
+	body := "#!/bin/sh\n{ echo \"$1\"; cat; echo; } >> '" + log + "'\n[ \"$1\" = get ] && echo '{}'\nexit 0\n"
+	if err := os.WriteFile(script, []byte(body), 0o700); err != nil {
+		t.Fatalf("write helper: %v", err)
+	}
+
+	if _, err := runCLI(t, "", append(base, "config", "set-credential-store", "--store", "command")...); err == nil || !strings.Contains(err.Error(), "--helper") {
+		t.Fatalf("expected the command store to require --helper, got %v", err)
+	}
+	if _, err := runCLI(t, "", append(base, "config", "set-credential-store", "--store", "command", "--helper", script)...); err != nil {
+		t.Fatalf("set credential store: %v", err)
+	}
+	if _, err := runCLI(t, "pw\n", append(base, "auth", "login", "--username", "worker@example.com", "--password-stdin")...); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if _, err := runCLI(t, "", append(base, "auth", "logout")...); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("read helper log: %v", err)
+	}
+	for _, want := range []string{"store\n", `"username":"worker@example.com"`, `"password":"pw"`, `"profile":"default"`, "erase\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("helper log lacks %q:\n%s", want, data)
+		}
+	}
+	matches, _ := filepath.Glob(filepath.Join(env.Home, ".config", "*", "credentials*"))
+	if len(matches) != 0 {
+		t.Fatalf("expected no credential files, found %v", matches)
+	}
+}
@@ -39,8 +39,8 @@ func newEngagementCmd(app *App) *cobra.Command {
 				"engagements": items,
 			}
 
-			if app.JSONOutput {
-				return output.WriteJSON(app.Stdout, payload)
+			if !app.Output.IsHuman() {
+				return output.Write(app.Stdout, app.Output, "", payload)
 			}
 
 			if len(items) == 0 {
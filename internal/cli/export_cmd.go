@@ -0,0 +1,187 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/config"
+	"github.com/ihildy/magnit-vms-cli/internal/export"
+	"github.com/ihildy/magnit-vms-cli/internal/output"
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+
+	"github.com/spf13/cobra"
+)
+
+func newExportCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export logged time to files",
+	}
+	cmd.AddCommand(newExportCSVCmd(app))
+	cmd.AddCommand(newExportICSCmd(app))
+	return cmd
+}
+
+func newExportCSVCmd(app *App) *cobra.Command {
+	var from string
+	var to string
+	var engagementID int64
+	var layout string
+	var outPath string
+
+	cmd := &cobra.Command{
+		Use:   "csv --from YYYY-MM-DD --to YYYY-MM-DD",
+		Short: "Export logged spans for a date range as CSV",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := export.ValidateLayout(layout); err != nil {
+				return err
+			}
+			fromDate, toDate, err := parseDateRange(app, from, to)
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+			weeks, resolvedEngagement, err := fetchExportWeeks(ctx, app, engagementID, fromDate, toDate)
+			if err != nil {
+				return err
+			}
+
+			var buf bytes.Buffer
+			rowCount := len(weeks)
+			if layout == export.LayoutWeekly {
+				err = export.WriteWeeklyCSV(&buf, weeks, fromDate, toDate)
+			} else {
+				var rows []export.Row
+				rows, err = export.Rows(weeks, fromDate, toDate)
+				if err == nil {
+					rowCount = len(rows)
+					err = export.WriteRowsCSV(&buf, rows)
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("write csv: %w", err)
+			}
+
+			if outPath == "" {
+				_, err := app.Stdout.Write(buf.Bytes())
+				return err
+			}
+			if err := os.WriteFile(outPath, buf.Bytes(), 0o600); err != nil {
+				return fmt.Errorf("write %s: %w", outPath, err)
+			}
+
+			payload := map[string]any{
+				"ok":            true,
+				"operation":     "export_csv",
+				"engagement_id": resolvedEngagement,
+				"from":          from,
+				"to":            to,
+				"layout":        layout,
+				"rows":          rowCount,
+				"file":          outPath,
+			}
+			human := fmt.Sprintf("Wrote %d row(s) to %s", rowCount, outPath)
+			return output.Write(app.Stdout, app.Output, human, payload)
+		},
+	}
+
+	cmd.Flags().StringVar(&from, "from", "", "First date in YYYY-MM-DD")
+	cmd.Flags().StringVar(&to, "to", "", "Last date in YYYY-MM-DD")
+	cmd.Flags().Int64Var(&engagementID, "engagement", 0, "Engagement ID override")
+	cmd.Flags().StringVar(&layout, "layout", export.LayoutRows, "CSV layout: rows (one row per span) or weekly (one row per week)")
+	cmd.Flags().StringVar(&outPath, "out", "", "Write CSV to this file instead of stdout")
+	_ = cmd.MarkFlagRequired("from")
+	_ = cmd.MarkFlagRequired("to")
+	return cmd
+}
+
+func newExportICSCmd(app *App) *cobra.Command {
+	var from string
+	var to string
+	var engagementID int64
+	var outPath string
+
+	cmd := &cobra.Command{
+		Use:   "ics --from YYYY-MM-DD --to YYYY-MM-DD",
+		Short: "Export logged spans for a date range as an iCalendar file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fromDate, toDate, err := parseDateRange(app, from, to)
+			if err != nil {
+				return err
+			}
+			loc, err := config.ResolveTimezone(app.Cfg)
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+			weeks, resolvedEngagement, err := fetchExportWeeks(ctx, app, engagementID, fromDate, toDate)
+			if err != nil {
+				return err
+			}
+
+			var buf bytes.Buffer
+			events, err := export.WriteICS(&buf, weeks, fromDate, toDate, export.ICSOptions{Location: loc, Now: time.Now()})
+			if err != nil {
+				return fmt.Errorf("write ics: %w", err)
+			}
+
+			if outPath == "" {
+				_, err := app.Stdout.Write(buf.Bytes())
+				return err
+			}
+			if err := os.WriteFile(outPath, buf.Bytes(), 0o600); err != nil {
+				return fmt.Errorf("write %s: %w", outPath, err)
+			}
+
+			payload := map[string]any{
+				"ok":            true,
+				"operation":     "export_ics",
+				"engagement_id": resolvedEngagement,
+				"from":          from,
+				"to":            to,
+				"events":        events,
+				"file":          outPath,
+			}
+			human := fmt.Sprintf("Wrote %d event(s) to %s", events, outPath)
+			return output.Write(app.Stdout, app.Output, human, payload)
+		},
+	}
+
+	cmd.Flags().StringVar(&from, "from", "", "First date in YYYY-MM-DD")
+	cmd.Flags().StringVar(&to, "to", "", "Last date in YYYY-MM-DD")
+	cmd.Flags().Int64Var(&engagementID, "engagement", 0, "Engagement ID override")
+	cmd.Flags().StringVar(&outPath, "out", "", "Write the calendar to this file instead of stdout")
+	_ = cmd.MarkFlagRequired("from")
+	_ = cmd.MarkFlagRequired("to")
+	return cmd
+}
+
+func fetchExportWeeks(ctx context.Context, app *App, engagementID int64, from, to time.Time) ([]export.Week, int64, error) {
+	client, _, _, err := app.NewAuthedClient(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	resolvedEngagement, err := app.ResolveEngagementID(ctx, client, engagementID)
+	if err != nil {
+		return nil, 0, err
+	}
+	fetched, err := fetchWeeks(ctx, client, resolvedEngagement, from, to)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	weeks := make([]export.Week, 0, len(fetched))
+	for _, wk := range fetched {
+		days, err := timecard.WeekDaySummaries(wk.Metadata)
+		if err != nil {
+			return nil, 0, fmt.Errorf("week of %s: %w", wk.WeekStart.Format("2006-01-02"), err)
+		}
+		weeks = append(weeks, export.Week{EngagementID: resolvedEngagement, WeekStart: wk.WeekStart, Days: days})
+	}
+	return weeks, resolvedEngagement, nil
+}
@@ -0,0 +1,406 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/config"
+	"github.com/ihildy/magnit-vms-cli/internal/importer"
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+
+	"github.com/spf13/cobra"
+)
+
+func newImportCmd(app *App) *cobra.Command {
+	var file string
+	var engagementID int64
+	var dryRun bool
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "import --file hours.csv|hours.yaml",
+		Short: "Apply many days from a CSV or YAML plan file",
+		Long: `Apply many days from a CSV or YAML plan file.
+
+CSV files need a header row with a date column and any of engagement, spans,
+dnw and notes. Spans are separated by semicolons:
+
+  date,engagement,spans,dnw,notes
+  2026-02-16,,labor:09:00-12:00;lunch:12:00-12:30;labor:12:30-17:00,,
+  2026-02-17,,,true,PTO
+
+YAML files hold a list of days (optionally under a "days" key):
+
+  days:
+    - date: 2026-02-16
+      spans: [labor:09:00-12:00, lunch:12:00-12:30, labor:12:30-17:00]
+    - date: 2026-02-17
+      dnw: true
+      notes: PTO
+
+Days are grouped by engagement and week; each week is fetched and saved once.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if file == "" {
+				return fmt.Errorf("--file is required")
+			}
+			loc, err := config.ResolveTimezone(app.Cfg)
+			if err != nil {
+				return err
+			}
+			edits, err := importer.ParsePlanFile(file, loc)
+			if err != nil {
+				return err
+			}
+
+			return runBulkEdits(context.Background(), app, edits, bulkOptions{
+				Operation:    "import",
+				EngagementID: engagementID,
+				DryRun:       dryRun,
+				Yes:          yes,
+				Extra:        map[string]any{"file": file},
+			})
+		},
+	}
+
+	cmd.AddCommand(newImportICSCmd(app))
+	for _, format := range []string{importer.TrackerToggl, importer.TrackerClockify, importer.TrackerHarvest} {
+		cmd.AddCommand(newImportTrackerCmd(app, format))
+	}
+	for _, format := range []string{importer.TrackerTimewarrior, importer.TrackerWatson, importer.TrackerOrg} {
+		cmd.AddCommand(newImportTagTrackerCmd(app, format))
+	}
+
+	cmd.Flags().StringVar(&file, "file", "", "Plan file (.csv, .yaml or .yml)")
+	cmd.Flags().Int64Var(&engagementID, "engagement", 0, "Engagement ID for rows that do not name one")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Validate and show the combined diff without saving")
+	cmd.Flags().BoolVar(&yes, "yes", false, "Skip interactive conflict confirmation")
+	return cmd
+}
+
+func newImportICSCmd(app *App) *cobra.Command {
+	var file string
+	var match string
+	var lunchMatch string
+	var from string
+	var to string
+	var engagementID int64
+	var dryRun bool
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "ics --file work.ics --match PATTERN --from YYYY-MM-DD --to YYYY-MM-DD",
+		Short: "Apply calendar events from an .ics file as spans",
+		Long: `Apply calendar events from an .ics file as spans.
+
+Events whose title matches --match (a case-insensitive glob such as "Work*")
+become labor spans and those matching --lunch-match become lunch spans; lunch
+is carved out of overlapping labor. Recurring events (RRULE, EXDATE and
+modified instances) are expanded within --from/--to, and times are converted
+from each event's TZID into the configured timezone.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if file == "" {
+				return fmt.Errorf("--file is required")
+			}
+			for _, pattern := range []string{match, lunchMatch} {
+				if err := importer.ValidateGlob(pattern); err != nil {
+					return err
+				}
+			}
+			fromDate, toDate, err := parseDateRange(app, from, to)
+			if err != nil {
+				return err
+			}
+			loc, err := config.ResolveTimezone(app.Cfg)
+			if err != nil {
+				return err
+			}
+
+			f, err := os.Open(file)
+			if err != nil {
+				return fmt.Errorf("open %s: %w", file, err)
+			}
+			defer f.Close()
+			name := filepath.Base(file)
+			events, err := importer.ParseICS(f, name, loc)
+			if err != nil {
+				return err
+			}
+			intervals, err := importer.CalendarIntervals(events, name, importer.ICSOptions{
+				Match:      match,
+				LunchMatch: lunchMatch,
+				From:       fromDate,
+				To:         toDate,
+				Location:   loc,
+			})
+			if err != nil {
+				return err
+			}
+			edits, err := timecard.IntervalsToEdits(intervals, loc, timecard.IntervalOptions{})
+			if err != nil {
+				return err
+			}
+			if len(edits) == 0 {
+				return fmt.Errorf("no events matching %q between %s and %s", match, from, to)
+			}
+
+			return runBulkEdits(context.Background(), app, edits, bulkOptions{
+				Operation:    "import_ics",
+				EngagementID: engagementID,
+				DryRun:       dryRun,
+				Yes:          yes,
+				Extra:        map[string]any{"file": file, "events": len(intervals)},
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&file, "file", "", "Calendar file (.ics)")
+	cmd.Flags().StringVar(&match, "match", "", "Glob for titles of events that count as labor, e.g. \"Work*\"")
+	cmd.Flags().StringVar(&lunchMatch, "lunch-match", "Lunch*", "Glob for titles of events that count as lunch")
+	cmd.Flags().StringVar(&from, "from", "", "First date in YYYY-MM-DD")
+	cmd.Flags().StringVar(&to, "to", "", "Last date in YYYY-MM-DD")
+	cmd.Flags().Int64Var(&engagementID, "engagement", 0, "Engagement ID override")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Validate and show the combined diff without saving")
+	cmd.Flags().BoolVar(&yes, "yes", false, "Skip interactive conflict confirmation")
+	_ = cmd.MarkFlagRequired("file")
+	_ = cmd.MarkFlagRequired("match")
+	_ = cmd.MarkFlagRequired("from")
+	_ = cmd.MarkFlagRequired("to")
+	return cmd
+}
+
+func newImportTrackerCmd(app *App, format string) *cobra.Command {
+	var file string
+	var project string
+	var engagementID int64
+	var dryRun bool
+	var yes bool
+	var rules intervalRuleFlags
+
+	cmd := &cobra.Command{
+		Use:   format + " --file export.csv",
+		Short: fmt.Sprintf("Apply a %s CSV export as spans", strings.ToUpper(format[:1])+format[1:]),
+		Long: fmt.Sprintf(`Apply a %s detailed CSV export as spans.
+
+Entries are converted to labor intervals in the configured timezone, entries
+separated by at most --merge-gap are merged, and the first gap per day between
+--lunch-gap-min and --lunch-gap-max becomes a lunch span. Exports without
+start/end times (Harvest) are laid end to end from --day-start.`, format),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if file == "" {
+				return fmt.Errorf("--file is required")
+			}
+			loc, err := config.ResolveTimezone(app.Cfg)
+			if err != nil {
+				return err
+			}
+			intervalOpts, dayStart, err := rules.resolve(app.Cfg.Import)
+			if err != nil {
+				return err
+			}
+
+			f, err := os.Open(file)
+			if err != nil {
+				return fmt.Errorf("open %s: %w", file, err)
+			}
+			defer f.Close()
+			intervals, err := importer.ParseTrackerCSV(f, filepath.Base(file), format, importer.TrackerOptions{
+				Location: loc,
+				Project:  project,
+				DayStart: dayStart,
+			})
+			if err != nil {
+				return err
+			}
+			edits, err := timecard.IntervalsToEdits(intervals, loc, intervalOpts)
+			if err != nil {
+				return err
+			}
+			if len(edits) == 0 {
+				return fmt.Errorf("no entries found in %s", file)
+			}
+
+			return runBulkEdits(context.Background(), app, edits, bulkOptions{
+				Operation:    "import_" + format,
+				EngagementID: engagementID,
+				DryRun:       dryRun,
+				Yes:          yes,
+				Extra:        map[string]any{"file": file, "entries": len(intervals)},
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&file, "file", "", "CSV export file")
+	cmd.Flags().StringVar(&project, "project", "", "Only import entries whose project matches this glob")
+	rules.bind(cmd)
+	cmd.Flags().Int64Var(&engagementID, "engagement", 0, "Engagement ID override")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Validate and show the combined diff without saving")
+	cmd.Flags().BoolVar(&yes, "yes", false, "Skip interactive conflict confirmation")
+	_ = cmd.MarkFlagRequired("file")
+	return cmd
+}
+
+var tagTrackerHelp = map[string]struct{ use, short, source string }{
+	importer.TrackerTimewarrior: {"timewarrior --file timew.json", "Apply a Timewarrior export as spans", "the JSON printed by `timew export`"},
+	importer.TrackerWatson:      {"watson --file frames", "Apply Watson frames as spans", "Watson's frames file or `watson log --json` output; projects count as tags"},
+	importer.TrackerOrg:         {"org --file notes.org", "Apply org-mode CLOCK lines as spans", "CLOCK: lines of an org-mode file; headline tags are inherited"},
+}
+
+func newImportTagTrackerCmd(app *App, format string) *cobra.Command {
+	var file string
+	var tags []string
+	var engagementID int64
+	var dryRun bool
+	var yes bool
+	var rules intervalRuleFlags
+
+	help := tagTrackerHelp[format]
+	cmd := &cobra.Command{
+		Use:   help.use,
+		Short: help.short,
+		Long: fmt.Sprintf(`%s.
+
+Reads %s.
+Only intervals carrying one of --tag are imported; without --tag the tags
+listed for the engagement under import.engagement_tags in the config are used,
+and with neither every interval is imported. Intervals are converted to spans
+per day in the configured timezone using the same merge and lunch rules as the
+CSV tracker importers.`, help.short, help.source),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if file == "" {
+				return fmt.Errorf("--file is required")
+			}
+			loc, err := config.ResolveTimezone(app.Cfg)
+			if err != nil {
+				return err
+			}
+			intervalOpts, _, err := rules.resolve(app.Cfg.Import)
+			if err != nil {
+				return err
+			}
+			if len(tags) == 0 {
+				tags = engagementTags(app.Cfg, engagementID)
+			}
+
+			f, err := os.Open(file)
+			if err != nil {
+				return fmt.Errorf("open %s: %w", file, err)
+			}
+			defer f.Close()
+			intervals, err := importer.ParseTagTracker(f, filepath.Base(file), format, importer.TagOptions{
+				Location: loc,
+				Tags:     tags,
+			})
+			if err != nil {
+				return err
+			}
+			edits, err := timecard.IntervalsToEdits(intervals, loc, intervalOpts)
+			if err != nil {
+				return err
+			}
+			if len(edits) == 0 {
+				if len(tags) > 0 {
+					return fmt.Errorf("no intervals tagged %s found in %s", strings.Join(tags, ", "), file)
+				}
+				return fmt.Errorf("no intervals found in %s", file)
+			}
+
+			return runBulkEdits(context.Background(), app, edits, bulkOptions{
+				Operation:    "import_" + format,
+				EngagementID: engagementID,
+				DryRun:       dryRun,
+				Yes:          yes,
+				Extra:        map[string]any{"file": file, "entries": len(intervals), "tags": tags},
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&file, "file", "", "Tracker export file")
+	cmd.Flags().StringSliceVar(&tags, "tag", nil, "Only import intervals with this tag (repeatable)")
+	rules.bind(cmd)
+	// Every interval from these trackers has timestamps.
+	_ = cmd.Flags().MarkHidden("day-start")
+	cmd.Flags().Int64Var(&engagementID, "engagement", 0, "Engagement ID override")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Validate and show the combined diff without saving")
+	cmd.Flags().BoolVar(&yes, "yes", false, "Skip interactive conflict confirmation")
+	_ = cmd.MarkFlagRequired("file")
+	return cmd
+}
+
+// engagementTags returns the configured tags for the --engagement override,
+// falling back to the default engagement.
+func engagementTags(cfg config.Config, override int64) []string {
+	id := override
+	if id <= 0 {
+		id = cfg.DefaultEngagementID
+	}
+	if id <= 0 {
+		return nil
+	}
+	return cfg.Import.EngagementTags[id]
+}
+
+const (
+	defaultMergeGap    = "5m"
+	defaultLunchGapMin = "15m"
+	defaultLunchGapMax = "90m"
+	defaultDayStart    = "09:00"
+)
+
+// intervalRuleFlags are the interval-to-span settings shared by tracker
+// importers. Empty flags fall back to the import section of the config.
+type intervalRuleFlags struct {
+	mergeGap    string
+	lunchGapMin string
+	lunchGapMax string
+	dayStart    string
+}
+
+func (f *intervalRuleFlags) bind(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.mergeGap, "merge-gap", "", "Merge entries separated by at most this gap (default from config, else "+defaultMergeGap+")")
+	cmd.Flags().StringVar(&f.lunchGapMin, "lunch-gap-min", "", "Shortest gap treated as lunch (default from config, else "+defaultLunchGapMin+")")
+	cmd.Flags().StringVar(&f.lunchGapMax, "lunch-gap-max", "", "Longest gap treated as lunch; 0 disables (default from config, else "+defaultLunchGapMax+")")
+	cmd.Flags().StringVar(&f.dayStart, "day-start", "", "HH:MM to start days whose entries have no times (default from config, else "+defaultDayStart+")")
+}
+
+func (f *intervalRuleFlags) resolve(cfg config.ImportConfig) (timecard.IntervalOptions, int, error) {
+	pick := func(flagValue, cfgValue, fallback string) string {
+		if strings.TrimSpace(flagValue) != "" {
+			return strings.TrimSpace(flagValue)
+		}
+		if strings.TrimSpace(cfgValue) != "" {
+			return strings.TrimSpace(cfgValue)
+		}
+		return fallback
+	}
+	var opts timecard.IntervalOptions
+	settings := []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"merge-gap", pick(f.mergeGap, cfg.MergeGap, defaultMergeGap), &opts.MergeGap},
+		{"lunch-gap-min", pick(f.lunchGapMin, cfg.LunchGapMin, defaultLunchGapMin), &opts.LunchGapMin},
+		{"lunch-gap-max", pick(f.lunchGapMax, cfg.LunchGapMax, defaultLunchGapMax), &opts.LunchGapMax},
+	}
+	for _, s := range settings {
+		d, err := time.ParseDuration(s.value)
+		if err != nil || d < 0 {
+			return timecard.IntervalOptions{}, 0, fmt.Errorf("invalid --%s %q", s.name, s.value)
+		}
+		*s.dest = d
+	}
+	if opts.LunchGapMax > 0 && opts.LunchGapMin > opts.LunchGapMax {
+		return timecard.IntervalOptions{}, 0, fmt.Errorf("--lunch-gap-min must not exceed --lunch-gap-max")
+	}
+
+	dayStart := pick(f.dayStart, cfg.DayStart, defaultDayStart)
+	t, err := time.Parse("15:04", dayStart)
+	if err != nil {
+		return timecard.IntervalOptions{}, 0, fmt.Errorf("invalid --day-start %q, expected HH:MM", dayStart)
+	}
+	return opts, t.Hour()*60 + t.Minute(), nil
+}
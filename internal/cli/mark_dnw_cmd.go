@@ -7,6 +7,7 @@ import (
 	"github.com/ihildy/magnit-vms-cli/internal/auth"
 	"github.com/ihildy/magnit-vms-cli/internal/config"
 	"github.com/ihildy/magnit-vms-cli/internal/output"
+	"github.com/ihildy/magnit-vms-cli/internal/queue"
 	"github.com/ihildy/magnit-vms-cli/internal/timecard"
 
 	"github.com/spf13/cobra"
@@ -17,11 +18,12 @@ func newMarkDNWCmd(app *App) *cobra.Command {
 	var engagementID int64
 	var dryRun bool
 	var yes bool
+	var queueOffline bool
 
 	cmd := &cobra.Command{
 		Use:   "mark-dnw --date YYYY-MM-DD",
 		Short: "Mark a day as did-not-work",
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
 			if date == "" {
 				return fmt.Errorf("--date is required")
 			}
@@ -35,6 +37,15 @@ func newMarkDNWCmd(app *App) *cobra.Command {
 				return err
 			}
 
+			var baseline *timecard.DaySummary
+			if queueOffline && !dryRun {
+				entry := queue.Entry{Operation: queue.OpMarkDNW, EngagementID: engagementID, Date: date, DidNotWork: true}
+				defer func() {
+					entry.Baseline = baseline
+					err = queueIfOffline(app, err, entry)
+				}()
+			}
+
 			ctx := context.Background()
 			client, _, httpCtx, err := app.NewAuthedClient(ctx)
 			if err != nil {
@@ -57,6 +68,8 @@ func newMarkDNWCmd(app *App) *cobra.Command {
 				return err
 			}
 
+			baseline = &change.Existing
+
 			if err := confirmConflict(app, change, yes); err != nil {
 				return err
 			}
@@ -72,7 +85,7 @@ func newMarkDNWCmd(app *App) *cobra.Command {
 					"payload":       patched,
 				}
 				human := "Dry run complete\n" + formatDayChangeHuman(change)
-				return output.Write(app.Stdout, app.JSONOutput, human, payload)
+				return output.Write(app.Stdout, app.Output, human, payload)
 			}
 
 			xsrf, err := auth.ExtractXSRFToken(httpCtx.Auth.Client, app.BaseURL())
@@ -101,7 +114,7 @@ func newMarkDNWCmd(app *App) *cobra.Command {
 				"total_hours":     totalHours,
 			}
 			human := fmt.Sprintf("Marked %s as did-not-work (billingItemId=%d)", date, saveResp.BillingItemID)
-			return output.Write(app.Stdout, app.JSONOutput, human, payload)
+			return output.Write(app.Stdout, app.Output, human, payload)
 		},
 	}
 
@@ -109,6 +122,7 @@ func newMarkDNWCmd(app *App) *cobra.Command {
 	cmd.Flags().Int64Var(&engagementID, "engagement", 0, "Engagement ID override")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Validate and show payload diff without saving")
 	cmd.Flags().BoolVar(&yes, "yes", false, "Skip interactive conflict confirmation")
+	cmd.Flags().BoolVar(&queueOffline, "queue-offline", false, "Queue the change for a later sync when the server cannot be reached")
 	_ = cmd.MarkFlagRequired("date")
 	return cmd
 }
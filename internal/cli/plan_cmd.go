@@ -0,0 +1,240 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/api"
+	"github.com/ihildy/magnit-vms-cli/internal/config"
+	"github.com/ihildy/magnit-vms-cli/internal/importer"
+	"github.com/ihildy/magnit-vms-cli/internal/output"
+	"github.com/ihildy/magnit-vms-cli/internal/plan"
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+
+	"github.com/spf13/cobra"
+)
+
+func newPlanCmd(app *App) *cobra.Command {
+	var file string
+	var date string
+	var spanArgs []string
+	var dnw bool
+	var notes string
+	var engagementID int64
+	var out string
+
+	cmd := &cobra.Command{
+		Use:   "plan (--file hours.csv | --date YYYY-MM-DD --span ... | --date YYYY-MM-DD --dnw) [--out plan.json]",
+		Short: "Compute proposed changes and write them to a plan file for review",
+		Long: `Compute proposed changes and write them to a plan file for review.
+
+Nothing is saved. The plan records every proposed day change, the full payload
+for each affected week and a fingerprint of the week as fetched. Review it,
+then run "magnit apply plan.json"; apply refuses to save if any week changed on
+the server since the plan was made.
+
+Input is either a plan file accepted by "magnit import" (--file) or a single
+day given with --date and --span or --dnw.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			loc, err := config.ResolveTimezone(app.Cfg)
+			if err != nil {
+				return err
+			}
+			edits, err := planInputEdits(file, date, spanArgs, dnw, notes, cmd.Flags().Changed("notes"), loc)
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+			client, _, _, err := app.NewAuthedClient(ctx)
+			if err != nil {
+				return err
+			}
+			if err := fillDefaultEngagement(ctx, app, client, edits, engagementID); err != nil {
+				return err
+			}
+			plans, err := planWeekEdits(ctx, client, edits)
+			if err != nil {
+				return err
+			}
+
+			pf := plan.File{
+				Version:   plan.Version,
+				CreatedAt: time.Now().UTC().Truncate(time.Second),
+				BaseURL:   app.BaseURL(),
+				Profile:   recordedProfile(app),
+			}
+			for _, p := range plans {
+				fingerprint, err := plan.Fingerprint(p.Original)
+				if err != nil {
+					return err
+				}
+				pf.Weeks = append(pf.Weeks, plan.Week{
+					EngagementID: p.EngagementID,
+					WeekStart:    timecard.FormatMDY(p.WeekStart),
+					Fingerprint:  fingerprint,
+					Changes:      p.Changes,
+					Payload:      p.Patched,
+				})
+			}
+			if err := plan.Write(out, pf); err != nil {
+				return err
+			}
+
+			payload := map[string]any{
+				"ok":        true,
+				"operation": "plan",
+				"plan_file": out,
+				"days":      len(edits),
+				"weeks":     pf.Weeks,
+			}
+			human := fmt.Sprintf("Plan written to %s (%d day(s) across %d week(s))\n%s\n\nReview, then run: magnit apply %s",
+				out, len(edits), len(plans), formatWeekPlansHuman(plans), out)
+			return output.Write(app.Stdout, app.Output, human, payload)
+		},
+	}
+
+	cmd.Flags().StringVar(&file, "file", "", "Plan input file (.csv, .yaml or .yml) in the import format")
+	cmd.Flags().StringVar(&date, "date", "", "Single day in YYYY-MM-DD")
+	cmd.Flags().StringSliceVar(&spanArgs, "span", nil, "Span for --date in form type:HH:MM-HH:MM (type: labor|lunch)")
+	cmd.Flags().BoolVar(&dnw, "dnw", false, "Mark --date as did-not-work")
+	cmd.Flags().StringVar(&notes, "notes", "", "Notes for --date")
+	cmd.Flags().Int64Var(&engagementID, "engagement", 0, "Engagement ID for days that do not name one")
+	cmd.Flags().StringVar(&out, "out", "plan.json", "Where to write the plan")
+	return cmd
+}
+
+func newApplyCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apply plan.json",
+		Short: "Save a reviewed plan if the server still matches it",
+		Long: `Save a reviewed plan if the server still matches it.
+
+Every week in the plan is fetched again and compared with the fingerprint
+recorded by "magnit plan". If any week differs, nothing is saved and the plan
+must be recreated. Running apply is the approval, so there is no prompt.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := args[0]
+			pf, err := plan.Read(path)
+			if err != nil {
+				return err
+			}
+			if !strings.EqualFold(strings.TrimRight(pf.BaseURL, "/"), app.BaseURL()) {
+				return fmt.Errorf("plan was made against %s but the current base URL is %s", pf.BaseURL, app.BaseURL())
+			}
+			if pf.Profile != recordedProfile(app) {
+				planProfile := pf.Profile
+				if planProfile == "" {
+					planProfile = config.DefaultProfile
+				}
+				return fmt.Errorf("plan was made for profile %s but the current profile is %s", planProfile, app.Profile)
+			}
+
+			ctx := context.Background()
+			client, _, httpCtx, err := app.NewAuthedClient(ctx)
+			if err != nil {
+				return err
+			}
+
+			reqs := make([]api.WeekRequest, len(pf.Weeks))
+			for i, w := range pf.Weeks {
+				reqs[i] = api.WeekRequest{EngagementID: w.EngagementID, WeekStart: w.WeekStart}
+			}
+			fetched, err := client.FetchWeeks(ctx, reqs)
+			if err != nil {
+				return err
+			}
+
+			plans := make([]weekPlan, 0, len(pf.Weeks))
+			var stale []string
+			for i, w := range pf.Weeks {
+				weekStart, err := time.ParseInLocation("01/02/2006", w.WeekStart, time.UTC)
+				if err != nil {
+					return fmt.Errorf("plan week %q: %w", w.WeekStart, err)
+				}
+				current := fetched[i]
+				fingerprint, err := plan.Fingerprint(current)
+				if err != nil {
+					return err
+				}
+				if fingerprint != w.Fingerprint {
+					stale = append(stale, fmt.Sprintf("week of %s (engagement %d)", w.WeekStart, w.EngagementID))
+				}
+				plans = append(plans, weekPlan{
+					EngagementID: w.EngagementID,
+					WeekStart:    weekStart,
+					Original:     current,
+					Patched:      w.Payload,
+					Changes:      w.Changes,
+				})
+			}
+			if len(stale) > 0 {
+				return fmt.Errorf("server state changed since the plan was made; nothing was saved. Re-run `magnit plan`. Changed: %s", strings.Join(stale, ", "))
+			}
+
+			outcomes, err := saveWeekPlans(ctx, app, client, httpCtx, plans)
+			if err != nil {
+				return err
+			}
+
+			results := make([]weekResult, 0, len(plans))
+			days := 0
+			for i, p := range plans {
+				days += len(p.Changes)
+				results = append(results, weekResult{
+					EngagementID:  p.EngagementID,
+					WeekStart:     timecard.FormatMDY(p.WeekStart),
+					Changes:       p.Changes,
+					BillingItemID: outcomes[i].BillingItemID,
+					Status:        outcomes[i].Status,
+				})
+			}
+			payload := map[string]any{
+				"ok":        true,
+				"operation": "apply",
+				"plan_file": path,
+				"days":      days,
+				"weeks":     results,
+			}
+			human := fmt.Sprintf("Applied %s: saved %d day(s) across %d week(s)\n%s", path, days, len(plans), formatWeekPlansHuman(plans))
+			return output.Write(app.Stdout, app.Output, human, payload)
+		},
+	}
+	return cmd
+}
+
+func planInputEdits(file, date string, spanArgs []string, dnw bool, notes string, notesSet bool, loc *time.Location) ([]timecard.DayEdit, error) {
+	switch {
+	case file != "" && date != "":
+		return nil, fmt.Errorf("use either --file or --date, not both")
+	case file != "":
+		if len(spanArgs) > 0 || dnw || notesSet {
+			return nil, fmt.Errorf("--span, --dnw and --notes only apply with --date")
+		}
+		return importer.ParsePlanFile(file, loc)
+	case date == "":
+		return nil, fmt.Errorf("--file or --date is required")
+	}
+
+	if dnw == (len(spanArgs) > 0) {
+		return nil, fmt.Errorf("--date needs either --span or --dnw")
+	}
+	targetDate, err := timecard.ParseDateYYYYMMDD(date, loc)
+	if err != nil {
+		return nil, err
+	}
+	edit := timecard.DayEdit{Date: targetDate, DidNotWork: dnw}
+	if !dnw {
+		edit.Spans, err = parseAndValidateSpans(spanArgs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if notesSet {
+		edit.Notes = &notes
+	}
+	return []timecard.DayEdit{edit}, nil
+}
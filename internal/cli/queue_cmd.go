@@ -0,0 +1,393 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/api"
+	"github.com/ihildy/magnit-vms-cli/internal/config"
+	"github.com/ihildy/magnit-vms-cli/internal/output"
+	"github.com/ihildy/magnit-vms-cli/internal/queue"
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+
+	"github.com/spf13/cobra"
+)
+
+// recordedProfile is the profile recorded on queue entries and plans; the
+// default profile is left empty so files written before profiles existed
+// still match.
+func recordedProfile(app *App) string {
+	if app.Profile == config.DefaultProfile {
+		return ""
+	}
+	return app.Profile
+}
+
+// queueIfOffline turns an error showing the server could not be reached into
+// a queued entry for `magnit sync`. Any other error is returned unchanged.
+func queueIfOffline(app *App, err error, entry queue.Entry) error {
+	if err == nil || !api.Unreachable(err) {
+		return err
+	}
+	if entry.EngagementID == 0 {
+		entry.EngagementID = app.Cfg.DefaultEngagementID
+	}
+	if entry.EngagementID == 0 {
+		return fmt.Errorf("%w; cannot queue without an engagement, pass --engagement or set a default", err)
+	}
+	entry.BaseURL = app.BaseURL()
+	entry.Profile = recordedProfile(app)
+	entry.QueuedAt = time.Now().UTC()
+	entry.Reason = err.Error()
+
+	path, q, qerr := loadQueue()
+	if qerr != nil {
+		return fmt.Errorf("%v; also failed to queue the change: %w", err, qerr)
+	}
+	added, replaced := q.Add(entry)
+	if qerr := queue.Save(path, q); qerr != nil {
+		return fmt.Errorf("%v; also failed to queue the change: %w", err, qerr)
+	}
+
+	payload := map[string]any{
+		"ok":            true,
+		"operation":     entry.Operation,
+		"date":          entry.Date,
+		"engagement_id": entry.EngagementID,
+		"queued":        true,
+		"queue_id":      added.ID,
+		"replaced":      replaced,
+		"reason":        entry.Reason,
+	}
+	human := fmt.Sprintf("Server unreachable; queued #%d (%s). Run `magnit sync` when back online.", added.ID, describeQueueEntry(added))
+	if replaced {
+		human += " It replaces an earlier queued change for the same day."
+	}
+	return output.Write(app.Stdout, app.Output, human, payload)
+}
+
+func loadQueue() (string, queue.Queue, error) {
+	path, err := queue.Path()
+	if err != nil {
+		return "", queue.Queue{}, err
+	}
+	q, err := queue.Load(path)
+	if err != nil {
+		return "", queue.Queue{}, err
+	}
+	return path, q, nil
+}
+
+func describeQueueEntry(e queue.Entry) string {
+	what := "did not work"
+	if !e.DidNotWork {
+		what = strings.Join(e.Spans, ", ")
+	}
+	return fmt.Sprintf("%s engagement %d: %s", e.Date, e.EngagementID, what)
+}
+
+func newQueueCmd(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "queue",
+		Short: "Inspect changes queued while the server was unreachable",
+	}
+	cmd.AddCommand(newQueueListCmd(app))
+	cmd.AddCommand(newQueueDropCmd(app))
+	return cmd
+}
+
+func newQueueListCmd(app *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List queued changes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, q, err := loadQueue()
+			if err != nil {
+				return err
+			}
+			entries := q.Entries
+			if entries == nil {
+				entries = []queue.Entry{}
+			}
+
+			var b strings.Builder
+			fmt.Fprintf(&b, "%d queued change(s)", len(entries))
+			for _, e := range entries {
+				target := e.BaseURL
+				if e.Profile != "" {
+					target += ", profile " + e.Profile
+				}
+				fmt.Fprintf(&b, "\n  #%d  %s  (%s, queued %s)", e.ID, describeQueueEntry(e), target, e.QueuedAt.Local().Format("2006-01-02 15:04"))
+			}
+			payload := map[string]any{
+				"ok":        true,
+				"operation": "queue_list",
+				"entries":   entries,
+			}
+			return output.Write(app.Stdout, app.Output, b.String(), payload)
+		},
+	}
+}
+
+func newQueueDropCmd(app *App) *cobra.Command {
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:   "drop <id>... | --all",
+		Short: "Remove queued changes without sending them",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if all == (len(args) > 0) {
+				return fmt.Errorf("pass queued change IDs or --all")
+			}
+			path, q, err := loadQueue()
+			if err != nil {
+				return err
+			}
+
+			var dropped []int
+			if all {
+				for _, e := range q.Entries {
+					dropped = append(dropped, e.ID)
+				}
+				q.Entries = nil
+			} else {
+				ids := make([]int, 0, len(args))
+				for _, arg := range args {
+					id, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+					if err != nil {
+						return fmt.Errorf("invalid queued change ID %q", arg)
+					}
+					ids = append(ids, id)
+				}
+				if missing := q.Remove(ids...); len(missing) > 0 {
+					return fmt.Errorf("no queued change with ID %v", missing)
+				}
+				dropped = ids
+			}
+			if err := queue.Save(path, q); err != nil {
+				return err
+			}
+
+			payload := map[string]any{
+				"ok":        true,
+				"operation": "queue_drop",
+				"dropped":   dropped,
+				"remaining": len(q.Entries),
+			}
+			human := fmt.Sprintf("Dropped %d queued change(s); %d remaining", len(dropped), len(q.Entries))
+			return output.Write(app.Stdout, app.Output, human, payload)
+		},
+	}
+	cmd.Flags().BoolVar(&all, "all", false, "Drop every queued change")
+	return cmd
+}
+
+type syncConflict struct {
+	QueueID      int                  `json:"queue_id"`
+	EngagementID int64                `json:"engagement_id"`
+	Baseline     *timecard.DaySummary `json:"baseline,omitempty"`
+	Change       timecard.DayChange   `json:"change"`
+}
+
+const (
+	syncApplied    = "applied"
+	syncSend       = "send"
+	syncConflicted = "conflict"
+	syncNoBaseline = "no_baseline"
+)
+
+// classifySync decides what sync does with a queued entry given the day as
+// it is now. With a baseline, only a day that differs from it conflicts;
+// without one, a day with any entries cannot be checked and is held back.
+func classifySync(e queue.Entry, c timecard.DayChange, overwrite bool) string {
+	switch {
+	case c.Existing.Equal(c.Proposed):
+		return syncApplied
+	case overwrite:
+		return syncSend
+	case e.Baseline != nil && !c.Existing.Equal(*e.Baseline):
+		return syncConflicted
+	case e.Baseline == nil && c.HadExisting:
+		return syncNoBaseline
+	}
+	return syncSend
+}
+
+func newSyncCmd(app *App) *cobra.Command {
+	var dryRun bool
+	var overwrite bool
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Send changes queued while the server was unreachable",
+		Long: `Send changes queued while the server was unreachable.
+
+Each queued day is fetched again and patched through the normal flow. A day
+that already matches the queued change is dropped from the queue. When the
+day was fetched before the change was queued, a day that differs from that
+fetch is a conflict. Without such a baseline a day that has any entries
+cannot be checked and is reported as unverified. Conflicts and unverified
+days stay queued and are not saved unless --overwrite is given. The remaining
+weeks are saved as one transaction and removed from the queue once saved.
+Only changes queued for the current base URL and profile are synced.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, q, err := loadQueue()
+			if err != nil {
+				return err
+			}
+			var pending []queue.Entry
+			for _, e := range q.Entries {
+				if strings.EqualFold(strings.TrimRight(e.BaseURL, "/"), app.BaseURL()) && e.Profile == recordedProfile(app) {
+					pending = append(pending, e)
+				}
+			}
+			if len(pending) == 0 {
+				payload := map[string]any{"ok": true, "operation": "sync", "dry_run": dryRun, "synced": []int{}, "already_applied": []int{}, "conflicts": []syncConflict{}, "unverified": []syncConflict{}}
+				return output.Write(app.Stdout, app.Output, "Nothing queued for "+app.BaseURL(), payload)
+			}
+
+			loc, err := config.ResolveTimezone(app.Cfg)
+			if err != nil {
+				return err
+			}
+			edits := make([]timecard.DayEdit, 0, len(pending))
+			byDay := make(map[string]int, len(pending))
+			for _, e := range pending {
+				edit, err := e.Edit(loc)
+				if err != nil {
+					return fmt.Errorf("%w (drop it with `magnit queue drop %d`)", err, e.ID)
+				}
+				byDay[engagementDateKey(e.EngagementID, edit.Date)] = len(edits)
+				edits = append(edits, edit)
+			}
+
+			ctx := context.Background()
+			client, _, httpCtx, err := app.NewAuthedClient(ctx)
+			if err != nil {
+				return err
+			}
+			fetched, err := planWeekEdits(ctx, client, edits)
+			if err != nil {
+				return err
+			}
+
+			originals := make(map[string]map[string]any, len(fetched))
+			var toApply []timecard.DayEdit
+			synced := []int{}
+			applied := []int{}
+			conflicts := []syncConflict{}
+			unverified := []syncConflict{}
+			for _, p := range fetched {
+				originals[engagementDateKey(p.EngagementID, p.WeekStart)] = p.Original
+				for _, c := range p.Changes {
+					date, err := time.ParseInLocation("01/02/2006", c.Date, loc)
+					if err != nil {
+						return err
+					}
+					idx := byDay[engagementDateKey(p.EngagementID, date)]
+					e := pending[idx]
+					switch classifySync(e, c, overwrite) {
+					case syncApplied:
+						applied = append(applied, e.ID)
+					case syncConflicted:
+						conflicts = append(conflicts, syncConflict{QueueID: e.ID, EngagementID: p.EngagementID, Baseline: e.Baseline, Change: c})
+					case syncNoBaseline:
+						unverified = append(unverified, syncConflict{QueueID: e.ID, EngagementID: p.EngagementID, Change: c})
+					default:
+						synced = append(synced, e.ID)
+						toApply = append(toApply, edits[idx])
+					}
+				}
+			}
+
+			groups, err := timecard.GroupEditsByWeek(toApply)
+			if err != nil {
+				return err
+			}
+			plans := make([]weekPlan, 0, len(groups))
+			for _, g := range groups {
+				plan, err := applyWeekEdits(originals[engagementDateKey(g.EngagementID, g.WeekStart)], g)
+				if err != nil {
+					return err
+				}
+				plans = append(plans, plan)
+			}
+
+			results := make([]weekResult, 0, len(plans))
+			for _, p := range plans {
+				results = append(results, weekResult{EngagementID: p.EngagementID, WeekStart: timecard.FormatMDY(p.WeekStart), Changes: p.Changes})
+			}
+			if !dryRun && len(plans) > 0 {
+				outcomes, err := saveWeekPlans(ctx, app, client, httpCtx, plans)
+				if err != nil {
+					return err
+				}
+				for i, o := range outcomes {
+					results[i].BillingItemID = o.BillingItemID
+					results[i].Status = o.Status
+				}
+			}
+			if !dryRun {
+				q.Remove(append(append([]int{}, synced...), applied...)...)
+				if err := queue.Save(path, q); err != nil {
+					return fmt.Errorf("changes were saved but the queue could not be updated: %w", err)
+				}
+			}
+
+			payload := map[string]any{
+				"ok":              true,
+				"operation":       "sync",
+				"dry_run":         dryRun,
+				"synced":          synced,
+				"already_applied": applied,
+				"conflicts":       conflicts,
+				"unverified":      unverified,
+				"weeks":           results,
+			}
+			return output.Write(app.Stdout, app.Output, formatSyncHuman(dryRun, plans, synced, applied, conflicts, unverified), payload)
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be sent without saving or changing the queue")
+	cmd.Flags().BoolVar(&overwrite, "overwrite", false, "Also send conflicting and unverified changes, replacing the day on the server")
+	return cmd
+}
+
+func engagementDateKey(engagementID int64, date time.Time) string {
+	return fmt.Sprintf("%d/%s", engagementID, date.Format("2006-01-02"))
+}
+
+func formatSyncHuman(dryRun bool, plans []weekPlan, synced, applied []int, conflicts, unverified []syncConflict) string {
+	var b strings.Builder
+	if dryRun {
+		b.WriteString("Dry run: ")
+		fmt.Fprintf(&b, "would send %d queued change(s)", len(synced))
+	} else {
+		fmt.Fprintf(&b, "Sent %d queued change(s)", len(synced))
+	}
+	if len(applied) > 0 {
+		fmt.Fprintf(&b, "; %d already on the server", len(applied))
+	}
+	if len(plans) > 0 {
+		b.WriteString("\n")
+		b.WriteString(formatWeekPlansHuman(plans))
+	}
+	if len(conflicts) > 0 {
+		fmt.Fprintf(&b, "\n%d conflict(s) left queued, changed on the server since they were queued; review and rerun with --overwrite or drop them:", len(conflicts))
+		writeSyncConflicts(&b, conflicts)
+	}
+	if len(unverified) > 0 {
+		fmt.Fprintf(&b, "\n%d change(s) left queued for days that already have entries, with no baseline to tell whether they changed since; review and rerun with --overwrite or drop them:", len(unverified))
+		writeSyncConflicts(&b, unverified)
+	}
+	return b.String()
+}
+
+func writeSyncConflicts(b *strings.Builder, conflicts []syncConflict) {
+	for _, c := range conflicts {
+		fmt.Fprintf(b, "\n  #%d engagement %d\n    %s", c.QueueID, c.EngagementID, strings.ReplaceAll(formatDayChangeHuman(c.Change), "\n", "\n    "))
+	}
+}
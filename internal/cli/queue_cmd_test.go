@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/ihildy/magnit-vms-cli/internal/queue"
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+)
+
+func TestClassifySync(t *testing.T) {
+	empty := timecard.DaySummary{WorkedDate: "02/18/2026"}
+	morning := timecard.DaySummary{WorkedDate: "02/18/2026", Spans: []timecard.SpanSummary{{Type: "labor", Start: "09:00", End: "12:00"}}}
+	other := timecard.DaySummary{WorkedDate: "02/18/2026", Spans: []timecard.SpanSummary{{Type: "labor", Start: "10:00", End: "11:00"}}}
+	fullDay := timecard.DaySummary{WorkedDate: "02/18/2026", Spans: []timecard.SpanSummary{{Type: "labor", Start: "09:00", End: "17:00"}}}
+
+	tests := []struct {
+		name      string
+		baseline  *timecard.DaySummary
+		existing  timecard.DaySummary
+		overwrite bool
+		want      string
+	}{
+		{"already applied", nil, fullDay, false, syncApplied},
+		{"empty day without baseline", nil, empty, false, syncSend},
+		{"filled day without baseline", nil, morning, false, syncNoBaseline},
+		{"correction of an unchanged day", &morning, morning, false, syncSend},
+		{"day changed since queued", &morning, other, false, syncConflicted},
+		{"overwrite", &morning, other, true, syncSend},
+		{"overwrite without baseline", nil, morning, true, syncSend},
+	}
+	for _, tt := range tests {
+		change := timecard.DayChange{
+			Date:        "02/18/2026",
+			HadExisting: len(tt.existing.Spans) > 0,
+			Existing:    tt.existing,
+			Proposed:    fullDay,
+		}
+		if got := classifySync(queue.Entry{Baseline: tt.baseline}, change, tt.overwrite); got != tt.want {
+			t.Fatalf("%s: got %s want %s", tt.name, got, tt.want)
+		}
+	}
+}
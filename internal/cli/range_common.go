@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/api"
+	"github.com/ihildy/magnit-vms-cli/internal/config"
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+)
+
+type weekMetadata struct {
+	WeekStart time.Time
+	Metadata  map[string]any
+}
+
+func parseDateRange(app *App, from, to string) (time.Time, time.Time, error) {
+	if from == "" || to == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("--from and --to are required")
+	}
+	loc, err := config.ResolveTimezone(app.Cfg)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	fromDate, err := timecard.ParseDateYYYYMMDD(from, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	toDate, err := timecard.ParseDateYYYYMMDD(to, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if toDate.Before(fromDate) {
+		return time.Time{}, time.Time{}, fmt.Errorf("--to must not be before --from")
+	}
+	return fromDate, toDate, nil
+}
+
+func fetchWeeks(ctx context.Context, client *api.Client, engagementID int64, from, to time.Time) ([]weekMetadata, error) {
+	weekStarts := timecard.WeekStartsBetween(from, to)
+	reqs := make([]api.WeekRequest, len(weekStarts))
+	for i, week := range weekStarts {
+		reqs[i] = api.WeekRequest{EngagementID: engagementID, WeekStart: timecard.FormatMDY(week)}
+	}
+	metadata, err := client.FetchWeeks(ctx, reqs)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]weekMetadata, len(weekStarts))
+	for i, week := range weekStarts {
+		out[i] = weekMetadata{WeekStart: week, Metadata: metadata[i]}
+	}
+	return out, nil
+}
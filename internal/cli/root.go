@@ -3,20 +3,40 @@ package cli
 import (
 	"fmt"
 
+	"github.com/ihildy/magnit-vms-cli/internal/api"
+	"github.com/ihildy/magnit-vms-cli/internal/config"
 	"github.com/ihildy/magnit-vms-cli/internal/keyring"
+	"github.com/ihildy/magnit-vms-cli/internal/output"
 	"github.com/spf13/cobra"
 )
 
 func NewRootCmd() *cobra.Command {
-	app := NewApp()
+	return newRootCmd(NewApp())
+}
 
+func newRootCmd(app *App) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:           "magnit",
 		Short:         "Log work hours to the Pro Unlimited worker API",
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			if err := app.LoadConfig(); err != nil {
+			if err := app.LoadConfig(inProfileCmd(cmd)); err != nil {
+				return err
+			}
+			if app.Trace {
+				app.Verbose = true
+			}
+			if err := app.ResolveOutput(cmd.Flags().Changed("output")); err != nil {
+				return err
+			}
+			if err := app.ResolveRetry(cmd.Flags().Changed("retries")); err != nil {
+				return err
+			}
+			if err := app.ResolveFetch(cmd.Flags().Changed("concurrency"), cmd.Flags().Changed("rate-limit")); err != nil {
+				return err
+			}
+			if err := app.ResolveRecording(); err != nil {
 				return err
 			}
 			if app.Cfg.BaseURL == "" {
@@ -29,8 +49,20 @@ func NewRootCmd() *cobra.Command {
 		},
 	}
 
-	cmd.PersistentFlags().BoolVar(&app.JSONOutput, "json", false, "Emit machine-readable JSON output")
+	cmd.PersistentFlags().BoolVar(&app.JSONOutput, "json", false, "Emit machine-readable JSON output (shorthand for --output json)")
+	cmd.PersistentFlags().StringVarP(&app.OutputFlag, "output", "o", "", "Output format: "+output.FormatNames()+" (default from config)")
+	cmd.PersistentFlags().StringVar(&app.FormatTemplate, "format", "", "Render the JSON payload through a Go text/template (helpers: hours, spanHours, duration, date, json)")
 	cmd.PersistentFlags().StringVar(&app.BaseURLOverride, "base-url", "", "Override API base URL")
+	cmd.PersistentFlags().StringVar(&app.ProfileFlag, "profile", "", "Config profile to use (default from "+config.ProfileEnvVar+", else the one chosen with config profile use)")
+	cmd.PersistentFlags().IntVar(&app.RetriesFlag, "retries", api.DefaultRetryPolicy.Retries, "Retries for transient network and server errors (default from config)")
+	cmd.PersistentFlags().StringVar(&app.RetryBaseFlag, "retry-base-delay", "", "First retry delay, doubled per attempt (default from config, else "+api.DefaultRetryPolicy.BaseDelay.String()+")")
+	cmd.PersistentFlags().StringVar(&app.RetryMaxFlag, "retry-max-delay", "", "Longest delay between retries, also caps Retry-After (default from config, else "+api.DefaultRetryPolicy.MaxDelay.String()+")")
+	cmd.PersistentFlags().IntVar(&app.ConcurrencyFlag, "concurrency", api.DefaultFetchOptions.Concurrency, "Weeks fetched in parallel by range commands (default from config)")
+	cmd.PersistentFlags().Float64Var(&app.RateLimitFlag, "rate-limit", api.DefaultFetchOptions.RateLimit, "Most week fetches started per second, 0 for no limit (default from config)")
+	cmd.PersistentFlags().StringVar(&app.RecordDir, "record", "", "Record every HTTP exchange, with secrets redacted, as JSON files in this directory")
+	cmd.PersistentFlags().StringVar(&app.ReplayDir, "replay", "", "Serve HTTP responses from a --record directory instead of the network")
+	cmd.PersistentFlags().BoolVarP(&app.Verbose, "verbose", "v", false, "Log each HTTP request with status and timing, session reuse and retries to stderr")
+	cmd.PersistentFlags().BoolVar(&app.Trace, "trace", false, "Like --verbose, also logging request and response headers and bodies (secrets redacted)")
 
 	cmd.AddCommand(newAuthCmd(app))
 	cmd.AddCommand(newEngagementCmd(app))
@@ -38,6 +70,25 @@ func NewRootCmd() *cobra.Command {
 	cmd.AddCommand(newShowCmd(app))
 	cmd.AddCommand(newSetCmd(app))
 	cmd.AddCommand(newMarkDNWCmd(app))
+	cmd.AddCommand(newExportCmd(app))
+	cmd.AddCommand(newImportCmd(app))
+	cmd.AddCommand(newClockCmd(app))
+	cmd.AddCommand(newPlanCmd(app))
+	cmd.AddCommand(newApplyCmd(app))
+	cmd.AddCommand(newQueueCmd(app))
+	cmd.AddCommand(newSyncCmd(app))
+	cmd.AddCommand(newDevCmd(app))
 
 	return cmd
 }
+
+// inProfileCmd reports whether cmd is config profile or one of its
+// subcommands, which must work while the selected profile does not exist.
+func inProfileCmd(cmd *cobra.Command) bool {
+	for c := cmd; c != nil && c.HasParent(); c = c.Parent() {
+		if c.Name() == "profile" && c.Parent().Name() == "config" {
+			return true
+		}
+	}
+	return false
+}
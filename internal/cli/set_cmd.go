@@ -7,6 +7,7 @@ import (
 	"github.com/ihildy/magnit-vms-cli/internal/auth"
 	"github.com/ihildy/magnit-vms-cli/internal/config"
 	"github.com/ihildy/magnit-vms-cli/internal/output"
+	"github.com/ihildy/magnit-vms-cli/internal/queue"
 	"github.com/ihildy/magnit-vms-cli/internal/timecard"
 
 	"github.com/spf13/cobra"
@@ -18,11 +19,12 @@ func newSetCmd(app *App) *cobra.Command {
 	var engagementID int64
 	var dryRun bool
 	var yes bool
+	var queueOffline bool
 
 	cmd := &cobra.Command{
 		Use:   "set --date YYYY-MM-DD --span type:HH:MM-HH:MM [--span ...]",
 		Short: "Set all spans for a day (replaces existing day spans)",
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
 			if date == "" {
 				return fmt.Errorf("--date is required")
 			}
@@ -40,6 +42,15 @@ func newSetCmd(app *App) *cobra.Command {
 				return err
 			}
 
+			var baseline *timecard.DaySummary
+			if queueOffline && !dryRun {
+				entry := queue.Entry{Operation: queue.OpSet, EngagementID: engagementID, Date: date, Spans: spanArgs}
+				defer func() {
+					entry.Baseline = baseline
+					err = queueIfOffline(app, err, entry)
+				}()
+			}
+
 			ctx := context.Background()
 			client, _, httpCtx, err := app.NewAuthedClient(ctx)
 			if err != nil {
@@ -62,6 +73,8 @@ func newSetCmd(app *App) *cobra.Command {
 				return err
 			}
 
+			baseline = &change.Existing
+
 			if err := confirmConflict(app, change, yes); err != nil {
 				return err
 			}
@@ -77,7 +90,7 @@ func newSetCmd(app *App) *cobra.Command {
 					"payload":       patched,
 				}
 				human := "Dry run complete\n" + formatDayChangeHuman(change)
-				return output.Write(app.Stdout, app.JSONOutput, human, payload)
+				return output.Write(app.Stdout, app.Output, human, payload)
 			}
 
 			xsrf, err := auth.ExtractXSRFToken(httpCtx.Auth.Client, app.BaseURL())
@@ -106,7 +119,7 @@ func newSetCmd(app *App) *cobra.Command {
 				"total_hours":     totalHours,
 			}
 			human := fmt.Sprintf("Saved hours for %s (billingItemId=%d)", date, saveResp.BillingItemID)
-			return output.Write(app.Stdout, app.JSONOutput, human, payload)
+			return output.Write(app.Stdout, app.Output, human, payload)
 		},
 	}
 
@@ -115,6 +128,7 @@ func newSetCmd(app *App) *cobra.Command {
 	cmd.Flags().Int64Var(&engagementID, "engagement", 0, "Engagement ID override")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Validate and show payload diff without saving")
 	cmd.Flags().BoolVar(&yes, "yes", false, "Skip interactive conflict confirmation")
+	cmd.Flags().BoolVar(&queueOffline, "queue-offline", false, "Queue the change for a later sync when the server cannot be reached")
 
 	_ = cmd.MarkFlagRequired("date")
 	_ = cmd.MarkFlagRequired("span")
@@ -64,7 +64,7 @@ func newShowCmd(app *App) *cobra.Command {
 				"total_hours":   totalHours,
 			}
 			human := timecard.FormatDaySummaryHuman(summary)
-			return output.Write(app.Stdout, app.JSONOutput, human, payload)
+			return output.Write(app.Stdout, app.Output, human, payload)
 		},
 	}
 
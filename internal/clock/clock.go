@@ -0,0 +1,284 @@
+// Package clock records local punch-in/punch-out events and turns a day's
+// punches into labor and lunch intervals.
+package clock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/config"
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+)
+
+const stateFileName = "clock.json"
+
+const (
+	PunchIn    = "in"
+	PunchOut   = "out"
+	PunchBreak = "break"
+)
+
+const (
+	StatusOff     = "off"
+	StatusWorking = "working"
+	StatusOnBreak = "on_break"
+)
+
+// Punch is one recorded clock event.
+type Punch struct {
+	Kind string    `json:"kind"`
+	At   time.Time `json:"at"`
+}
+
+// State is the on-disk punch log. Punches are kept in the order they were
+// recorded; committed days are pruned.
+type State struct {
+	Punches []Punch `json:"punches"`
+}
+
+// Problem is a punch sequence issue that blocks committing Date.
+type Problem struct {
+	Date    string `json:"date"`
+	Message string `json:"message"`
+}
+
+// Problems is returned when a day's punches cannot be committed.
+type Problems []Problem
+
+func (p Problems) Error() string {
+	lines := make([]string, 0, len(p))
+	for _, problem := range p {
+		lines = append(lines, problem.Date+": "+problem.Message)
+	}
+	return "punch problems:\n  " + strings.Join(lines, "\n  ")
+}
+
+// StatePath returns the punch log path of a profile next to the config file:
+// clock.json for the default profile and clock-<profile>.json otherwise, so
+// punches are never committed to another account's engagement.
+func StatePath(profile string) (string, error) {
+	cfgPath, err := config.ConfigPath()
+	if err != nil {
+		return "", err
+	}
+	name := stateFileName
+	if profile != "" && profile != config.DefaultProfile {
+		name = "clock-" + profile + ".json"
+	}
+	return filepath.Join(filepath.Dir(cfgPath), name), nil
+}
+
+// Load reads the punch log; a missing file is an empty state.
+func Load(path string) (State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return State{}, nil
+		}
+		return State{}, fmt.Errorf("read clock state: %w", err)
+	}
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return State{}, fmt.Errorf("parse clock state: %w", err)
+	}
+	return s, nil
+}
+
+// Save writes the punch log atomically.
+func Save(path string, s State) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create clock state dir: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal clock state: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write clock state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("write clock state: %w", err)
+	}
+	return nil
+}
+
+// Status reports whether the last punch leaves the user working, on break or
+// clocked out, and when that started.
+func (s State) Status() (string, time.Time) {
+	if len(s.Punches) == 0 {
+		return StatusOff, time.Time{}
+	}
+	last := s.Punches[len(s.Punches)-1]
+	switch last.Kind {
+	case PunchIn:
+		return StatusWorking, last.At
+	case PunchBreak:
+		return StatusOnBreak, last.At
+	default:
+		return StatusOff, last.At
+	}
+}
+
+// Record appends a punch after checking it is a valid transition from the
+// current status: in while off or on break, break while working, out while
+// working.
+func (s *State) Record(kind string, at time.Time) error {
+	status, _ := s.Status()
+	switch kind {
+	case PunchIn:
+		if status == StatusWorking {
+			return fmt.Errorf("already clocked in")
+		}
+	case PunchBreak:
+		if status != StatusWorking {
+			return fmt.Errorf("cannot start a break while %s", strings.ReplaceAll(status, "_", " "))
+		}
+	case PunchOut:
+		if status == StatusOnBreak {
+			return fmt.Errorf("on break; clock in before clocking out")
+		}
+		if status != StatusWorking {
+			return fmt.Errorf("not clocked in")
+		}
+	default:
+		return fmt.Errorf("unknown punch %q", kind)
+	}
+	s.Punches = append(s.Punches, Punch{Kind: kind, At: at})
+	return nil
+}
+
+// Days returns the distinct local dates (YYYY-MM-DD) that have punches.
+func (s State) Days(loc *time.Location) []string {
+	seen := map[string]struct{}{}
+	var out []string
+	for _, p := range s.Punches {
+		day := p.At.In(loc).Format("2006-01-02")
+		if _, ok := seen[day]; ok {
+			continue
+		}
+		seen[day] = struct{}{}
+		out = append(out, day)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Prune drops every punch on the given local date.
+func (s *State) Prune(date time.Time, loc *time.Location) {
+	day := date.In(loc).Format("2006-01-02")
+	kept := s.Punches[:0]
+	for _, p := range s.Punches {
+		if p.At.In(loc).Format("2006-01-02") != day {
+			kept = append(kept, p)
+		}
+	}
+	s.Punches = kept
+}
+
+// Analyze pairs punches into labor (in→break/out) and lunch (break→in)
+// intervals and reports unclosed punches, pairs crossing midnight, pairs that
+// end before they start and intervals that overlap.
+func (s State) Analyze(loc *time.Location) ([]timecard.Interval, Problems) {
+	var intervals []timecard.Interval
+	var problems Problems
+	dayOf := func(t time.Time) string { return t.In(loc).Format("2006-01-02") }
+	stamp := func(t time.Time) string { return t.In(loc).Format("2006-01-02 15:04") }
+
+	var open *Punch
+	for i := range s.Punches {
+		p := s.Punches[i]
+		if open != nil {
+			spanType := timecard.SpanTypeLabor
+			if open.Kind == PunchBreak {
+				spanType = timecard.SpanTypeLunch
+			}
+			switch {
+			case !p.At.After(open.At):
+				problems = append(problems, Problem{Date: dayOf(open.At), Message: fmt.Sprintf("%s at %s ends at or before it starts (%s)", open.Kind, stamp(open.At), stamp(p.At))})
+			case dayOf(open.At) != dayOf(p.At):
+				problems = append(problems, Problem{Date: dayOf(open.At), Message: fmt.Sprintf("%s at %s runs to %s and crosses midnight", open.Kind, stamp(open.At), stamp(p.At))})
+			default:
+				intervals = append(intervals, timecard.Interval{
+					Type:   spanType,
+					Start:  open.At,
+					End:    p.At,
+					Source: fmt.Sprintf("%s %s-%s", open.Kind, open.At.In(loc).Format("15:04"), p.At.In(loc).Format("15:04")),
+				})
+			}
+		}
+		if p.Kind == PunchOut {
+			open = nil
+		} else {
+			open = &s.Punches[i]
+		}
+	}
+	if open != nil {
+		what := "clocked in"
+		if open.Kind == PunchBreak {
+			what = "break started"
+		}
+		problems = append(problems, Problem{Date: dayOf(open.At), Message: fmt.Sprintf("unclosed punch: %s at %s", what, stamp(open.At))})
+	}
+
+	sorted := append([]timecard.Interval(nil), intervals...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+	for i := 1; i < len(sorted); i++ {
+		prev, cur := sorted[i-1], sorted[i]
+		if cur.Start.Before(prev.End) {
+			problems = append(problems, Problem{Date: dayOf(cur.Start), Message: fmt.Sprintf("overlapping punches: %s and %s", prev.Source, cur.Source)})
+		}
+	}
+	return intervals, problems
+}
+
+// DayIntervals returns the intervals for one local date, or Problems if any
+// punch on that date cannot be committed.
+func (s State) DayIntervals(date time.Time, loc *time.Location) ([]timecard.Interval, error) {
+	day := date.In(loc).Format("2006-01-02")
+	all, problems := s.Analyze(loc)
+
+	var dayProblems Problems
+	for _, p := range problems {
+		if p.Date == day {
+			dayProblems = append(dayProblems, p)
+		}
+	}
+	if len(dayProblems) > 0 {
+		return nil, dayProblems
+	}
+
+	var out []timecard.Interval
+	for _, iv := range all {
+		if iv.Start.In(loc).Format("2006-01-02") == day {
+			out = append(out, iv)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no punches recorded on %s", day)
+	}
+	return out, nil
+}
+
+// WorkedHours sums labor on the given date, counting an open clock-in up to
+// now.
+func (s State) WorkedHours(date, now time.Time, loc *time.Location) float64 {
+	day := date.In(loc).Format("2006-01-02")
+	intervals, _ := s.Analyze(loc)
+	var total time.Duration
+	for _, iv := range intervals {
+		if iv.Type == timecard.SpanTypeLabor && iv.Start.In(loc).Format("2006-01-02") == day {
+			total += iv.End.Sub(iv.Start)
+		}
+	}
+	if status, since := s.Status(); status == StatusWorking && since.In(loc).Format("2006-01-02") == day && now.After(since) {
+		total += now.Sub(since)
+	}
+	return total.Hours()
+}
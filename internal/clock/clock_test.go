@@ -0,0 +1,133 @@
+package clock
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func at(hhmm string) time.Time {
+	t, _ := time.ParseInLocation("2006-01-02 15:04", hhmm, time.UTC)
+	return t
+}
+
+func record(t *testing.T, s *State, kind, when string) {
+	t.Helper()
+	if err := s.Record(kind, at(when)); err != nil {
+		t.Fatalf("record %s at %s: %v", kind, when, err)
+	}
+}
+
+func TestRecordRejectsInvalidTransitions(t *testing.T) {
+	var s State
+	if err := s.Record(PunchOut, at("2026-02-16 09:00")); err == nil {
+		t.Fatal("expected error clocking out while off")
+	}
+	record(t, &s, PunchIn, "2026-02-16 09:00")
+	if err := s.Record(PunchIn, at("2026-02-16 09:05")); err == nil {
+		t.Fatal("expected error clocking in twice")
+	}
+	record(t, &s, PunchBreak, "2026-02-16 12:00")
+	if err := s.Record(PunchOut, at("2026-02-16 12:30")); err == nil {
+		t.Fatal("expected error clocking out while on break")
+	}
+	if status, since := s.Status(); status != StatusOnBreak || !since.Equal(at("2026-02-16 12:00")) {
+		t.Fatalf("unexpected status %s since %s", status, since)
+	}
+}
+
+func TestDayIntervalsPairsPunches(t *testing.T) {
+	var s State
+	record(t, &s, PunchIn, "2026-02-16 09:00")
+	record(t, &s, PunchBreak, "2026-02-16 12:00")
+	record(t, &s, PunchIn, "2026-02-16 12:30")
+	record(t, &s, PunchOut, "2026-02-16 17:00")
+	record(t, &s, PunchIn, "2026-02-17 09:00")
+
+	intervals, err := s.DayIntervals(at("2026-02-16 00:00"), time.UTC)
+	if err != nil {
+		t.Fatalf("day intervals: %v", err)
+	}
+	var got []string
+	for _, iv := range intervals {
+		got = append(got, iv.Type+":"+iv.Start.Format("15:04")+"-"+iv.End.Format("15:04"))
+	}
+	if strings.Join(got, " ") != "labor:09:00-12:00 lunch:12:00-12:30 labor:12:30-17:00" {
+		t.Fatalf("unexpected intervals: %v", got)
+	}
+	if hours := s.WorkedHours(at("2026-02-16 00:00"), at("2026-02-16 23:00"), time.UTC); hours != 7.5 {
+		t.Fatalf("worked hours = %v, want 7.5", hours)
+	}
+
+	_, err = s.DayIntervals(at("2026-02-17 00:00"), time.UTC)
+	var problems Problems
+	if !errors.As(err, &problems) || !strings.Contains(err.Error(), "unclosed punch") {
+		t.Fatalf("expected unclosed punch problem, got %v", err)
+	}
+
+	s.Prune(at("2026-02-16 00:00"), time.UTC)
+	if len(s.Punches) != 1 || s.Days(time.UTC)[0] != "2026-02-17" {
+		t.Fatalf("unexpected punches after prune: %+v", s.Punches)
+	}
+}
+
+func TestAnalyzeReportsMidnightAndOverlap(t *testing.T) {
+	var s State
+	record(t, &s, PunchIn, "2026-02-16 22:00")
+	record(t, &s, PunchOut, "2026-02-17 01:00")
+	record(t, &s, PunchIn, "2026-02-18 09:00")
+	record(t, &s, PunchOut, "2026-02-18 12:00")
+	record(t, &s, PunchIn, "2026-02-18 11:00")
+	record(t, &s, PunchOut, "2026-02-18 13:00")
+
+	_, problems := s.Analyze(time.UTC)
+	if len(problems) != 2 {
+		t.Fatalf("expected 2 problems, got %+v", problems)
+	}
+	if problems[0].Date != "2026-02-16" || !strings.Contains(problems[0].Message, "crosses midnight") {
+		t.Fatalf("unexpected midnight problem: %+v", problems[0])
+	}
+	if problems[1].Date != "2026-02-18" || !strings.Contains(problems[1].Message, "overlapping") {
+		t.Fatalf("unexpected overlap problem: %+v", problems[1])
+	}
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clock.json")
+	var s State
+	record(t, &s, PunchIn, "2026-02-16 09:00")
+	if err := Save(path, s); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(got.Punches) != 1 || !got.Punches[0].At.Equal(at("2026-02-16 09:00")) {
+		t.Fatalf("unexpected state: %+v", got)
+	}
+	empty, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || len(empty.Punches) != 0 {
+		t.Fatalf("missing file should be empty state, got %+v, %v", empty, err)
+	}
+}
+
+func TestStatePathIsPerProfile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+
+	paths := map[string]string{}
+	for _, profile := range []string{"", "default", "work"} {
+		path, err := StatePath(profile)
+		if err != nil {
+			t.Fatalf("state path for %q: %v", profile, err)
+		}
+		paths[profile] = filepath.Base(path)
+	}
+	if paths[""] != "clock.json" || paths["default"] != "clock.json" || paths["work"] != "clock-work.json" {
+		t.Fatalf("unexpected state paths %v", paths)
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -14,18 +17,70 @@ const (
 	defaultBaseURL = "https://prowand.pro-unlimited.com"
 	configDirName  = "magnit-vms-cli"
 	configFileName = "config.yaml"
+
+	// DefaultProfile names the settings at the top level of the config.
+	DefaultProfile = "default"
+	// ProfileEnvVar selects the profile when --profile is not given.
+	ProfileEnvVar = "MAGNIT_PROFILE"
 )
 
 type OutputConfig struct {
-	JSONDefault bool `yaml:"json_default,omitempty"`
+	Format      string `yaml:"format,omitempty"`
+	JSONDefault bool   `yaml:"json_default,omitempty"`
+}
+
+// ImportConfig holds defaults for converting tracker intervals into spans.
+// Durations use Go syntax such as "5m" or "1h30m"; DayStart is HH:MM.
+// EngagementTags lists the tracker tags that belong to each engagement ID.
+type ImportConfig struct {
+	MergeGap       string             `yaml:"merge_gap,omitempty"`
+	LunchGapMin    string             `yaml:"lunch_gap_min,omitempty"`
+	LunchGapMax    string             `yaml:"lunch_gap_max,omitempty"`
+	DayStart       string             `yaml:"day_start,omitempty"`
+	EngagementTags map[int64][]string `yaml:"engagement_tags,omitempty"`
+}
+
+// HTTPConfig tunes how API requests are sent, retried and how many weeks are
+// fetched at once. Proxy overrides HTTP(S)_PROXY; CAFile adds trusted CAs;
+// ClientCert/ClientKey enable mTLS. Durations use Go syntax; nil or zero
+// values use the built-in defaults.
+type HTTPConfig struct {
+	Retries        *int     `yaml:"retries,omitempty"`
+	RetryBaseDelay string   `yaml:"retry_base_delay,omitempty"`
+	RetryMaxDelay  string   `yaml:"retry_max_delay,omitempty"`
+	Concurrency    int      `yaml:"concurrency,omitempty"`
+	RateLimit      *float64 `yaml:"rate_limit,omitempty"`
+	Proxy          string   `yaml:"proxy,omitempty"`
+	CAFile         string   `yaml:"ca_file,omitempty"`
+	ClientCert     string   `yaml:"client_cert,omitempty"`
+	ClientKey      string   `yaml:"client_key,omitempty"`
+	Timeout        string   `yaml:"timeout,omitempty"`
+	UserAgent      string   `yaml:"user_agent,omitempty"`
+}
+
+// Profile holds the per-account settings of a named profile. Empty BaseURL,
+// Timezone, CredentialStore and CredentialHelper fall back to the top-level
+// values; the default engagement never does, since engagements belong to one
+// account.
+type Profile struct {
+	BaseURL             string `yaml:"base_url,omitempty"`
+	DefaultEngagementID int64  `yaml:"default_engagement_id,omitempty"`
+	Timezone            string `yaml:"timezone,omitempty"`
+	CredentialStore     string `yaml:"credential_store,omitempty"`
+	CredentialHelper    string `yaml:"credential_helper,omitempty"`
 }
 
 type Config struct {
-	BaseURL             string       `yaml:"base_url,omitempty"`
-	DefaultEngagementID int64        `yaml:"default_engagement_id,omitempty"`
-	Timezone            string       `yaml:"timezone,omitempty"`
-	CredentialStore     string       `yaml:"credential_store,omitempty"`
-	Output              OutputConfig `yaml:"output,omitempty"`
+	BaseURL             string             `yaml:"base_url,omitempty"`
+	DefaultEngagementID int64              `yaml:"default_engagement_id,omitempty"`
+	Timezone            string             `yaml:"timezone,omitempty"`
+	CredentialStore     string             `yaml:"credential_store,omitempty"`
+	CredentialHelper    string             `yaml:"credential_helper,omitempty"`
+	Output              OutputConfig       `yaml:"output,omitempty"`
+	Import              ImportConfig       `yaml:"import,omitempty"`
+	HTTP                HTTPConfig         `yaml:"http,omitempty"`
+	CurrentProfile      string             `yaml:"current_profile,omitempty"`
+	Profiles            map[string]Profile `yaml:"profiles,omitempty"`
 }
 
 func DefaultConfig() Config {
@@ -89,6 +144,110 @@ func Save(cfg Config, path string) error {
 	return nil
 }
 
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+// ValidateProfileName accepts letters, digits, "-" and "_", since the name
+// becomes part of keyring service and file names.
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q (use letters, digits, - and _)", name)
+	}
+	return nil
+}
+
+// ActiveProfile picks the profile from the --profile flag, MAGNIT_PROFILE
+// and current_profile, in that order.
+func (c Config) ActiveProfile(flag string) string {
+	for _, name := range []string{flag, os.Getenv(ProfileEnvVar), c.CurrentProfile} {
+		if name = strings.TrimSpace(name); name != "" {
+			return name
+		}
+	}
+	return DefaultProfile
+}
+
+// HasProfile reports whether name is the default profile or a configured
+// one.
+func (c Config) HasProfile(name string) bool {
+	_, ok := c.Profiles[name]
+	return ok || name == DefaultProfile
+}
+
+// ProfileNames lists the default profile followed by the configured ones in
+// name order.
+func (c Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles)+1)
+	for name := range c.Profiles {
+		if name != DefaultProfile {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{DefaultProfile}, names...)
+}
+
+// ForProfile returns the effective config for the named profile: the
+// top-level config with the profile's settings applied.
+func (c Config) ForProfile(name string) (Config, error) {
+	if name == "" || name == DefaultProfile {
+		return c, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return Config{}, fmt.Errorf("unknown profile %q; add it with `magnit config profile add %s`", name, name)
+	}
+	out := c
+	if p.BaseURL != "" {
+		out.BaseURL = p.BaseURL
+	}
+	out.DefaultEngagementID = p.DefaultEngagementID
+	if p.Timezone != "" {
+		out.Timezone = p.Timezone
+	}
+	if p.CredentialStore != "" {
+		out.CredentialStore = p.CredentialStore
+	}
+	if p.CredentialHelper != "" {
+		out.CredentialHelper = p.CredentialHelper
+	}
+	return out, nil
+}
+
+// WithProfile folds an effective config produced by ForProfile back into c:
+// per-account settings go to the named profile, shared sections such as
+// output, import and http to the top level.
+func (c Config) WithProfile(name string, effective Config) Config {
+	if name == "" || name == DefaultProfile {
+		effective.CurrentProfile = c.CurrentProfile
+		effective.Profiles = c.Profiles
+		return effective
+	}
+	out := c
+	out.Output = effective.Output
+	out.Import = effective.Import
+	out.HTTP = effective.HTTP
+
+	p := Profile{DefaultEngagementID: effective.DefaultEngagementID}
+	if effective.BaseURL != c.BaseURL {
+		p.BaseURL = effective.BaseURL
+	}
+	if effective.Timezone != c.Timezone {
+		p.Timezone = effective.Timezone
+	}
+	if effective.CredentialStore != c.CredentialStore {
+		p.CredentialStore = effective.CredentialStore
+	}
+	if effective.CredentialHelper != c.CredentialHelper {
+		p.CredentialHelper = effective.CredentialHelper
+	}
+	out.Profiles = make(map[string]Profile, len(c.Profiles)+1)
+	for k, v := range c.Profiles {
+		out.Profiles[k] = v
+	}
+	out.Profiles[name] = p
+	return out
+}
+
 func ResolveTimezone(cfg Config) (*time.Location, error) {
 	if cfg.Timezone != "" {
 		loc, err := time.LoadLocation(cfg.Timezone)
@@ -100,3 +259,15 @@ func ResolveTimezone(cfg Config) (*time.Location, error) {
 
 	return time.Now().Location(), nil
 }
+
+// DefaultOutputFormat returns the configured output format name, treating the
+// legacy json_default flag as "json" when no explicit format is set.
+func DefaultOutputFormat(cfg Config) string {
+	if cfg.Output.Format != "" {
+		return cfg.Output.Format
+	}
+	if cfg.Output.JSONDefault {
+		return "json"
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package config
+
+import "testing"
+
+func TestProfileRoundTrip(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DefaultEngagementID = 1001
+	cfg.Timezone = "America/New_York"
+	cfg.Profiles = map[string]Profile{"acme": {BaseURL: "https://acme.example.com", DefaultEngagementID: 2002}}
+
+	acme, err := cfg.ForProfile("acme")
+	if err != nil {
+		t.Fatalf("for profile: %v", err)
+	}
+	if acme.BaseURL != "https://acme.example.com" || acme.DefaultEngagementID != 2002 || acme.Timezone != "America/New_York" {
+		t.Fatalf("unexpected effective config %+v", acme)
+	}
+	if _, err := cfg.ForProfile("missing"); err == nil {
+		t.Fatalf("expected an error for an unknown profile")
+	}
+
+	acme.Timezone = "Europe/Berlin"
+	acme.Output.Format = "json"
+	merged := cfg.WithProfile("acme", acme)
+	if merged.Timezone != "America/New_York" || merged.DefaultEngagementID != 1001 || merged.Output.Format != "json" {
+		t.Fatalf("top level changed unexpectedly: %+v", merged)
+	}
+	want := Profile{BaseURL: "https://acme.example.com", DefaultEngagementID: 2002, Timezone: "Europe/Berlin"}
+	if merged.Profiles["acme"] != want {
+		t.Fatalf("profile saved as %+v, want %+v", merged.Profiles["acme"], want)
+	}
+	if cfg.Profiles["acme"].Timezone != "" {
+		t.Fatalf("WithProfile modified its receiver")
+	}
+}
+
+func TestActiveProfile(t *testing.T) {
+	cfg := Config{CurrentProfile: "acme"}
+	t.Setenv(ProfileEnvVar, "")
+	if got := cfg.ActiveProfile(""); got != "acme" {
+		t.Fatalf("current profile: got %q", got)
+	}
+	t.Setenv(ProfileEnvVar, "env")
+	if got := cfg.ActiveProfile(""); got != "env" {
+		t.Fatalf("env profile: got %q", got)
+	}
+	if got := cfg.ActiveProfile("flag"); got != "flag" {
+		t.Fatalf("flag profile: got %q", got)
+	}
+	if got := (Config{}).ActiveProfile(""); got != "env" {
+		t.Fatalf("env without current profile: got %q", got)
+	}
+	if err := ValidateProfileName("work/2"); err == nil {
+		t.Fatalf("expected a slash to be rejected")
+	}
+}
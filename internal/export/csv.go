@@ -0,0 +1,152 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+)
+
+const (
+	LayoutRows   = "rows"
+	LayoutWeekly = "weekly"
+)
+
+// Week is one fetched timecard week for an engagement.
+type Week struct {
+	EngagementID int64
+	WeekStart    time.Time
+	Days         []timecard.DaySummary
+}
+
+type Row struct {
+	Date         string  `json:"date"`
+	EngagementID int64   `json:"engagement_id"`
+	SpanType     string  `json:"span_type"`
+	Start        string  `json:"start"`
+	End          string  `json:"end"`
+	Hours        float64 `json:"hours"`
+	DidNotWork   bool    `json:"did_not_work"`
+	Notes        string  `json:"notes"`
+}
+
+var rowsHeader = []string{"date", "engagement_id", "span_type", "start", "end", "hours", "did_not_work", "notes"}
+
+var weekdayColumns = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+
+func ValidateLayout(layout string) error {
+	switch layout {
+	case LayoutRows, LayoutWeekly:
+		return nil
+	default:
+		return fmt.Errorf("invalid layout %q (allowed: %s, %s)", layout, LayoutRows, LayoutWeekly)
+	}
+}
+
+// Rows flattens every day between from and to (inclusive) into one row per
+// span. Days without spans still produce a single row so DNW and empty days
+// stay visible.
+func Rows(weeks []Week, from, to time.Time) ([]Row, error) {
+	var out []Row
+	for _, week := range weeks {
+		for _, day := range week.Days {
+			date, err := timecard.ParseWorkedDate(day.WorkedDate, from.Location())
+			if err != nil {
+				return nil, err
+			}
+			if date.Before(from) || date.After(to) {
+				continue
+			}
+			base := Row{
+				Date:         date.Format("2006-01-02"),
+				EngagementID: week.EngagementID,
+				DidNotWork:   day.DidNotWork,
+				Notes:        day.Notes,
+			}
+			if day.DidNotWork || len(day.Spans) == 0 {
+				out = append(out, base)
+				continue
+			}
+			for _, span := range day.Spans {
+				row := base
+				row.SpanType = span.Type
+				row.Start = span.Start
+				row.End = span.End
+				row.Hours = timecard.SpanHours(span)
+				out = append(out, row)
+			}
+		}
+	}
+	return out, nil
+}
+
+func WriteRowsCSV(w io.Writer, rows []Row) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(rowsHeader); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		record := []string{
+			r.Date,
+			strconv.FormatInt(r.EngagementID, 10),
+			r.SpanType,
+			r.Start,
+			r.End,
+			formatHours(r.Hours),
+			strconv.FormatBool(r.DidNotWork),
+			r.Notes,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteWeeklyCSV writes one row per week with labor hours per weekday. Days
+// outside the from/to range are left blank.
+func WriteWeeklyCSV(w io.Writer, weeks []Week, from, to time.Time) error {
+	cw := csv.NewWriter(w)
+	header := append([]string{"week_start", "engagement_id"}, weekdayColumns...)
+	header = append(header, "total")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, week := range weeks {
+		cells := make([]string, len(weekdayColumns))
+		total := 0.0
+		for _, day := range week.Days {
+			date, err := timecard.ParseWorkedDate(day.WorkedDate, from.Location())
+			if err != nil {
+				return err
+			}
+			if date.Before(from) || date.After(to) {
+				continue
+			}
+			idx := int(date.Weekday()+6) % 7
+			if day.DidNotWork {
+				cells[idx] = "DNW"
+				continue
+			}
+			hours := timecard.LaborHours(day.Spans)
+			cells[idx] = formatHours(hours)
+			total += hours
+		}
+		record := append([]string{week.WeekStart.Format("2006-01-02"), strconv.FormatInt(week.EngagementID, 10)}, cells...)
+		record = append(record, formatHours(total))
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatHours(h float64) string {
+	return strconv.FormatFloat(h, 'f', 2, 64)
+}
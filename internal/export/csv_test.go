@@ -0,0 +1,68 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+)
+
+func sampleWeek(t *testing.T) Week {
+	t.Helper()
+	start, _ := time.ParseInLocation("2006-01-02", "2026-02-16", time.UTC)
+	return Week{
+		EngagementID: 42,
+		WeekStart:    start,
+		Days: []timecard.DaySummary{
+			{WorkedDate: "02/16/2026", DidNotWork: true},
+			{WorkedDate: "02/17/2026", Notes: "standup, review", Spans: []timecard.SpanSummary{
+				{Type: timecard.SpanTypeLabor, Start: "09:00", End: "12:00"},
+				{Type: timecard.SpanTypeLunch, Start: "12:00", End: "12:30"},
+				{Type: timecard.SpanTypeLabor, Start: "12:30", End: "17:00"},
+			}},
+			{WorkedDate: "02/18/2026", Spans: []timecard.SpanSummary{
+				{Type: timecard.SpanTypeLabor, Start: "09:00", End: "10:00"},
+			}},
+		},
+	}
+}
+
+func TestWriteRowsCSVFlattensSpans(t *testing.T) {
+	from, _ := time.ParseInLocation("2006-01-02", "2026-02-16", time.UTC)
+	to, _ := time.ParseInLocation("2006-01-02", "2026-02-17", time.UTC)
+
+	rows, err := Rows([]Week{sampleWeek(t)}, from, to)
+	if err != nil {
+		t.Fatalf("rows: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteRowsCSV(&buf, rows); err != nil {
+		t.Fatalf("write rows: %v", err)
+	}
+
+	want := "date,engagement_id,span_type,start,end,hours,did_not_work,notes\n" +
+		"2026-02-16,42,,,,0.00,true,\n" +
+		"2026-02-17,42,labor,09:00,12:00,3.00,false,\"standup, review\"\n" +
+		"2026-02-17,42,lunch,12:00,12:30,0.50,false,\"standup, review\"\n" +
+		"2026-02-17,42,labor,12:30,17:00,4.50,false,\"standup, review\"\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected csv:\n%s", buf.String())
+	}
+}
+
+func TestWriteWeeklyCSVSumsLaborPerWeekday(t *testing.T) {
+	from, _ := time.ParseInLocation("2006-01-02", "2026-02-16", time.UTC)
+	to, _ := time.ParseInLocation("2006-01-02", "2026-02-22", time.UTC)
+
+	var buf bytes.Buffer
+	if err := WriteWeeklyCSV(&buf, []Week{sampleWeek(t)}, from, to); err != nil {
+		t.Fatalf("write weekly: %v", err)
+	}
+
+	want := "week_start,engagement_id,mon,tue,wed,thu,fri,sat,sun,total\n" +
+		"2026-02-16,42,DNW,7.50,1.00,,,,,8.50\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected csv:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,151 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+)
+
+const icsProdID = "-//magnit-vms-cli//hours export//EN"
+
+// ICSOptions controls calendar rendering. Now is stamped into DTSTAMP so
+// output is reproducible in tests.
+type ICSOptions struct {
+	Location *time.Location
+	Now      time.Time
+}
+
+// WriteICS renders labor and lunch spans as timed VEVENTs and DNW days as
+// all-day events. UIDs depend only on engagement, date, span type and start
+// time so re-importing the file updates events instead of duplicating them,
+// even after other spans of the day were added or removed.
+func WriteICS(w io.Writer, weeks []Week, from, to time.Time, opts ICSOptions) (int, error) {
+	loc := opts.Location
+	if loc == nil {
+		loc = from.Location()
+	}
+	stamp := opts.Now.UTC().Format("20060102T150405Z")
+
+	var b strings.Builder
+	writeLine(&b, "BEGIN:VCALENDAR")
+	writeLine(&b, "VERSION:2.0")
+	writeLine(&b, "PRODID:"+icsProdID)
+	writeLine(&b, "CALSCALE:GREGORIAN")
+
+	events := 0
+	for _, week := range weeks {
+		for _, day := range week.Days {
+			date, err := timecard.ParseWorkedDate(day.WorkedDate, loc)
+			if err != nil {
+				return 0, err
+			}
+			if date.Before(from) || date.After(to) {
+				continue
+			}
+			dayKey := date.Format("20060102")
+
+			if day.DidNotWork {
+				writeLine(&b, "BEGIN:VEVENT")
+				writeLine(&b, fmt.Sprintf("UID:%d-%s-dnw@magnit-vms-cli", week.EngagementID, dayKey))
+				writeLine(&b, "DTSTAMP:"+stamp)
+				writeLine(&b, "DTSTART;VALUE=DATE:"+dayKey)
+				writeLine(&b, "DTEND;VALUE=DATE:"+date.AddDate(0, 0, 1).Format("20060102"))
+				writeLine(&b, "SUMMARY:Did not work")
+				writeDescription(&b, week.EngagementID, day.Notes)
+				writeLine(&b, "TRANSP:TRANSPARENT")
+				writeLine(&b, "END:VEVENT")
+				events++
+				continue
+			}
+
+			for _, span := range day.Spans {
+				start, err := spanTime(date, span.Start, loc)
+				if err != nil {
+					return 0, fmt.Errorf("%s: %w", day.WorkedDate, err)
+				}
+				end, err := spanTime(date, span.End, loc)
+				if err != nil {
+					return 0, fmt.Errorf("%s: %w", day.WorkedDate, err)
+				}
+
+				writeLine(&b, "BEGIN:VEVENT")
+				writeLine(&b, fmt.Sprintf("UID:%d-%s-%s-%s@magnit-vms-cli", week.EngagementID, dayKey, span.Type, start.Format("1504")))
+				writeLine(&b, "DTSTAMP:"+stamp)
+				writeLine(&b, "DTSTART"+formatICSTime(start))
+				writeLine(&b, "DTEND"+formatICSTime(end))
+				writeLine(&b, "SUMMARY:"+escapeText(spanTitle(span.Type)))
+				writeDescription(&b, week.EngagementID, day.Notes)
+				if span.Type == timecard.SpanTypeLunch {
+					writeLine(&b, "TRANSP:TRANSPARENT")
+				}
+				writeLine(&b, "END:VEVENT")
+				events++
+			}
+		}
+	}
+
+	writeLine(&b, "END:VCALENDAR")
+	_, err := io.WriteString(w, b.String())
+	return events, err
+}
+
+func spanTitle(spanType string) string {
+	switch spanType {
+	case timecard.SpanTypeLunch:
+		return "Lunch"
+	default:
+		return "Work"
+	}
+}
+
+func writeDescription(b *strings.Builder, engagementID int64, notes string) {
+	desc := fmt.Sprintf("Engagement %d", engagementID)
+	if strings.TrimSpace(notes) != "" {
+		desc += "\n" + notes
+	}
+	writeLine(b, "DESCRIPTION:"+escapeText(desc))
+}
+
+func spanTime(date time.Time, hhmm string, loc *time.Location) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02 15:04", date.Format("2006-01-02")+" "+hhmm, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid span time %q", hhmm)
+	}
+	return t, nil
+}
+
+// formatICSTime writes timed values in UTC. A TZID parameter would need a
+// matching VTIMEZONE component, which some clients (Outlook) insist on.
+func formatICSTime(t time.Time) string {
+	return ":" + t.UTC().Format("20060102T150405Z")
+}
+
+func escapeText(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return r.Replace(s)
+}
+
+// writeLine folds content lines at 75 octets as required by RFC 5545.
+func writeLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts toward the limit.
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func isRuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
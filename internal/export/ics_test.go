@@ -0,0 +1,57 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteICSEmitsSpansAndAllDayDNW(t *testing.T) {
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	from, _ := time.ParseInLocation("2006-01-02", "2026-02-16", loc)
+	to, _ := time.ParseInLocation("2006-01-02", "2026-02-17", loc)
+
+	var buf bytes.Buffer
+	now := time.Date(2026, 2, 20, 8, 0, 0, 0, time.UTC)
+	events, err := WriteICS(&buf, []Week{sampleWeek(t)}, from, to, ICSOptions{Location: loc, Now: now})
+	if err != nil {
+		t.Fatalf("write ics: %v", err)
+	}
+	if events != 4 {
+		t.Fatalf("expected 4 events, got %d", events)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"UID:42-20260216-dnw@magnit-vms-cli\r\n",
+		"DTSTART;VALUE=DATE:20260216\r\nDTEND;VALUE=DATE:20260217\r\n",
+		"UID:42-20260217-labor-1230@magnit-vms-cli\r\nDTSTAMP:20260220T080000Z\r\nDTSTART:20260217T203000Z\r\n",
+		"SUMMARY:Lunch\r\n",
+		"DESCRIPTION:Engagement 42\\nstandup\\, review\r\n",
+		"DTSTAMP:20260220T080000Z\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in output:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "TZID") {
+		t.Fatalf("TZID without VTIMEZONE in output:\n%s", out)
+	}
+	if strings.Contains(out, "-20260218-") {
+		t.Fatalf("days after --to must be excluded:\n%s", out)
+	}
+}
+
+func TestWriteLineFoldsLongLines(t *testing.T) {
+	var b strings.Builder
+	writeLine(&b, "DESCRIPTION:"+strings.Repeat("x", 200))
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Fatalf("line exceeds 75 octets: %d", len(line))
+		}
+	}
+}
@@ -0,0 +1,511 @@
+// Package fakevms is an in-memory stand-in for the VMS endpoints the CLI
+// uses. It issues session cookies on login, requires the bearer token on API
+// calls and the XSRF header on saves, generates empty weekly timecards on
+// first access and keeps whatever is saved, so the CLI can be exercised end
+// to end without a real account.
+package fakevms
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/auth"
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+)
+
+const (
+	accessCookie  = "productionaccess_token"
+	xsrfCookie    = "XSRF-TOKEN"
+	pendingCookie = "mfa_pending"
+	workerPage    = "/wand/app/worker/index.html"
+	mfaPage       = "/login/mfa.html"
+	mdyLayout     = "01/02/2006"
+	spanLayout    = "01/02/2006 15:04"
+)
+
+// Engagement is one engagement listed by engagement-items.
+type Engagement struct {
+	ID                 int64  `json:"id"`
+	Status             string `json:"status"`
+	StartDate          string `json:"startDate"`
+	EndDate            string `json:"endDate"`
+	JobTitle           string `json:"jobTitle"`
+	BuyerName          string `json:"buyerName"`
+	EngagementCode     string `json:"engagementCode"`
+	TimecardTemplateID int64  `json:"timecardTemplateId"`
+}
+
+// Server holds the fake state. Create it with New and serve Handler. A
+// non-empty TOTPSecret makes login ask for a one-time code after the
+// password.
+type Server struct {
+	Username    string
+	Password    string
+	TOTPSecret  string
+	Engagements []Engagement
+
+	mu       sync.Mutex
+	sessions map[string]string // access token -> xsrf token
+	pending  map[string]bool   // password accepted, code still due
+	weeks    map[weekKey]map[string]any
+	nextID   int64
+}
+
+type weekKey struct {
+	engagementID int64
+	weekStart    string
+}
+
+// New returns a server accepting username/password with one active
+// engagement.
+func New(username, password string) *Server {
+	return &Server{
+		Username: username,
+		Password: password,
+		Engagements: []Engagement{{
+			ID:                 1001,
+			Status:             "Active",
+			StartDate:          "01/05/2026",
+			EndDate:            "12/31/2026",
+			JobTitle:           "Software Engineer",
+			BuyerName:          "Example Corp",
+			EngagementCode:     "ENG-1001",
+			TimecardTemplateID: 4,
+		}},
+		sessions: map[string]string{},
+		pending:  map[string]bool{},
+		weeks:    map[weekKey]map[string]any{},
+		nextID:   5000,
+	}
+}
+
+// Handler routes the endpoints the CLI calls.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login.html", s.login)
+	mux.HandleFunc("POST "+mfaPage, s.verifyCode)
+	mux.HandleFunc(workerPage, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte("<html><body>Worker portal</body></html>"))
+	})
+	mux.HandleFunc("GET /wand2/api/users/current", s.authed(s.currentUser))
+	mux.HandleFunc("GET /wand2/engagement/api/engagement-items", s.authed(s.engagementItems))
+	mux.HandleFunc("GET /wand2/api/billing/billing-items/0/metadata", s.authed(s.metadata))
+	mux.HandleFunc("GET /wand2/api/billing/billing-items/0/worker/totalhours", s.authed(s.totalHours))
+	mux.HandleFunc("POST /wand2/api/billing/billing-items", s.authed(s.save))
+	return mux
+}
+
+// ExpireSessions forgets every issued session, as the real server does when
+// tokens time out.
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = map[string]string{}
+}
+
+// IssueSession creates a session as if the user had signed in through the
+// browser and returns its access and XSRF tokens.
+func (s *Server) IssueSession() (access, xsrf string) {
+	access, xsrf = randomToken(), randomToken()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[access] = xsrf
+	return access, xsrf
+}
+
+// Week returns a copy of the stored week, or nil if it was never fetched.
+func (s *Server) Week(engagementID int64, weekStart time.Time) map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	week, ok := s.weeks[weekKey{engagementID, timecard.FormatMDY(timecard.WeekStartMonday(weekStart))}]
+	if !ok {
+		return nil
+	}
+	return cloneMap(week)
+}
+
+const loginPage = `<html><body>
+<p>Please log in to your account below</p>
+<form method="post" action="/login.html">
+<input type="text" name="username">
+<input type="password" name="password_login">
+</form>
+%s</body></html>`
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if r.Method != http.MethodPost {
+		fmt.Fprintf(w, loginPage, "")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad form", http.StatusBadRequest)
+		return
+	}
+	if r.PostForm.Get("username") != s.Username || r.PostForm.Get("password_login") != s.Password {
+		fmt.Fprintf(w, loginPage, "<p class=error>Invalid username / password</p>")
+		return
+	}
+
+	if s.TOTPSecret != "" {
+		challenge := randomToken()
+		s.mu.Lock()
+		s.pending[challenge] = true
+		s.mu.Unlock()
+		http.SetCookie(w, &http.Cookie{Name: pendingCookie, Value: challenge, Path: "/", HttpOnly: true})
+		fmt.Fprintf(w, codePage, "")
+		return
+	}
+	s.startSession(w, r)
+}
+
+const codePage = `<html><body>
+<p>Enter the code from your authenticator app</p>
+<form method="post" action="/login/mfa.html">
+<input type="hidden" name="challenge_type" value="totp">
+<input type="text" name="otpCode" autocomplete="one-time-code">
+<input type="submit" value="Verify">
+</form>
+%s</body></html>`
+
+// verifyCode accepts the current TOTP code or the one from the previous or
+// next period, as real servers allow for clock drift.
+func (s *Server) verifyCode(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	c, err := r.Cookie(pendingCookie)
+	s.mu.Lock()
+	ok := err == nil && s.pending[c.Value]
+	s.mu.Unlock()
+	if !ok {
+		http.Redirect(w, r, "/login.html", http.StatusFound)
+		return
+	}
+	code := r.FormValue("otpCode")
+	now := time.Now()
+	for _, at := range []time.Time{now, now.Add(-30 * time.Second), now.Add(30 * time.Second)} {
+		if want, err := auth.TOTP(s.TOTPSecret, at); err == nil && code == want {
+			s.mu.Lock()
+			delete(s.pending, c.Value)
+			s.mu.Unlock()
+			s.startSession(w, r)
+			return
+		}
+	}
+	fmt.Fprintf(w, codePage, "<p class=error>Invalid code</p>")
+}
+
+func (s *Server) startSession(w http.ResponseWriter, r *http.Request) {
+	access, xsrf := s.IssueSession()
+	http.SetCookie(w, &http.Cookie{Name: accessCookie, Value: access, Path: "/", HttpOnly: true})
+	http.SetCookie(w, &http.Cookie{Name: xsrfCookie, Value: xsrf, Path: "/"})
+	http.Redirect(w, r, workerPage, http.StatusFound)
+}
+
+// authed accepts the access token as a bearer token or cookie and answers
+// 401 for unknown sessions.
+func (s *Server) authed(next func(http.ResponseWriter, *http.Request, string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" {
+			if c, err := r.Cookie(accessCookie); err == nil {
+				token = c.Value
+			}
+		}
+		s.mu.Lock()
+		xsrf, ok := s.sessions[token]
+		s.mu.Unlock()
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "unauthorized"})
+			return
+		}
+		next(w, r, xsrf)
+	}
+}
+
+func (s *Server) currentUser(w http.ResponseWriter, r *http.Request, _ string) {
+	writeJSON(w, http.StatusOK, map[string]any{"userId": 42, "fullName": "Fake Worker", "email": s.Username})
+}
+
+func (s *Server) engagementItems(w http.ResponseWriter, r *http.Request, _ string) {
+	writeJSON(w, http.StatusOK, map[string]any{"content": s.Engagements, "totalElements": len(s.Engagements)})
+}
+
+func (s *Server) metadata(w http.ResponseWriter, r *http.Request, _ string) {
+	engagement, weekStart, ok := s.weekQuery(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	week := cloneMap(s.loadWeek(engagement, weekStart))
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, week)
+}
+
+func (s *Server) totalHours(w http.ResponseWriter, r *http.Request, _ string) {
+	engagement, weekStart, ok := s.weekQuery(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	week := cloneMap(s.loadWeek(engagement, weekStart))
+	s.mu.Unlock()
+
+	days, err := timecard.WeekDaySummaries(week)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	total := 0.0
+	for _, d := range days {
+		total += timecard.LaborHours(d.Spans)
+	}
+	writeJSON(w, http.StatusOK, map[string]float64{"regularHours": total, "overtimeHours": 0, "totalHours": total})
+}
+
+func (s *Server) save(w http.ResponseWriter, r *http.Request, xsrf string) {
+	if r.Header.Get("x-xsrf-token") != xsrf {
+		writeJSON(w, http.StatusForbidden, map[string]any{"error": "invalid xsrf token"})
+		return
+	}
+	var payload map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid json: " + err.Error()})
+		return
+	}
+
+	engagement, err := s.engagement(toInt64(payload["engagementId"]))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	weekStart, err := time.Parse(mdyLayout, toString(payload["selectedDate"]))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid selectedDate"})
+		return
+	}
+	weekStart = timecard.WeekStartMonday(weekStart)
+
+	details, _ := payload["billingItemDetails"].([]any)
+	if detailErrs := validateDetails(details, weekStart); len(detailErrs) > 0 {
+		writeJSON(w, http.StatusOK, map[string]any{"billingItemId": 0, "errors": nil, "billingItemDetailErrors": detailErrs})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := s.loadWeek(engagement, weekStart)
+	id := toInt64(stored["id"])
+	if id == 0 {
+		id = s.newID()
+	}
+	payload["id"] = id
+	for _, d := range details {
+		detail, _ := d.(map[string]any)
+		s.assignIDs(detail)
+	}
+	s.weeks[weekKey{engagement.ID, timecard.FormatMDY(weekStart)}] = payload
+	writeJSON(w, http.StatusOK, map[string]any{"billingItemId": id, "billingItemIds": []int64{id}, "errors": nil, "billingItemDetailErrors": nil})
+}
+
+// weekQuery reads engagementId and selectedDate, answering 400/404 itself.
+func (s *Server) weekQuery(w http.ResponseWriter, r *http.Request) (Engagement, time.Time, bool) {
+	q := r.URL.Query()
+	id, err := strconv.ParseInt(q.Get("engagementId"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid engagementId"})
+		return Engagement{}, time.Time{}, false
+	}
+	engagement, err := s.engagement(id)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": err.Error()})
+		return Engagement{}, time.Time{}, false
+	}
+	selected, err := time.Parse(mdyLayout, q.Get("selectedDate"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid selectedDate"})
+		return Engagement{}, time.Time{}, false
+	}
+	return engagement, timecard.WeekStartMonday(selected), true
+}
+
+func (s *Server) engagement(id int64) (Engagement, error) {
+	for _, e := range s.Engagements {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return Engagement{}, fmt.Errorf("engagement %d not found", id)
+}
+
+// loadWeek returns the stored week, generating an empty one first. Callers
+// hold s.mu.
+func (s *Server) loadWeek(e Engagement, weekStart time.Time) map[string]any {
+	key := weekKey{e.ID, timecard.FormatMDY(weekStart)}
+	if week, ok := s.weeks[key]; ok {
+		return week
+	}
+	weekEnd := timecard.WeekEndSunday(weekStart)
+	details := make([]any, 0, 7)
+	for day := 0; day < 7; day++ {
+		details = append(details, map[string]any{
+			"id":                0,
+			"workedDate":        timecard.FormatMDY(weekStart.AddDate(0, 0, day)),
+			"didNotWork":        false,
+			"timeEntrySpanDtos": nil,
+			"timeEntry":         map[string]any{"id": 0, "notes": ""},
+		})
+	}
+	week := map[string]any{
+		"id":                    0,
+		"type":                  "TIME",
+		"engagementId":          e.ID,
+		"requisitionId":         e.ID,
+		"timecardTemplateId":    e.TimecardTemplateID,
+		"selectedDate":          timecard.FormatMDY(weekStart),
+		"selectedEndDate":       timecard.FormatMDY(weekEnd),
+		"periodEndDate":         timecard.FormatMDY(weekEnd),
+		"bypassLeaveValidation": false,
+		"attachments":           []any{},
+		"billingItemDetails":    details,
+	}
+	// Round-trip through JSON so numbers look like decoded server data.
+	week = cloneMap(week)
+	s.weeks[key] = week
+	return week
+}
+
+// assignIDs gives saved days, time entries and spans server IDs the way the
+// real API does, keeping IDs that already exist. Callers hold s.mu.
+func (s *Server) assignIDs(detail map[string]any) {
+	if detail == nil {
+		return
+	}
+	spans, _ := detail["timeEntrySpanDtos"].([]any)
+	dnw, _ := detail["didNotWork"].(bool)
+	if len(spans) == 0 && !dnw {
+		return
+	}
+	if toInt64(detail["id"]) == 0 {
+		detail["id"] = s.newID()
+	}
+	entry, _ := detail["timeEntry"].(map[string]any)
+	if entry == nil {
+		entry = map[string]any{"notes": ""}
+		detail["timeEntry"] = entry
+	}
+	if toInt64(entry["id"]) == 0 {
+		entry["id"] = s.newID()
+	}
+	for _, sp := range spans {
+		span, _ := sp.(map[string]any)
+		if span == nil {
+			continue
+		}
+		if toInt64(span["id"]) == 0 {
+			span["id"] = s.newID()
+		}
+		span["timeEntryId"] = entry["id"]
+	}
+}
+
+func (s *Server) newID() int64 {
+	s.nextID++
+	return s.nextID
+}
+
+// validateDetails mirrors the server's per-day checks: days must fall in the
+// week and spans must be well formed, on the worked date and not overlap.
+func validateDetails(details []any, weekStart time.Time) []map[string]any {
+	var errs []map[string]any
+	weekEnd := timecard.WeekEndSunday(weekStart)
+	for _, d := range details {
+		detail, _ := d.(map[string]any)
+		workedDate := toString(detail["workedDate"])
+		day, err := time.Parse(mdyLayout, workedDate)
+		if err != nil || day.Before(weekStart) || day.After(weekEnd) {
+			errs = append(errs, map[string]any{"workedDate": workedDate, "message": "worked date is outside the selected week"})
+			continue
+		}
+		spans, _ := detail["timeEntrySpanDtos"].([]any)
+		type interval struct{ start, end time.Time }
+		var seen []interval
+		for _, sp := range spans {
+			span, _ := sp.(map[string]any)
+			start, errStart := time.Parse(spanLayout, toString(span["startTimeStr"]))
+			end, errEnd := time.Parse(spanLayout, toString(span["endTimeStr"]))
+			switch {
+			case errStart != nil || errEnd != nil:
+				errs = append(errs, map[string]any{"workedDate": workedDate, "message": "invalid span time"})
+			case !sameDay(start, day) || !end.After(start):
+				errs = append(errs, map[string]any{"workedDate": workedDate, "message": "span must start on the worked date and end after it starts"})
+			default:
+				for _, other := range seen {
+					if start.Before(other.end) && other.start.Before(end) {
+						errs = append(errs, map[string]any{"workedDate": workedDate, "message": "spans overlap"})
+						break
+					}
+				}
+				seen = append(seen, interval{start, end})
+			}
+		}
+	}
+	return errs
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func cloneMap(in map[string]any) map[string]any {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return nil
+	}
+	var out map[string]any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil
+	}
+	return out
+}
+
+func randomToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func toInt64(v any) int64 {
+	switch t := v.(type) {
+	case float64:
+		return int64(t)
+	case int64:
+		return t
+	case int:
+		return int64(t)
+	case json.Number:
+		n, _ := t.Int64()
+		return n
+	}
+	return 0
+}
+
+func toString(v any) string {
+	s, _ := v.(string)
+	return s
+}
@@ -0,0 +1,110 @@
+package fakevms
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/api"
+	"github.com/ihildy/magnit-vms-cli/internal/auth"
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+)
+
+func login(t *testing.T, srv *httptest.Server, password string) (*api.Client, string, error) {
+	t.Helper()
+	httpClient, err := auth.NewHTTPClient()
+	if err != nil {
+		t.Fatalf("new http client: %v", err)
+	}
+	authn := &auth.Authenticator{BaseURL: srv.URL, Client: httpClient}
+	if _, err := authn.LoginUser(context.Background(), "worker@example.com", password); err != nil {
+		return nil, "", err
+	}
+	xsrf, err := auth.ExtractXSRFToken(httpClient, srv.URL)
+	if err != nil {
+		t.Fatalf("extract xsrf: %v", err)
+	}
+	return &api.Client{BaseURL: srv.URL, HTTP: httpClient}, xsrf, nil
+}
+
+func TestLoginRejectsWrongPassword(t *testing.T) {
+	srv := httptest.NewServer(New("worker@example.com", "pw").Handler())
+	defer srv.Close()
+
+	if _, _, err := login(t, srv, "wrong"); err == nil || err.Error() != "invalid username or password" {
+		t.Fatalf("expected invalid credentials, got %v", err)
+	}
+}
+
+func TestSaveRoundTripAssignsIDsAndChecksXSRF(t *testing.T) {
+	fake := New("worker@example.com", "pw")
+	srv := httptest.NewServer(fake.Handler())
+	defer srv.Close()
+
+	client, xsrf, err := login(t, srv, "pw")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	ctx := context.Background()
+	metadata, err := client.GetMetadata(ctx, 1001, "02/18/2026")
+	if err != nil {
+		t.Fatalf("get metadata: %v", err)
+	}
+	if metadata["selectedDate"] != "02/16/2026" {
+		t.Fatalf("expected the week of 02/16/2026, got %v", metadata["selectedDate"])
+	}
+
+	day, _ := time.Parse("2006-01-02", "2026-02-18")
+	labor, _ := timecard.ParseSpanArg("labor:09:00-17:00")
+	patched, _, err := timecard.PatchDay(metadata, day, []timecard.Span{labor}, false)
+	if err != nil {
+		t.Fatalf("patch day: %v", err)
+	}
+
+	if _, err := client.SaveBillingItems(ctx, patched, "wrong"); !errors.Is(err, api.ErrSessionExpired) {
+		t.Fatalf("expected a bad xsrf token to be rejected, got %v", err)
+	}
+	resp, err := client.SaveBillingItems(ctx, patched, xsrf)
+	if err != nil || resp.BillingItemID == 0 || resp.BillingItemDetailErr != nil {
+		t.Fatalf("save: %+v, %v", resp, err)
+	}
+
+	hours, err := client.GetTotalHours(ctx, 1001, "02/16/2026")
+	if err != nil || hours["totalHours"] != 8 {
+		t.Fatalf("expected 8 total hours, got %v, %v", hours, err)
+	}
+	summary, err := timecard.FindDaySummary(fake.Week(1001, day), day)
+	if err != nil || len(summary.Spans) != 1 {
+		t.Fatalf("saved day not stored: %+v, %v", summary, err)
+	}
+
+	fake.ExpireSessions()
+	if _, err := client.GetMetadata(ctx, 1001, "02/16/2026"); !errors.Is(err, api.ErrSessionExpired) {
+		t.Fatalf("expected expired session, got %v", err)
+	}
+}
+
+func TestSaveReportsOverlappingSpans(t *testing.T) {
+	srv := httptest.NewServer(New("worker@example.com", "pw").Handler())
+	defer srv.Close()
+
+	client, xsrf, err := login(t, srv, "pw")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	metadata, err := client.GetMetadata(context.Background(), 1001, "02/16/2026")
+	if err != nil {
+		t.Fatalf("get metadata: %v", err)
+	}
+	details := metadata["billingItemDetails"].([]any)
+	details[0].(map[string]any)["timeEntrySpanDtos"] = []any{
+		map[string]any{"startTimeStr": "02/16/2026 09:00", "endTimeStr": "02/16/2026 12:00"},
+		map[string]any{"startTimeStr": "02/16/2026 11:00", "endTimeStr": "02/16/2026 13:00"},
+	}
+	resp, err := client.SaveBillingItems(context.Background(), metadata, xsrf)
+	if err != nil || resp.BillingItemDetailErr == nil {
+		t.Fatalf("expected detail errors, got %+v, %v", resp, err)
+	}
+}
@@ -0,0 +1,538 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+)
+
+// maxOccurrences bounds the occurrences of one rule inside the import range.
+const maxOccurrences = 5000
+
+// CalendarEvent is a parsed VEVENT. Start and End are absolute instants; Loc
+// is the zone recurrences are expanded in so wall-clock times survive DST.
+type CalendarEvent struct {
+	UID          string
+	Summary      string
+	Start        time.Time
+	End          time.Time
+	AllDay       bool
+	Cancelled    bool
+	RRule        string
+	ExDates      []time.Time
+	RecurrenceID *time.Time
+	Loc          *time.Location
+	Line         int
+}
+
+// ICSOptions selects which events become spans.
+type ICSOptions struct {
+	Match      string
+	LunchMatch string
+	From       time.Time
+	To         time.Time
+	Location   *time.Location
+}
+
+// ParseICS reads every VEVENT in a calendar. Floating times are interpreted
+// in loc; a TZID that is not an IANA zone name is a row error.
+func ParseICS(r io.Reader, name string, loc *time.Location) ([]CalendarEvent, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, RowError{File: name, Err: err}
+	}
+
+	var events []CalendarEvent
+	var errs RowErrors
+	var cur *CalendarEvent
+	var durationValue string
+	// Components nest (VALARM inside VEVENT); only the event's own
+	// properties may fill cur.
+	var components []string
+	for _, l := range lines {
+		prop, params, value := parseContentLine(l.text)
+		switch prop {
+		case "BEGIN":
+			components = append(components, strings.ToUpper(value))
+			if strings.EqualFold(value, "VEVENT") {
+				cur = &CalendarEvent{Line: l.number, Loc: loc}
+				durationValue = ""
+			}
+			continue
+		case "END":
+			if len(components) > 0 {
+				components = components[:len(components)-1]
+			}
+			if !strings.EqualFold(value, "VEVENT") || cur == nil {
+				continue
+			}
+			if cur.End.IsZero() && durationValue != "" {
+				d, err := parseICSDuration(durationValue)
+				if err != nil {
+					errs = append(errs, RowError{File: name, Line: cur.Line, Err: err})
+					cur = nil
+					continue
+				}
+				cur.End = cur.Start.Add(d)
+			}
+			if cur.Start.IsZero() {
+				errs = append(errs, RowError{File: name, Line: cur.Line, Err: errors.New("event has no DTSTART")})
+			} else {
+				if cur.End.IsZero() {
+					cur.End = cur.Start
+				}
+				events = append(events, *cur)
+			}
+			cur = nil
+			continue
+		}
+		if cur == nil || len(components) == 0 || components[len(components)-1] != "VEVENT" {
+			continue
+		}
+
+		switch prop {
+		case "UID":
+			cur.UID = value
+		case "SUMMARY":
+			cur.Summary = unescapeICSText(value)
+		case "STATUS":
+			cur.Cancelled = strings.EqualFold(value, "CANCELLED")
+		case "RRULE":
+			cur.RRule = value
+		case "DURATION":
+			durationValue = value
+		case "DTSTART", "DTEND", "RECURRENCE-ID", "EXDATE":
+			for _, part := range strings.Split(value, ",") {
+				t, allDay, zone, err := parseICSTime(part, params, loc)
+				if err != nil {
+					errs = append(errs, RowError{File: name, Line: l.number, Err: err})
+					break
+				}
+				switch prop {
+				case "DTSTART":
+					cur.Start, cur.AllDay, cur.Loc = t, allDay, zone
+				case "DTEND":
+					cur.End = t
+				case "RECURRENCE-ID":
+					cur.RecurrenceID = &t
+				case "EXDATE":
+					cur.ExDates = append(cur.ExDates, t)
+				}
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return events, nil
+}
+
+// CalendarIntervals expands recurring events within the options' date range
+// and turns events matching Match into labor and LunchMatch into lunch.
+// All-day and cancelled events are ignored.
+func CalendarIntervals(events []CalendarEvent, name string, opts ICSOptions) ([]timecard.Interval, error) {
+	loc := opts.Location
+	rangeStart := time.Date(opts.From.Year(), opts.From.Month(), opts.From.Day(), 0, 0, 0, 0, loc)
+	rangeEnd := time.Date(opts.To.Year(), opts.To.Month(), opts.To.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, 1)
+
+	overrides := map[string]CalendarEvent{}
+	for _, ev := range events {
+		if ev.RecurrenceID != nil {
+			overrides[ev.UID+"\x00"+ev.RecurrenceID.UTC().Format(time.RFC3339)] = ev
+		}
+	}
+
+	var out []timecard.Interval
+	var errs RowErrors
+	add := func(ev CalendarEvent, start, end time.Time) {
+		if ev.Cancelled || ev.AllDay || !start.Before(rangeEnd) || !end.After(rangeStart) {
+			return
+		}
+		spanType, ok := classifyEvent(ev.Summary, opts)
+		if !ok {
+			return
+		}
+		out = append(out, timecard.Interval{
+			Type:   spanType,
+			Start:  start,
+			End:    end,
+			Source: fmt.Sprintf("%s:%d", name, ev.Line),
+		})
+	}
+
+	for _, ev := range events {
+		if ev.RecurrenceID != nil {
+			add(ev, ev.Start, ev.End)
+			continue
+		}
+		if ev.RRule == "" {
+			add(ev, ev.Start, ev.End)
+			continue
+		}
+		starts, err := expandRRule(ev, rangeStart, rangeEnd)
+		if err != nil {
+			errs = append(errs, RowError{File: name, Line: ev.Line, Err: err})
+			continue
+		}
+		duration := ev.End.Sub(ev.Start)
+		for _, start := range starts {
+			if _, overridden := overrides[ev.UID+"\x00"+start.UTC().Format(time.RFC3339)]; overridden {
+				continue
+			}
+			add(ev, start, start.Add(duration))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out, nil
+}
+
+func classifyEvent(summary string, opts ICSOptions) (string, bool) {
+	title := strings.ToLower(strings.TrimSpace(summary))
+	if opts.LunchMatch != "" && globMatch(opts.LunchMatch, title) {
+		return timecard.SpanTypeLunch, true
+	}
+	if globMatch(opts.Match, title) {
+		return timecard.SpanTypeLabor, true
+	}
+	return "", false
+}
+
+func globMatch(pattern, title string) bool {
+	ok, err := path.Match(strings.ToLower(pattern), title)
+	return err == nil && ok
+}
+
+// ValidateGlob reports malformed --match patterns up front.
+func ValidateGlob(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return nil
+}
+
+// expandRRule returns the start times of occurrences overlapping
+// [rangeStart, limit) for the subset of RFC 5545 used by calendar apps for
+// working hours: DAILY, WEEKLY (with BYDAY), MONTHLY and YEARLY with
+// INTERVAL, COUNT and UNTIL. Other rule parts are rejected rather than
+// ignored, since ignoring them would put hours on the wrong days.
+func expandRRule(ev CalendarEvent, rangeStart, limit time.Time) ([]time.Time, error) {
+	rule := map[string]string{}
+	for _, part := range strings.Split(ev.RRule, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid RRULE part %q", part)
+		}
+		key, value := strings.ToUpper(strings.TrimSpace(kv[0])), strings.ToUpper(strings.TrimSpace(kv[1]))
+		switch key {
+		case "FREQ", "INTERVAL", "COUNT", "UNTIL", "BYDAY", "WKST":
+		default:
+			return nil, fmt.Errorf("unsupported RRULE part %s", key)
+		}
+		rule[key] = value
+	}
+	if _, ok := rule["BYDAY"]; ok && rule["FREQ"] != "WEEKLY" {
+		return nil, fmt.Errorf("unsupported RRULE BYDAY with FREQ=%s", rule["FREQ"])
+	}
+
+	interval := 1
+	if v, ok := rule["INTERVAL"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid RRULE INTERVAL %q", v)
+		}
+		interval = n
+	}
+	if wkst, ok := rule["WKST"]; ok && wkst != "MO" && interval > 1 && rule["FREQ"] == "WEEKLY" {
+		return nil, fmt.Errorf("unsupported RRULE WKST=%s with INTERVAL=%d", wkst, interval)
+	}
+	count := 0
+	if v, ok := rule["COUNT"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid RRULE COUNT %q", v)
+		}
+		count = n
+	}
+	var until time.Time
+	if v, ok := rule["UNTIL"]; ok {
+		t, _, _, err := parseICSTime(v, nil, ev.Loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RRULE UNTIL: %w", err)
+		}
+		until = t
+		if len(v) == 8 {
+			until = until.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+	}
+
+	local := ev.Start.In(ev.Loc)
+	excluded := map[string]struct{}{}
+	for _, ex := range ev.ExDates {
+		excluded[ex.UTC().Format(time.RFC3339)] = struct{}{}
+	}
+
+	duration := ev.End.Sub(ev.Start)
+	var out []time.Time
+	var capErr error
+	emitted := 0
+	accept := func(t time.Time) bool {
+		if t.Before(ev.Start) {
+			return true
+		}
+		if !until.IsZero() && t.After(until) {
+			return false
+		}
+		if count > 0 && emitted >= count {
+			return false
+		}
+		if !t.Before(limit) {
+			return false
+		}
+		// Occurrences before the range still count toward COUNT.
+		emitted++
+		if !t.Add(duration).After(rangeStart) {
+			return true
+		}
+		if _, skip := excluded[t.UTC().Format(time.RFC3339)]; skip {
+			return true
+		}
+		if len(out) >= maxOccurrences {
+			capErr = fmt.Errorf("RRULE yields more than %d occurrences in the import range", maxOccurrences)
+			return false
+		}
+		out = append(out, t)
+		return true
+	}
+	done := func() ([]time.Time, error) {
+		if capErr != nil {
+			return nil, capErr
+		}
+		return out, nil
+	}
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, local.Hour(), local.Minute(), local.Second(), 0, ev.Loc)
+	}
+
+	switch rule["FREQ"] {
+	case "DAILY":
+		for i := 0; ; i += interval {
+			if !accept(at(local.Year(), local.Month(), local.Day()+i)) {
+				return done()
+			}
+		}
+	case "WEEKLY":
+		days, err := parseByDay(rule["BYDAY"], local.Weekday())
+		if err != nil {
+			return nil, err
+		}
+		weekStart := local.AddDate(0, 0, -((int(local.Weekday()) + 6) % 7))
+		for w := 0; ; w += interval {
+			for _, wd := range days {
+				offset := (int(wd) + 6) % 7
+				t := at(weekStart.Year(), weekStart.Month(), weekStart.Day()+w*7+offset)
+				if !accept(t) {
+					return done()
+				}
+			}
+		}
+	case "MONTHLY":
+		for i := 0; ; i += interval {
+			t := at(local.Year(), local.Month()+time.Month(i), local.Day())
+			if t.Day() != local.Day() {
+				// Months without this day are skipped, per RFC 5545.
+				if t.After(limit) {
+					return done()
+				}
+				continue
+			}
+			if !accept(t) {
+				return done()
+			}
+		}
+	case "YEARLY":
+		for i := 0; ; i += interval {
+			t := at(local.Year()+i, local.Month(), local.Day())
+			if t.Day() != local.Day() {
+				if t.After(limit) {
+					return done()
+				}
+				continue
+			}
+			if !accept(t) {
+				return done()
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported RRULE FREQ %q", rule["FREQ"])
+	}
+}
+
+var icsWeekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+func parseByDay(raw string, fallback time.Weekday) ([]time.Weekday, error) {
+	if raw == "" {
+		return []time.Weekday{fallback}, nil
+	}
+	var days []time.Weekday
+	for _, part := range strings.Split(raw, ",") {
+		wd, ok := icsWeekdays[strings.TrimSpace(part)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported RRULE BYDAY %q", part)
+		}
+		days = append(days, wd)
+	}
+	sort.Slice(days, func(i, j int) bool {
+		return (int(days[i])+6)%7 < (int(days[j])+6)%7
+	})
+	return days, nil
+}
+
+type icsLine struct {
+	number int
+	text   string
+}
+
+// unfoldICS joins continuation lines (those starting with a space or tab)
+// while remembering the line each logical line started on.
+func unfoldICS(r io.Reader) ([]icsLine, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var out []icsLine
+	n := 0
+	for scanner.Scan() {
+		n++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t")) && len(out) > 0 {
+			out[len(out)-1].text += text[1:]
+			continue
+		}
+		if text == "" {
+			continue
+		}
+		out = append(out, icsLine{number: n, text: text})
+	}
+	return out, scanner.Err()
+}
+
+func parseContentLine(line string) (string, map[string]string, string) {
+	colon := -1
+	inQuotes := false
+	for i, c := range line {
+		if c == '"' {
+			inQuotes = !inQuotes
+		}
+		if c == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return strings.ToUpper(line), nil, ""
+	}
+	head, value := line[:colon], line[colon+1:]
+	parts := strings.Split(head, ";")
+	params := map[string]string{}
+	for _, p := range parts[1:] {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) == 2 {
+			params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, value
+}
+
+func parseICSTime(value string, params map[string]string, fallback *time.Location) (time.Time, bool, *time.Location, error) {
+	value = strings.TrimSpace(value)
+	loc := fallback
+	if tzid := params["TZID"]; tzid != "" {
+		l, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, false, nil, fmt.Errorf("unknown TZID %q; export the calendar with IANA zone names", tzid)
+		}
+		loc = l
+	}
+	if params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		if err != nil {
+			return time.Time{}, false, nil, fmt.Errorf("invalid date %q", value)
+		}
+		return t, true, loc, nil
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		if err != nil {
+			return time.Time{}, false, nil, fmt.Errorf("invalid UTC time %q", value)
+		}
+		return t, false, time.UTC, nil
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	if err != nil {
+		return time.Time{}, false, nil, fmt.Errorf("invalid time %q", value)
+	}
+	return t, false, loc, nil
+}
+
+// parseICSDuration handles the dur-value forms used in practice, e.g.
+// PT8H, PT7H30M, P1D.
+func parseICSDuration(value string) (time.Duration, error) {
+	v := strings.ToUpper(strings.TrimSpace(value))
+	v = strings.TrimPrefix(v, "+")
+	if !strings.HasPrefix(v, "P") {
+		return 0, fmt.Errorf("invalid DURATION %q", value)
+	}
+	v = v[1:]
+	var total time.Duration
+	inTime := false
+	num := ""
+	for _, c := range v {
+		switch {
+		case c == 'T':
+			inTime = true
+		case c >= '0' && c <= '9':
+			num += string(c)
+		default:
+			n, err := strconv.Atoi(num)
+			if err != nil {
+				return 0, fmt.Errorf("invalid DURATION %q", value)
+			}
+			num = ""
+			switch {
+			case c == 'W':
+				total += time.Duration(n) * 7 * 24 * time.Hour
+			case c == 'D':
+				total += time.Duration(n) * 24 * time.Hour
+			case c == 'H' && inTime:
+				total += time.Duration(n) * time.Hour
+			case c == 'M' && inTime:
+				total += time.Duration(n) * time.Minute
+			case c == 'S' && inTime:
+				total += time.Duration(n) * time.Second
+			default:
+				return 0, fmt.Errorf("invalid DURATION %q", value)
+			}
+		}
+	}
+	return total, nil
+}
+
+func unescapeICSText(s string) string {
+	r := strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return r.Replace(s)
+}
@@ -0,0 +1,179 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+)
+
+const sampleICS = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:work-1\r\n" +
+	"SUMMARY:Work block\r\n" +
+	"DTSTART;TZID=America/New_York:20260216T120000\r\n" +
+	"DTEND;TZID=America/New_York:20260216T200000\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=6\r\n" +
+	"EXDATE;TZID=America/New_York:20260218T120000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:work-1\r\n" +
+	"RECURRENCE-ID;TZID=America/New_York:20260220T120000\r\n" +
+	"SUMMARY:Work block\r\n" +
+	"DTSTART;TZID=America/New_York:20260220T130000\r\n" +
+	"DTEND;TZID=America/New_York:20260220T170000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:lunch-1\r\n" +
+	"SUMMARY:Lunch\r\n" +
+	"DTSTART:20260216T200000Z\r\n" +
+	"DURATION:PT30M\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup\r\n" +
+	"SUMMARY:Standup\r\n" +
+	"DTSTART:20260217T090000\r\n" +
+	"DTEND:20260217T091500\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestCalendarIntervalsExpandsRecurrenceAndTimezones(t *testing.T) {
+	la, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	events, err := ParseICS(strings.NewReader(sampleICS), "work.ics", la)
+	if err != nil {
+		t.Fatalf("parse ics: %v", err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("expected 4 events, got %d", len(events))
+	}
+
+	from := time.Date(2026, 2, 16, 0, 0, 0, 0, la)
+	to := time.Date(2026, 2, 22, 0, 0, 0, 0, la)
+	intervals, err := CalendarIntervals(events, "work.ics", ICSOptions{
+		Match:      "work*",
+		LunchMatch: "Lunch*",
+		From:       from,
+		To:         to,
+		Location:   la,
+	})
+	if err != nil {
+		t.Fatalf("calendar intervals: %v", err)
+	}
+
+	edits, err := timecard.IntervalsToEdits(intervals, la, timecard.IntervalOptions{})
+	if err != nil {
+		t.Fatalf("intervals to edits: %v", err)
+	}
+	got := spansByDay(t, edits)
+	want := map[string]string{
+		"2026-02-16": "labor:09:00-12:00 lunch:12:00-12:30 labor:12:30-17:00",
+		"2026-02-20": "labor:10:00-14:00",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected days: %v", got)
+	}
+	for day, spans := range want {
+		if got[day] != spans {
+			t.Fatalf("%s: got %q want %q", day, got[day], spans)
+		}
+	}
+}
+
+func TestExpandRRuleHonorsUntilAndInterval(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	ev := CalendarEvent{Start: start, End: start.Add(time.Hour), RRule: "FREQ=DAILY;INTERVAL=2;UNTIL=20260308T090000Z", Loc: time.UTC}
+	starts, err := expandRRule(ev, start, start.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("expand: %v", err)
+	}
+	if len(starts) != 4 || starts[3].Day() != 8 {
+		t.Fatalf("unexpected occurrences: %v", starts)
+	}
+}
+
+func TestExpandRRuleLongRunningSeries(t *testing.T) {
+	start := time.Date(2012, 1, 2, 9, 0, 0, 0, time.UTC)
+	ev := CalendarEvent{Start: start, End: start.Add(8 * time.Hour), RRule: "FREQ=DAILY", Loc: time.UTC}
+	from := time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)
+	starts, err := expandRRule(ev, from, from.AddDate(0, 0, 5))
+	if err != nil {
+		t.Fatalf("expand: %v", err)
+	}
+	if len(starts) != 5 || !starts[0].Equal(from.Add(9*time.Hour)) {
+		t.Fatalf("unexpected occurrences: %v", starts)
+	}
+
+	ev.RRule = "FREQ=DAILY;COUNT=3"
+	if starts, err := expandRRule(ev, from, from.AddDate(0, 0, 5)); err != nil || len(starts) != 0 {
+		t.Fatalf("COUNT should be spent before the range: %v, %v", starts, err)
+	}
+
+	ev.RRule = "FREQ=DAILY"
+	if _, err := expandRRule(ev, from, from.AddDate(20, 0, 0)); err == nil || !strings.Contains(err.Error(), "more than") {
+		t.Fatalf("expected an occurrence cap error, got %v", err)
+	}
+}
+
+func TestExpandRRuleRejectsUnsupportedParts(t *testing.T) {
+	start := time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC)
+	for _, rule := range []string{
+		"FREQ=MONTHLY;BYDAY=1FR",
+		"FREQ=MONTHLY;BYMONTHDAY=15",
+		"FREQ=WEEKLY;BYDAY=MO,FR;BYSETPOS=1",
+		"FREQ=WEEKLY;INTERVAL=2;WKST=SU;BYDAY=MO",
+		"FREQ=WEEKLY;BYDAY=1MO",
+	} {
+		ev := CalendarEvent{Start: start, End: start.Add(time.Hour), RRule: rule, Loc: time.UTC}
+		if _, err := expandRRule(ev, start, start.AddDate(0, 2, 0)); err == nil {
+			t.Fatalf("expected %q to be rejected", rule)
+		}
+	}
+	ev := CalendarEvent{Start: start, End: start.Add(time.Hour), RRule: "FREQ=WEEKLY;WKST=SU;BYDAY=FR", Loc: time.UTC}
+	if starts, err := expandRRule(ev, start, start.AddDate(0, 0, 14)); err != nil || len(starts) != 2 {
+		t.Fatalf("WKST without INTERVAL should be accepted: %v, %v", starts, err)
+	}
+}
+
+func TestParseICSRejectsUnknownTZID(t *testing.T) {
+	cal := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:x\r\nSUMMARY:Work\r\n" +
+		"DTSTART;TZID=Pacific Standard Time:20260216T090000\r\n" +
+		"DTEND;TZID=Pacific Standard Time:20260216T170000\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	_, err := ParseICS(strings.NewReader(cal), "cal.ics", time.UTC)
+	if err == nil || !strings.Contains(err.Error(), "unknown TZID") {
+		t.Fatalf("expected an unknown TZID error, got %v", err)
+	}
+}
+
+func TestParseICSIgnoresAlarmProperties(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:work-2\r\n" +
+		"SUMMARY:Work block\r\n" +
+		"DTSTART:20260216T090000Z\r\n" +
+		"DURATION:PT8H\r\n" +
+		"BEGIN:VALARM\r\n" +
+		"ACTION:DISPLAY\r\n" +
+		"SUMMARY:Reminder\r\n" +
+		"TRIGGER:-PT15M\r\n" +
+		"DURATION:PT5M\r\n" +
+		"REPEAT:1\r\n" +
+		"END:VALARM\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	events, err := ParseICS(strings.NewReader(input), "work.ics", time.UTC)
+	if err != nil {
+		t.Fatalf("parse ics: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	if ev := events[0]; ev.Summary != "Work block" || ev.End.Sub(ev.Start) != 8*time.Hour {
+		t.Fatalf("alarm properties leaked into the event: %+v", ev)
+	}
+}
@@ -0,0 +1,246 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+)
+
+const (
+	TrackerTimewarrior = "timewarrior"
+	TrackerWatson      = "watson"
+	TrackerOrg         = "org"
+)
+
+// TagOptions restricts terminal tracker imports to intervals carrying at least
+// one of Tags (case-insensitive). An empty Tags list imports everything.
+type TagOptions struct {
+	Location *time.Location
+	Tags     []string
+}
+
+func (o TagOptions) matches(tags []string) bool {
+	if len(o.Tags) == 0 {
+		return true
+	}
+	for _, want := range o.Tags {
+		for _, have := range tags {
+			if strings.EqualFold(strings.TrimSpace(want), strings.TrimSpace(have)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ParseTagTracker dispatches to the Timewarrior, Watson or org-mode parser.
+func ParseTagTracker(r io.Reader, name, format string, opts TagOptions) ([]timecard.Interval, error) {
+	switch format {
+	case TrackerTimewarrior:
+		return ParseTimewarrior(r, name, opts)
+	case TrackerWatson:
+		return ParseWatson(r, name, opts)
+	case TrackerOrg:
+		return ParseOrgClocks(r, name, opts)
+	default:
+		return nil, fmt.Errorf("unsupported tracker %q (allowed: %s, %s, %s)", format, TrackerTimewarrior, TrackerWatson, TrackerOrg)
+	}
+}
+
+// ParseTimewarrior reads the JSON array printed by `timew export`.
+func ParseTimewarrior(r io.Reader, name string, opts TagOptions) ([]timecard.Interval, error) {
+	var entries []struct {
+		ID    int      `json:"id"`
+		Start string   `json:"start"`
+		End   string   `json:"end"`
+		Tags  []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, RowError{File: name, Err: fmt.Errorf("parse timewarrior export: %w", err)}
+	}
+
+	var out []timecard.Interval
+	var errs RowErrors
+	for i, e := range entries {
+		ref := fmt.Sprintf("%s#%d", name, e.ID)
+		if e.ID == 0 {
+			ref = fmt.Sprintf("%s[%d]", name, i)
+		}
+		if !opts.matches(e.Tags) {
+			continue
+		}
+		if e.End == "" {
+			errs = append(errs, RowError{File: ref, Err: errors.New("interval is still open")})
+			continue
+		}
+		start, err1 := time.Parse("20060102T150405Z", e.Start)
+		end, err2 := time.Parse("20060102T150405Z", e.End)
+		if err1 != nil || err2 != nil {
+			errs = append(errs, RowError{File: ref, Err: fmt.Errorf("invalid timestamps %q-%q", e.Start, e.End)})
+			continue
+		}
+		out = append(out, timecard.Interval{Type: timecard.SpanTypeLabor, Start: start, End: end, Source: ref})
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return out, nil
+}
+
+// ParseWatson reads either Watson's frames file (an array of
+// [start, stop, project, id, tags, updated] arrays) or `watson log --json`
+// output. The project name counts as a tag for filtering.
+func ParseWatson(r io.Reader, name string, opts TagOptions) ([]timecard.Interval, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, RowError{File: name, Err: err}
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, RowError{File: name, Err: fmt.Errorf("parse watson json: %w", err)}
+	}
+
+	var out []timecard.Interval
+	var errs RowErrors
+	for i, item := range raw {
+		ref := fmt.Sprintf("%s[%d]", name, i)
+		var start, end time.Time
+		var tags []string
+
+		trimmed := bytes.TrimSpace(item)
+		if len(trimmed) > 0 && trimmed[0] == '[' {
+			var frame []json.RawMessage
+			if err := json.Unmarshal(item, &frame); err != nil || len(frame) < 3 {
+				errs = append(errs, RowError{File: ref, Err: errors.New("invalid frame")})
+				continue
+			}
+			var startTS, endTS float64
+			var project string
+			if json.Unmarshal(frame[0], &startTS) != nil || json.Unmarshal(frame[1], &endTS) != nil || json.Unmarshal(frame[2], &project) != nil {
+				errs = append(errs, RowError{File: ref, Err: errors.New("invalid frame")})
+				continue
+			}
+			if len(frame) > 4 {
+				_ = json.Unmarshal(frame[4], &tags)
+			}
+			start, end = time.Unix(int64(startTS), 0), time.Unix(int64(endTS), 0)
+			tags = append(tags, project)
+		} else {
+			var entry struct {
+				ID      string   `json:"id"`
+				Project string   `json:"project"`
+				Start   string   `json:"start"`
+				Stop    string   `json:"stop"`
+				Tags    []string `json:"tags"`
+			}
+			if err := json.Unmarshal(item, &entry); err != nil {
+				errs = append(errs, RowError{File: ref, Err: errors.New("invalid log entry")})
+				continue
+			}
+			var err1, err2 error
+			start, err1 = time.Parse(time.RFC3339, entry.Start)
+			end, err2 = time.Parse(time.RFC3339, entry.Stop)
+			if err1 != nil || err2 != nil {
+				errs = append(errs, RowError{File: ref, Err: fmt.Errorf("invalid timestamps %q-%q", entry.Start, entry.Stop)})
+				continue
+			}
+			tags = append(entry.Tags, entry.Project)
+		}
+
+		if !opts.matches(tags) {
+			continue
+		}
+		out = append(out, timecard.Interval{Type: timecard.SpanTypeLabor, Start: start, End: end, Source: ref})
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return out, nil
+}
+
+var (
+	orgHeadline = regexp.MustCompile(`^(\*+)\s+(.*?)(?:\s+(:[[:alnum:]_@#%:]+:))?\s*$`)
+	orgClock    = regexp.MustCompile(`^\s*CLOCK:\s*\[([^\]]+)\](?:--\[([^\]]+)\])?`)
+)
+
+// ParseOrgClocks reads CLOCK: lines from an org-mode file. Timestamps are
+// local to the configured timezone and headline tags are inherited by nested
+// headlines, as org-mode does.
+func ParseOrgClocks(r io.Reader, name string, opts TagOptions) ([]timecard.Interval, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var tagStack [][]string
+	var out []timecard.Interval
+	var errs RowErrors
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+
+		if m := orgHeadline.FindStringSubmatch(text); m != nil {
+			level := len(m[1])
+			if len(tagStack) >= level {
+				tagStack = tagStack[:level-1]
+			}
+			for len(tagStack) < level-1 {
+				tagStack = append(tagStack, nil)
+			}
+			var tags []string
+			for _, t := range strings.Split(m[3], ":") {
+				if t != "" {
+					tags = append(tags, t)
+				}
+			}
+			tagStack = append(tagStack, tags)
+			continue
+		}
+
+		m := orgClock.FindStringSubmatch(text)
+		if m == nil {
+			continue
+		}
+		var inherited []string
+		for _, tags := range tagStack {
+			inherited = append(inherited, tags...)
+		}
+		if !opts.matches(inherited) {
+			continue
+		}
+		if m[2] == "" {
+			errs = append(errs, RowError{File: name, Line: line, Err: errors.New("clock is still running")})
+			continue
+		}
+		start, err1 := parseOrgTimestamp(m[1], opts.Location)
+		end, err2 := parseOrgTimestamp(m[2], opts.Location)
+		if err1 != nil || err2 != nil {
+			errs = append(errs, RowError{File: name, Line: line, Err: fmt.Errorf("invalid clock timestamps [%s]--[%s]", m[1], m[2])})
+			continue
+		}
+		out = append(out, timecard.Interval{Type: timecard.SpanTypeLabor, Start: start, End: end, Source: fmt.Sprintf("%s:%d", name, line)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, RowError{File: name, Err: err}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return out, nil
+}
+
+// parseOrgTimestamp handles "2026-02-16 Mon 09:00"; the weekday is optional.
+func parseOrgTimestamp(value string, loc *time.Location) (time.Time, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 2 {
+		return time.Time{}, fmt.Errorf("invalid org timestamp %q", value)
+	}
+	return time.ParseInLocation("2006-01-02 15:04", fields[0]+" "+fields[len(fields)-1], loc)
+}
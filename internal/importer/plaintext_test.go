@@ -0,0 +1,108 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+)
+
+func TestParseTimewarriorFiltersByTagAndConvertsTimezone(t *testing.T) {
+	input := `[
+{"id":3,"start":"20260216T140000Z","end":"20260216T170000Z","tags":["acme","review"]},
+{"id":2,"start":"20260216T173000Z","end":"20260216T220000Z","tags":["ACME"]},
+{"id":1,"start":"20260216T220000Z","end":"20260216T230000Z","tags":["side-project"]}
+]`
+	loc, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	intervals, err := ParseTimewarrior(strings.NewReader(input), "timew.json", TagOptions{Location: loc, Tags: []string{"acme"}})
+	if err != nil {
+		t.Fatalf("parse timewarrior: %v", err)
+	}
+	if len(intervals) != 2 || intervals[0].Source != "timew.json#3" {
+		t.Fatalf("unexpected intervals: %+v", intervals)
+	}
+
+	edits, err := timecard.IntervalsToEdits(intervals, loc, timecard.IntervalOptions{LunchGapMin: 15 * time.Minute, LunchGapMax: time.Hour})
+	if err != nil {
+		t.Fatalf("intervals to edits: %v", err)
+	}
+	got := spansByDay(t, edits)
+	if got["2026-02-16"] != "labor:08:00-11:00 lunch:11:00-11:30 labor:11:30-16:00" {
+		t.Fatalf("unexpected spans: %v", got)
+	}
+}
+
+func TestParseTimewarriorRejectsOpenInterval(t *testing.T) {
+	input := `[{"id":1,"start":"20260216T140000Z","tags":["acme"]}]`
+	_, err := ParseTimewarrior(strings.NewReader(input), "timew.json", TagOptions{Location: time.UTC})
+	var rowErrs RowErrors
+	if !errors.As(err, &rowErrs) || !strings.Contains(err.Error(), "still open") {
+		t.Fatalf("expected open interval error, got %v", err)
+	}
+}
+
+func TestParseWatsonFramesAndLogJSON(t *testing.T) {
+	frames := `[
+[1771232400, 1771243200, "acme", "a1", ["backend"], 1771243200],
+[1771245000, 1771257600, "other", "a2", ["acme-billable"], 1771257600]
+]`
+	intervals, err := ParseWatson(strings.NewReader(frames), "frames", TagOptions{Location: time.UTC, Tags: []string{"acme"}})
+	if err != nil {
+		t.Fatalf("parse frames: %v", err)
+	}
+	if len(intervals) != 1 || intervals[0].Start.UTC().Format("15:04") != "09:00" || intervals[0].End.UTC().Format("15:04") != "12:00" {
+		t.Fatalf("unexpected frame intervals: %+v", intervals)
+	}
+
+	log := `[{"id":"b1","project":"portal","start":"2026-02-17T09:00:00-06:00","stop":"2026-02-17T12:00:00-06:00","tags":["acme"]}]`
+	intervals, err = ParseWatson(strings.NewReader(log), "log.json", TagOptions{Location: time.UTC, Tags: []string{"acme"}})
+	if err != nil {
+		t.Fatalf("parse log json: %v", err)
+	}
+	if len(intervals) != 1 || intervals[0].Start.UTC().Format("2006-01-02 15:04") != "2026-02-17 15:00" {
+		t.Fatalf("unexpected log intervals: %+v", intervals)
+	}
+}
+
+func TestParseOrgClocksInheritsHeadlineTags(t *testing.T) {
+	input := `* Acme                                                          :acme:
+** Feature work
+   :LOGBOOK:
+   CLOCK: [2026-02-16 Mon 09:00]--[2026-02-16 Mon 12:00] =>  3:00
+   CLOCK: [2026-02-16 Mon 12:30]--[2026-02-16 Mon 17:00] =>  4:30
+   :END:
+* Personal                                                      :home:
+  CLOCK: [2026-02-16 Mon 18:00]--[2026-02-16 Mon 19:00] =>  1:00
+* Acme again :client:acme:
+  CLOCK: [2026-02-17 Tue 09:00]--[2026-02-17 Tue 11:00] =>  2:00
+`
+	intervals, err := ParseOrgClocks(strings.NewReader(input), "work.org", TagOptions{Location: time.UTC, Tags: []string{"acme"}})
+	if err != nil {
+		t.Fatalf("parse org: %v", err)
+	}
+	if len(intervals) != 3 || intervals[0].Source != "work.org:4" || intervals[2].Source != "work.org:10" {
+		t.Fatalf("unexpected intervals: %+v", intervals)
+	}
+
+	edits, err := timecard.IntervalsToEdits(intervals, time.UTC, timecard.IntervalOptions{LunchGapMin: 15 * time.Minute, LunchGapMax: time.Hour})
+	if err != nil {
+		t.Fatalf("intervals to edits: %v", err)
+	}
+	got := spansByDay(t, edits)
+	if got["2026-02-16"] != "labor:09:00-12:00 lunch:12:00-12:30 labor:12:30-17:00" || got["2026-02-17"] != "labor:09:00-11:00" {
+		t.Fatalf("unexpected spans: %v", got)
+	}
+}
+
+func TestParseOrgClocksRejectsRunningClock(t *testing.T) {
+	input := "* Task\n  CLOCK: [2026-02-16 Mon 09:00]\n"
+	_, err := ParseOrgClocks(strings.NewReader(input), "work.org", TagOptions{Location: time.UTC})
+	if err == nil || !strings.Contains(err.Error(), "work.org:2") {
+		t.Fatalf("expected running clock error with line, got %v", err)
+	}
+}
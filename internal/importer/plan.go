@@ -0,0 +1,311 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+	"gopkg.in/yaml.v3"
+)
+
+// RowError ties a parse or validation failure to a line of the input file.
+type RowError struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e RowError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.File, e.Err)
+}
+
+func (e RowError) Unwrap() error {
+	return e.Err
+}
+
+// RowErrors collects every failing row so a file can be fixed in one pass.
+type RowErrors []RowError
+
+func (e RowErrors) Error() string {
+	lines := make([]string, 0, len(e))
+	for _, re := range e {
+		lines = append(lines, re.Error())
+	}
+	return fmt.Sprintf("%d invalid row(s):\n  %s", len(e), strings.Join(lines, "\n  "))
+}
+
+// PlanRow is the loosely typed form of one day in a CSV or YAML plan file.
+type PlanRow struct {
+	Line         int
+	Date         string
+	EngagementID int64
+	Spans        []string
+	DidNotWork   bool
+	Notes        *string
+}
+
+// ParsePlanFile reads a CSV or YAML plan, chosen by file extension.
+func ParsePlanFile(path string, loc *time.Location) ([]timecard.DayEdit, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	name := filepath.Base(path)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return ParsePlanCSV(bytes.NewReader(data), name, loc)
+	case ".yaml", ".yml":
+		return ParsePlanYAML(bytes.NewReader(data), name, loc)
+	default:
+		return nil, fmt.Errorf("unsupported plan file %q (expected .csv, .yaml or .yml)", path)
+	}
+}
+
+// ParsePlanCSV reads rows with a header of date, engagement, spans, dnw and
+// notes. Only date is mandatory; spans are separated by semicolons or spaces,
+// e.g. "labor:09:00-12:00;lunch:12:00-12:30".
+func ParsePlanCSV(r io.Reader, name string, loc *time.Location) ([]timecard.DayEdit, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, RowError{File: name, Err: errors.New("file is empty")}
+		}
+		return nil, RowError{File: name, Line: 1, Err: err}
+	}
+	columns := map[string]int{}
+	for i, h := range header {
+		columns[normalizeColumn(h)] = i
+	}
+	if _, ok := columns["date"]; !ok {
+		return nil, RowError{File: name, Line: 1, Err: errors.New("missing required column \"date\"")}
+	}
+
+	var rows []PlanRow
+	var errs RowErrors
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			errs = append(errs, RowError{File: name, Line: line, Err: err})
+			continue
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+		get := func(col string) string {
+			idx, ok := columns[col]
+			if !ok || idx >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[idx])
+		}
+
+		row := PlanRow{Line: line, Date: get("date")}
+		if raw := get("engagement"); raw != "" {
+			id, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || id <= 0 {
+				errs = append(errs, RowError{File: name, Line: line, Err: fmt.Errorf("invalid engagement %q", raw)})
+				continue
+			}
+			row.EngagementID = id
+		}
+		row.Spans = splitSpans(get("spans"))
+		if raw := get("dnw"); raw != "" {
+			dnw, err := parseBool(raw)
+			if err != nil {
+				errs = append(errs, RowError{File: name, Line: line, Err: err})
+				continue
+			}
+			row.DidNotWork = dnw
+		}
+		if notes := get("notes"); notes != "" {
+			row.Notes = &notes
+		}
+		rows = append(rows, row)
+	}
+
+	edits, rowErrs := buildEdits(rows, name, loc)
+	errs = append(errs, rowErrs...)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return edits, nil
+}
+
+// ParsePlanYAML reads either a top-level list of days or a mapping with a
+// "days" list. Each day has date, engagement, spans, dnw and notes keys.
+func ParsePlanYAML(r io.Reader, name string, loc *time.Location) ([]timecard.DayEdit, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, RowError{File: name, Err: errors.New("file is empty")}
+		}
+		return nil, RowError{File: name, Err: fmt.Errorf("parse yaml: %w", err)}
+	}
+	root := &doc
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	if root.Kind == yaml.MappingNode {
+		var days *yaml.Node
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i].Value == "days" {
+				days = root.Content[i+1]
+			}
+		}
+		if days == nil {
+			return nil, RowError{File: name, Line: root.Line, Err: errors.New("expected a \"days\" list")}
+		}
+		root = days
+	}
+	if root.Kind != yaml.SequenceNode {
+		return nil, RowError{File: name, Line: root.Line, Err: errors.New("expected a list of days")}
+	}
+
+	var rows []PlanRow
+	var errs RowErrors
+	for _, item := range root.Content {
+		var raw struct {
+			Date       string   `yaml:"date"`
+			Engagement int64    `yaml:"engagement"`
+			Spans      []string `yaml:"spans"`
+			DNW        bool     `yaml:"dnw"`
+			Notes      *string  `yaml:"notes"`
+		}
+		if err := item.Decode(&raw); err != nil {
+			errs = append(errs, RowError{File: name, Line: item.Line, Err: err})
+			continue
+		}
+		rows = append(rows, PlanRow{
+			Line:         item.Line,
+			Date:         raw.Date,
+			EngagementID: raw.Engagement,
+			Spans:        raw.Spans,
+			DidNotWork:   raw.DNW,
+			Notes:        raw.Notes,
+		})
+	}
+
+	edits, rowErrs := buildEdits(rows, name, loc)
+	errs = append(errs, rowErrs...)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return edits, nil
+}
+
+func buildEdits(rows []PlanRow, name string, loc *time.Location) ([]timecard.DayEdit, RowErrors) {
+	var edits []timecard.DayEdit
+	var errs RowErrors
+	for _, row := range rows {
+		edit, err := row.toEdit(name, loc)
+		if err != nil {
+			errs = append(errs, RowError{File: name, Line: row.Line, Err: err})
+			continue
+		}
+		edits = append(edits, edit)
+	}
+	return edits, errs
+}
+
+func (row PlanRow) toEdit(name string, loc *time.Location) (timecard.DayEdit, error) {
+	if strings.TrimSpace(row.Date) == "" {
+		return timecard.DayEdit{}, errors.New("date is required")
+	}
+	date, err := timecard.ParseDateYYYYMMDD(strings.TrimSpace(row.Date), loc)
+	if err != nil {
+		return timecard.DayEdit{}, err
+	}
+	edit := timecard.DayEdit{
+		EngagementID: row.EngagementID,
+		Date:         date,
+		DidNotWork:   row.DidNotWork,
+		Notes:        row.Notes,
+		Source:       fmt.Sprintf("%s:%d", name, row.Line),
+	}
+
+	if row.DidNotWork {
+		if len(row.Spans) > 0 {
+			return timecard.DayEdit{}, errors.New("a did-not-work day cannot also have spans")
+		}
+		return edit, nil
+	}
+	if len(row.Spans) == 0 {
+		return timecard.DayEdit{}, errors.New("spans are required unless dnw is true")
+	}
+	spans := make([]timecard.Span, 0, len(row.Spans))
+	for _, raw := range row.Spans {
+		span, err := timecard.ParseSpanArg(raw)
+		if err != nil {
+			return timecard.DayEdit{}, err
+		}
+		spans = append(spans, span)
+	}
+	validated, err := timecard.ValidateSpans(spans)
+	if err != nil {
+		return timecard.DayEdit{}, err
+	}
+	edit.Spans = validated
+	return edit, nil
+}
+
+func splitSpans(raw string) []string {
+	fields := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ';' || r == ' ' || r == '\t'
+	})
+	return fields
+}
+
+func parseBool(raw string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "1", "true", "yes", "y", "x":
+		return true, nil
+	case "0", "false", "no", "n":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid dnw value %q", raw)
+	}
+}
+
+func normalizeColumn(h string) string {
+	value := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+	switch value {
+	case "engagement_id", "engagementid":
+		return "engagement"
+	case "did_not_work", "didnotwork":
+		return "dnw"
+	case "span":
+		return "spans"
+	case "note":
+		return "notes"
+	default:
+		return value
+	}
+}
+
+func isBlankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
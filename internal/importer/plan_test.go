@@ -0,0 +1,74 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePlanCSV(t *testing.T) {
+	input := "date,engagement,spans,dnw,notes\n" +
+		"2026-02-16,,labor:09:00-12:00;lunch:12:00-12:30;labor:12:30-17:00,,\n" +
+		"\n" +
+		"2026-02-17,77,,true,PTO\n"
+
+	edits, err := ParsePlanCSV(strings.NewReader(input), "hours.csv", time.UTC)
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	if len(edits) != 2 {
+		t.Fatalf("expected 2 edits, got %d", len(edits))
+	}
+	if len(edits[0].Spans) != 3 || edits[0].Notes != nil || edits[0].Source != "hours.csv:2" {
+		t.Fatalf("unexpected first edit: %+v", edits[0])
+	}
+	if !edits[1].DidNotWork || edits[1].EngagementID != 77 || edits[1].Notes == nil || *edits[1].Notes != "PTO" {
+		t.Fatalf("unexpected second edit: %+v", edits[1])
+	}
+	if edits[1].Source != "hours.csv:4" {
+		t.Fatalf("expected line number to skip blank line, got %s", edits[1].Source)
+	}
+}
+
+func TestParsePlanCSVReportsEveryBadRow(t *testing.T) {
+	input := "date,spans\n" +
+		"2026-02-16,labor:09:00-12:00;lunch:11:00-12:30\n" +
+		"2026-02-17,labor:09:00-17:00\n" +
+		"02/18/2026,labor:09:00-17:00\n"
+
+	_, err := ParsePlanCSV(strings.NewReader(input), "hours.csv", time.UTC)
+	var rowErrs RowErrors
+	if !errors.As(err, &rowErrs) {
+		t.Fatalf("expected RowErrors, got %v", err)
+	}
+	if len(rowErrs) != 2 || rowErrs[0].Line != 2 || rowErrs[1].Line != 4 {
+		t.Fatalf("unexpected row errors: %v", err)
+	}
+	if !strings.Contains(err.Error(), "hours.csv:2: spans overlap") {
+		t.Fatalf("expected overlap error with line number, got %v", err)
+	}
+}
+
+func TestParsePlanYAMLTracksLines(t *testing.T) {
+	input := `days:
+  - date: 2026-02-16
+    spans: [labor:09:00-12:00, labor:13:00-17:00]
+    notes: ""
+  - date: 2026-02-17
+    dnw: true
+    spans: [labor:09:00-12:00]
+`
+	_, err := ParsePlanYAML(strings.NewReader(input), "hours.yaml", time.UTC)
+	if err == nil || !strings.Contains(err.Error(), "hours.yaml:5: a did-not-work day cannot also have spans") {
+		t.Fatalf("expected line-numbered error, got %v", err)
+	}
+
+	edits, err := ParsePlanYAML(strings.NewReader(strings.SplitN(input, "  - date: 2026-02-17", 2)[0]), "hours.yaml", time.UTC)
+	if err != nil {
+		t.Fatalf("parse yaml: %v", err)
+	}
+	if len(edits) != 1 || edits[0].Notes == nil || *edits[0].Notes != "" {
+		t.Fatalf("expected explicit empty notes to be kept, got %+v", edits)
+	}
+}
@@ -0,0 +1,245 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+)
+
+const (
+	TrackerToggl    = "toggl"
+	TrackerClockify = "clockify"
+	TrackerHarvest  = "harvest"
+)
+
+// TrackerOptions filters and anchors rows from a time tracker export.
+// Project is a case-insensitive glob; DayStart (minutes after midnight) is
+// where Harvest rows without timestamps are stacked from.
+type TrackerOptions struct {
+	Location *time.Location
+	Project  string
+	DayStart int
+}
+
+type trackerColumns struct {
+	startDate []string
+	startTime []string
+	endDate   []string
+	endTime   []string
+	date      []string
+	hours     []string
+	project   []string
+}
+
+var trackerFormats = map[string]trackerColumns{
+	TrackerToggl: {
+		startDate: []string{"start date"},
+		startTime: []string{"start time"},
+		endDate:   []string{"end date"},
+		endTime:   []string{"end time"},
+		project:   []string{"project"},
+	},
+	TrackerClockify: {
+		startDate: []string{"start date"},
+		startTime: []string{"start time"},
+		endDate:   []string{"end date"},
+		endTime:   []string{"end time"},
+		project:   []string{"project"},
+	},
+	TrackerHarvest: {
+		date:      []string{"date", "spent date"},
+		hours:     []string{"hours"},
+		startTime: []string{"started at", "start time"},
+		endTime:   []string{"ended at", "end time"},
+		project:   []string{"project"},
+	},
+}
+
+var (
+	trackerDateLayouts = []string{"2006-01-02", "01/02/2006", "1/2/2006", "02.01.2006", "2006/01/02"}
+	trackerTimeLayouts = []string{"15:04:05", "15:04", "03:04:05 PM", "03:04 PM", "3:04:05 PM", "3:04 PM", "3:04PM"}
+)
+
+// ParseTrackerCSV reads a Toggl, Clockify or Harvest CSV export into labor
+// intervals. Rows with a start and end become intervals directly; Harvest
+// rows with only hours are laid end to end from DayStart in file order.
+func ParseTrackerCSV(r io.Reader, name, format string, opts TrackerOptions) ([]timecard.Interval, error) {
+	cols, ok := trackerFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported tracker %q (allowed: %s, %s, %s)", format, TrackerToggl, TrackerClockify, TrackerHarvest)
+	}
+	if err := ValidateGlob(opts.Project); err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, RowError{File: name, Err: errors.New("file is empty")}
+		}
+		return nil, RowError{File: name, Line: 1, Err: err}
+	}
+	index := map[string]int{}
+	for i, h := range header {
+		index[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+	}
+	find := func(aliases []string) int {
+		for _, a := range aliases {
+			if i, ok := index[a]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+	startDateCol, startTimeCol := find(cols.startDate), find(cols.startTime)
+	endDateCol, endTimeCol := find(cols.endDate), find(cols.endTime)
+	dateCol, hoursCol, projectCol := find(cols.date), find(cols.hours), find(cols.project)
+
+	if opts.Project != "" && projectCol < 0 {
+		return nil, RowError{File: name, Line: 1, Err: errors.New("--project needs a project column")}
+	}
+
+	timestamped := startTimeCol >= 0 && endTimeCol >= 0 && (startDateCol >= 0 || dateCol >= 0)
+	if !timestamped && (dateCol < 0 || hoursCol < 0) {
+		return nil, RowError{File: name, Line: 1, Err: fmt.Errorf("header does not look like a %s export", format)}
+	}
+	if startDateCol < 0 {
+		startDateCol = dateCol
+	}
+	if endDateCol < 0 {
+		endDateCol = startDateCol
+	}
+
+	var out []timecard.Interval
+	var errs RowErrors
+	cursors := map[string]time.Time{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			errs = append(errs, RowError{File: name, Line: line, Err: err})
+			continue
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+		field := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		if opts.Project != "" && !globMatch(opts.Project, strings.ToLower(field(projectCol))) {
+			continue
+		}
+		source := fmt.Sprintf("%s:%d", name, line)
+
+		if timestamped && field(startTimeCol) != "" && field(endTimeCol) != "" {
+			start, err := parseTrackerTime(field(startDateCol), field(startTimeCol), opts.Location)
+			if err != nil {
+				errs = append(errs, RowError{File: name, Line: line, Err: err})
+				continue
+			}
+			endDate := field(endDateCol)
+			if endDate == "" {
+				endDate = field(startDateCol)
+			}
+			end, err := parseTrackerTime(endDate, field(endTimeCol), opts.Location)
+			if err != nil {
+				errs = append(errs, RowError{File: name, Line: line, Err: err})
+				continue
+			}
+			if !end.After(start) {
+				errs = append(errs, RowError{File: name, Line: line, Err: errors.New("end must be after start")})
+				continue
+			}
+			out = append(out, timecard.Interval{Type: timecard.SpanTypeLabor, Start: start, End: end, Source: source})
+			continue
+		}
+
+		if dateCol < 0 || hoursCol < 0 {
+			errs = append(errs, RowError{File: name, Line: line, Err: errors.New("row has no start/end times")})
+			continue
+		}
+		day, err := parseTrackerDate(field(dateCol), opts.Location)
+		if err != nil {
+			errs = append(errs, RowError{File: name, Line: line, Err: err})
+			continue
+		}
+		hours, err := parseTrackerHours(field(hoursCol))
+		if err != nil {
+			errs = append(errs, RowError{File: name, Line: line, Err: err})
+			continue
+		}
+		key := day.Format("2006-01-02")
+		start, ok := cursors[key]
+		if !ok {
+			start = day.Add(time.Duration(opts.DayStart) * time.Minute)
+		}
+		end := start.Add(time.Duration(hours * float64(time.Hour)))
+		cursors[key] = end
+		out = append(out, timecard.Interval{Type: timecard.SpanTypeLabor, Start: start, End: end, Source: source})
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return out, nil
+}
+
+func parseTrackerDate(value string, loc *time.Location) (time.Time, error) {
+	for _, layout := range trackerDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+func parseTrackerTime(date, clock string, loc *time.Location) (time.Time, error) {
+	day, err := parseTrackerDate(date, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	normalized := strings.ToUpper(strings.TrimSpace(clock))
+	for _, layout := range trackerTimeLayouts {
+		if t, err := time.Parse(layout, normalized); err == nil {
+			return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", clock)
+}
+
+// parseTrackerHours accepts decimal hours ("7.5") or H:MM ("7:30").
+func parseTrackerHours(value string) (float64, error) {
+	v := strings.TrimSpace(value)
+	var hours float64
+	if h, m, ok := strings.Cut(v, ":"); ok {
+		whole, err1 := strconv.Atoi(h)
+		minutes, err2 := strconv.Atoi(m)
+		if err1 != nil || err2 != nil || whole < 0 || minutes < 0 || minutes > 59 {
+			return 0, fmt.Errorf("invalid hours %q", value)
+		}
+		hours = float64(whole) + float64(minutes)/60
+	} else {
+		var err error
+		if hours, err = strconv.ParseFloat(v, 64); err != nil {
+			return 0, fmt.Errorf("invalid hours %q", value)
+		}
+	}
+	if hours <= 0 || hours > 24 {
+		return 0, fmt.Errorf("invalid hours %q", value)
+	}
+	return hours, nil
+}
@@ -0,0 +1,100 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+)
+
+func spansByDay(t *testing.T, edits []timecard.DayEdit) map[string]string {
+	t.Helper()
+	out := map[string]string{}
+	for _, e := range edits {
+		var parts []string
+		for _, s := range e.Spans {
+			parts = append(parts, s.Type+":"+s.Start+"-"+s.End)
+		}
+		out[e.Date.Format("2006-01-02")] = strings.Join(parts, " ")
+	}
+	return out
+}
+
+func TestParseTrackerCSVTogglMergesAndInfersLunch(t *testing.T) {
+	input := "User,Email,Client,Project,Task,Description,Billable,Start date,Start time,End date,End time,Duration,Tags,Amount ()\n" +
+		"Ann,ann@example.com,Acme,Portal,,Standup,Yes,2026-02-16,09:00:00,2026-02-16,09:15:00,00:15:00,,\n" +
+		"Ann,ann@example.com,Acme,Portal,,Feature,Yes,2026-02-16,09:17:00,2026-02-16,12:00:00,02:43:00,,\n" +
+		"Ann,ann@example.com,Acme,Internal,,Admin,No,2026-02-16,12:00:00,2026-02-16,12:10:00,00:10:00,,\n" +
+		"Ann,ann@example.com,Acme,Portal,,Feature,Yes,2026-02-16,12:45:00,2026-02-16,17:00:00,04:15:00,,\n"
+
+	intervals, err := ParseTrackerCSV(strings.NewReader(input), "toggl.csv", TrackerToggl, TrackerOptions{Location: time.UTC, Project: "portal"})
+	if err != nil {
+		t.Fatalf("parse toggl: %v", err)
+	}
+	if len(intervals) != 3 || intervals[0].Source != "toggl.csv:2" {
+		t.Fatalf("unexpected intervals: %+v", intervals)
+	}
+
+	edits, err := timecard.IntervalsToEdits(intervals, time.UTC, timecard.IntervalOptions{
+		MergeGap:    5 * time.Minute,
+		LunchGapMin: 15 * time.Minute,
+		LunchGapMax: 90 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("intervals to edits: %v", err)
+	}
+	got := spansByDay(t, edits)
+	if want := "labor:09:00-12:00 lunch:12:00-12:45 labor:12:45-17:00"; got["2026-02-16"] != want {
+		t.Fatalf("got %q want %q", got["2026-02-16"], want)
+	}
+}
+
+func TestParseTrackerCSVClockifyTwelveHourClock(t *testing.T) {
+	input := "Project,Client,Description,Task,User,Email,Tags,Billable,Start Date,Start Time,End Date,End Time,Duration (h),Duration (decimal)\n" +
+		"Portal,Acme,Feature,,Ann,ann@example.com,,Yes,02/17/2026,08:30 AM,02/17/2026,01:00 PM,04:30:00,4.50\n"
+
+	intervals, err := ParseTrackerCSV(strings.NewReader(input), "clockify.csv", TrackerClockify, TrackerOptions{Location: time.UTC})
+	if err != nil {
+		t.Fatalf("parse clockify: %v", err)
+	}
+	if len(intervals) != 1 || intervals[0].Start.Hour() != 8 || intervals[0].End.Hour() != 13 {
+		t.Fatalf("unexpected intervals: %+v", intervals)
+	}
+}
+
+func TestParseTrackerCSVHarvestStacksHoursFromDayStart(t *testing.T) {
+	input := "Date,Client,Project,Project Code,Task,Notes,Hours,Billable?\n" +
+		"2026-02-18,Acme,Portal,,Dev,,3.5,Yes\n" +
+		"2026-02-18,Acme,Portal,,Review,,4:30,Yes\n" +
+		"2026-02-18,Acme,Portal,,Review,,lots,Yes\n"
+
+	_, err := ParseTrackerCSV(strings.NewReader(input), "harvest.csv", TrackerHarvest, TrackerOptions{Location: time.UTC, DayStart: 8 * 60})
+	if err == nil || !strings.Contains(err.Error(), "harvest.csv:4: invalid hours") {
+		t.Fatalf("expected line-numbered hours error, got %v", err)
+	}
+
+	input = strings.Join(strings.Split(input, "\n")[:3], "\n") + "\n"
+	intervals, err := ParseTrackerCSV(strings.NewReader(input), "harvest.csv", TrackerHarvest, TrackerOptions{Location: time.UTC, DayStart: 8 * 60})
+	if err != nil {
+		t.Fatalf("parse harvest: %v", err)
+	}
+	edits, err := timecard.IntervalsToEdits(intervals, time.UTC, timecard.IntervalOptions{})
+	if err != nil {
+		t.Fatalf("intervals to edits: %v", err)
+	}
+	if got := spansByDay(t, edits)["2026-02-18"]; got != "labor:08:00-16:00" {
+		t.Fatalf("unexpected spans: %q", got)
+	}
+}
+
+func TestParseTrackerCSVHarvestRejectsZeroAndOverlongHours(t *testing.T) {
+	for _, hours := range []string{"0", "0:00", "24.5", "30:00"} {
+		input := "Date,Client,Project,Project Code,Task,Notes,Hours,Billable?\n" +
+			"2026-02-18,Acme,Portal,,Dev,," + hours + ",Yes\n"
+		_, err := ParseTrackerCSV(strings.NewReader(input), "harvest.csv", TrackerHarvest, TrackerOptions{Location: time.UTC, DayStart: 8 * 60})
+		if err == nil || !strings.Contains(err.Error(), "harvest.csv:2: invalid hours") {
+			t.Fatalf("expected hours %q to be rejected, got %v", hours, err)
+		}
+	}
+}
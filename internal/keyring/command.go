@@ -0,0 +1,153 @@
+package keyring
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// CredentialHelperEnvVar overrides the configured credential helper command.
+const CredentialHelperEnvVar = "MAGNIT_CREDENTIAL_HELPER"
+
+// helperTimeout bounds each helper call, which may wait for an unlock prompt.
+const helperTimeout = 2 * time.Minute
+
+// HelperRequest is written as JSON to the helper's stdin. Get and erase send
+// Keys; store sends Items.
+type HelperRequest struct {
+	Service string            `json:"service"`
+	Profile string            `json:"profile"`
+	Keys    []string          `json:"keys,omitempty"`
+	Items   map[string]string `json:"items,omitempty"`
+}
+
+// HelperResponse is read from the helper's stdout after get. Keys the helper
+// does not know are left out of Items.
+type HelperResponse struct {
+	Items map[string]string `json:"items"`
+}
+
+// commandBackend delegates storage to an external executable called as
+// "<helper> get|store|erase" with a HelperRequest on stdin, like git
+// credential helpers. Secrets never touch the CLI's own files.
+type commandBackend struct {
+	helper  string
+	service string
+	profile string
+}
+
+func (c commandBackend) get(key string) (string, error) {
+	items, err := c.getMany([]string{key})
+	if err != nil {
+		return "", err
+	}
+	return items[key], nil
+}
+
+// getMany asks for all keys in one call; any missing key is errItemNotFound.
+func (c commandBackend) getMany(keys []string) (map[string]string, error) {
+	out, err := c.run("get", HelperRequest{Keys: keys})
+	if err != nil {
+		return nil, err
+	}
+	var resp HelperResponse
+	if len(bytes.TrimSpace(out)) > 0 {
+		if err := json.Unmarshal(out, &resp); err != nil {
+			return nil, fmt.Errorf("credential helper get: parse response: %w", err)
+		}
+	}
+	items := make(map[string]string, len(keys))
+	for _, key := range keys {
+		value, ok := resp.Items[key]
+		if !ok {
+			return nil, errItemNotFound
+		}
+		items[key] = value
+	}
+	return items, nil
+}
+
+func (c commandBackend) set(items map[string]string) error {
+	_, err := c.run("store", HelperRequest{Items: items})
+	return err
+}
+
+func (c commandBackend) remove(keys []string) error {
+	_, err := c.run("erase", HelperRequest{Keys: keys})
+	return err
+}
+
+func (c commandBackend) run(action string, req HelperRequest) ([]byte, error) {
+	args, err := splitCommand(c.helper)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s credential store needs a helper; set credential_helper in the config or %s", StoreCommand, CredentialHelperEnvVar)
+	}
+	req.Service, req.Profile = c.service, c.profile
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("credential helper %s: %w", action, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), helperTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], append(args[1:], action)...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > 500 {
+			msg = msg[:500] + "..."
+		}
+		if msg != "" {
+			return nil, fmt.Errorf("credential helper %s: %w: %s", action, err, msg)
+		}
+		return nil, fmt.Errorf("credential helper %s: %w", action, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// splitCommand splits a helper command line on whitespace, honoring single
+// and double quotes so paths with spaces can be quoted. No shell is involved.
+func splitCommand(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote, inArg = r, true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("credential helper command has an unterminated quote")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,155 @@
+package keyring
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const helperStateEnvVar = "MAGNIT_TEST_HELPER_STATE"
+
+// TestCredentialHelperProcess is not a real test: it is run as the helper
+// by the tests below, keeping items in a JSON file keyed by profile.
+func TestCredentialHelperProcess(t *testing.T) {
+	statePath := os.Getenv(helperStateEnvVar)
+	if statePath == "" {
+		return
+	}
+	os.Exit(runFakeHelper(statePath, os.Args[len(os.Args)-1]))
+}
+
+func runFakeHelper(statePath, action string) int {
+	var req HelperRequest
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintln(os.Stderr, "bad request:", err)
+		return 1
+	}
+	state := map[string]map[string]string{}
+	if data, err := os.ReadFile(statePath); err == nil {
+		_ = json.Unmarshal(data, &state)
+	}
+	entry := state[req.Service+"/"+req.Profile]
+	if entry == nil {
+		entry = map[string]string{}
+	}
+	switch action {
+	case "get":
+		resp := HelperResponse{Items: map[string]string{}}
+		for _, key := range req.Keys {
+			if value, ok := entry[key]; ok {
+				resp.Items[key] = value
+			}
+		}
+		_ = json.NewEncoder(os.Stdout).Encode(resp)
+		return 0
+	case "store":
+		for key, value := range req.Items {
+			entry[key] = value
+		}
+	case "erase":
+		for _, key := range req.Keys {
+			delete(entry, key)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "vault is locked")
+		return 2
+	}
+	state[req.Service+"/"+req.Profile] = entry
+	data, _ := json.Marshal(state)
+	if err := os.WriteFile(statePath, data, 0o600); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func fakeHelper(t *testing.T) (helper, statePath string) {
+	t.Helper()
+	statePath = filepath.Join(t.TempDir(), "vault.json")
+	t.Setenv(helperStateEnvVar, statePath)
+	t.Setenv(CredentialHelperEnvVar, "")
+	return fmt.Sprintf("%q -test.run=^TestCredentialHelperProcess$ --", os.Args[0]), statePath
+}
+
+func TestCommandStoreRoundTrip(t *testing.T) {
+	isolateConfigHome(t)
+	t.Setenv(CredentialStoreEnvVar, "")
+	helper, statePath := fakeHelper(t)
+
+	store := Store{Backend: StoreCommand, Helper: helper}
+	if _, err := store.LoadCredentials(); err != ErrCredentialsNotFound {
+		t.Fatalf("expected ErrCredentialsNotFound before store, got %v", err)
+	}
+	want := Credentials{Username: "user@example.com", Password: "secret"}
+	if err := store.SaveCredentials(want); err != nil {
+		t.Fatalf("save credentials: %v", err)
+	}
+	got, err := store.LoadCredentials()
+	if err != nil {
+		t.Fatalf("load credentials: %v", err)
+	}
+	if got != want {
+		t.Fatalf("unexpected credentials: got=%+v want=%+v", got, want)
+	}
+
+	work := Store{Backend: StoreCommand, Helper: helper, Profile: "work"}
+	if _, err := work.LoadCredentials(); err != ErrCredentialsNotFound {
+		t.Fatalf("expected the work profile to be empty, got %v", err)
+	}
+
+	if err := store.DeleteCredentials(); err != nil {
+		t.Fatalf("delete credentials: %v", err)
+	}
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		t.Fatalf("read helper state: %v", err)
+	}
+	var state map[string]map[string]string
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("parse helper state: %v", err)
+	}
+	if entry := state[serviceName+"/default"]; len(entry) != 0 {
+		t.Fatalf("expected erase to clear the entry, got %v", entry)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(statePath), credentialsFileName)); !os.IsNotExist(err) {
+		t.Fatalf("expected no credentials file next to the helper state")
+	}
+}
+
+func TestCommandStoreErrors(t *testing.T) {
+	isolateConfigHome(t)
+	t.Setenv(CredentialStoreEnvVar, "")
+	helper, _ := fakeHelper(t)
+
+	if _, err := (Store{Backend: StoreCommand}).LoadCredentials(); err == nil || !strings.Contains(err.Error(), CredentialHelperEnvVar) {
+		t.Fatalf("expected a missing helper error, got %v", err)
+	}
+
+	t.Setenv(CredentialHelperEnvVar, helper)
+	if err := (Store{Backend: StoreCommand, Helper: "/nonexistent/helper"}).SaveSession("s"); err != nil {
+		t.Fatalf("expected %s to override the configured helper: %v", CredentialHelperEnvVar, err)
+	}
+
+	_, err := commandBackend{helper: helper}.run("unlock", HelperRequest{})
+	if err == nil || !strings.Contains(err.Error(), "vault is locked") {
+		t.Fatalf("expected helper stderr in the error, got %v", err)
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	got, err := splitCommand(`  "/opt/My Tools/helper" --vault 'work vault' plain `)
+	if err != nil {
+		t.Fatalf("split: %v", err)
+	}
+	want := []string{"/opt/My Tools/helper", "--vault", "work vault", "plain"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q want %q", got, want)
+	}
+	if _, err := splitCommand(`helper "unterminated`); err == nil {
+		t.Fatalf("expected an unterminated quote error")
+	}
+}
@@ -0,0 +1,390 @@
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ihildy/magnit-vms-cli/internal/config"
+	"golang.org/x/crypto/scrypt"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// PassphraseEnvVar holds the encrypted-file passphrase.
+	PassphraseEnvVar = "MAGNIT_CREDENTIAL_PASSPHRASE"
+	// PassphraseFDEnvVar names a file descriptor to read the passphrase from,
+	// e.g. 3 with `3<passphrase.txt`.
+	PassphraseFDEnvVar = "MAGNIT_CREDENTIAL_PASSPHRASE_FD"
+
+	encryptedFormatVersion = 1
+	scryptN                = 1 << 15
+	scryptR                = 8
+	scryptP                = 1
+	keyLength              = 32
+	saltLength             = 16
+)
+
+// ErrWrongPassphrase means the encrypted credentials file could not be
+// decrypted, either because the passphrase is wrong or the file is damaged.
+var ErrWrongPassphrase = errors.New("cannot decrypt credentials: wrong passphrase or damaged file")
+
+// PromptFunc asks the user for a passphrase. confirm is set when a new file
+// is created or rekeyed, so the prompt should ask twice.
+type PromptFunc func(label string, confirm bool) ([]byte, error)
+
+// encryptedFile is the on-disk envelope. The scrypt parameters and salt are
+// bound to the ciphertext as additional data.
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func (e encryptedFile) additionalData() []byte {
+	return []byte(fmt.Sprintf("magnit-vms-cli/v%d/%s/%d/%d/%d/%x", e.Version, e.KDF, e.N, e.R, e.P, e.Salt))
+}
+
+// derivedKey caches the scrypt output for one file so a command derives it
+// once, not on every item read.
+type derivedKey struct {
+	salt []byte
+	key  []byte
+}
+
+var keyCache = struct {
+	sync.Mutex
+	byPath map[string]derivedKey
+}{byPath: map[string]derivedKey{}}
+
+var fdPassphrase struct {
+	once  sync.Once
+	value []byte
+	err   error
+	// file is kept open: the descriptor belongs to the caller.
+	file *os.File
+}
+
+// encryptedFileBackend keeps items as YAML encrypted with AES-256-GCM under
+// a key derived from a passphrase with scrypt.
+type encryptedFileBackend struct {
+	name   string
+	prompt PromptFunc
+}
+
+func (f encryptedFileBackend) path() (string, error) {
+	cfgPath, err := config.ConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(cfgPath), f.name), nil
+}
+
+func (f encryptedFileBackend) read() (string, map[string]string, error) {
+	path, err := f.path()
+	if err != nil {
+		return "", nil, fmt.Errorf("resolve credentials path: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return path, map[string]string{}, nil
+		}
+		return "", nil, fmt.Errorf("read encrypted credentials file: %w", err)
+	}
+	var envelope encryptedFile
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return "", nil, fmt.Errorf("parse encrypted credentials file: %w", err)
+	}
+	if envelope.Version != encryptedFormatVersion || envelope.KDF != "scrypt" {
+		return "", nil, fmt.Errorf("unsupported encrypted credentials format (version %d, kdf %q)", envelope.Version, envelope.KDF)
+	}
+
+	key, cached := cachedKey(path, envelope.Salt)
+	if !cached {
+		passphrase, err := resolvePassphrase(f.prompt, false)
+		if err != nil {
+			return "", nil, err
+		}
+		if key, err = scrypt.Key(passphrase, envelope.Salt, envelope.N, envelope.R, envelope.P, keyLength); err != nil {
+			return "", nil, fmt.Errorf("derive key: %w", err)
+		}
+	}
+	plaintext, err := openSealed(key, envelope)
+	if err != nil {
+		return "", nil, err
+	}
+	if !cached {
+		storeKey(path, envelope.Salt, key)
+	}
+
+	items := map[string]string{}
+	if err := yaml.Unmarshal(plaintext, &items); err != nil {
+		return "", nil, fmt.Errorf("parse decrypted credentials: %w", err)
+	}
+	return path, items, nil
+}
+
+// write encrypts items with the file's cached key, deriving a new one (and
+// asking for a passphrase with confirmation) when the file is new.
+func (f encryptedFileBackend) write(path string, items map[string]string) error {
+	if len(items) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("delete encrypted credentials file: %w", err)
+		}
+		forgetKey(path)
+		return nil
+	}
+	keyCache.Lock()
+	cached, ok := keyCache.byPath[path]
+	keyCache.Unlock()
+	if !ok {
+		passphrase, err := resolvePassphrase(f.prompt, true)
+		if err != nil {
+			return err
+		}
+		if cached, err = newDerivedKey(passphrase); err != nil {
+			return err
+		}
+	}
+	if err := writeEncrypted(path, cached, items); err != nil {
+		return err
+	}
+	storeKey(path, cached.salt, cached.key)
+	return nil
+}
+
+func (f encryptedFileBackend) get(key string) (string, error) {
+	_, items, err := f.read()
+	if err != nil {
+		return "", err
+	}
+	value, ok := items[key]
+	if !ok {
+		return "", errItemNotFound
+	}
+	return value, nil
+}
+
+func (f encryptedFileBackend) set(updates map[string]string) error {
+	path, items, err := f.read()
+	if err != nil {
+		return err
+	}
+	for k, v := range updates {
+		items[k] = v
+	}
+	return f.write(path, items)
+}
+
+// remove deletes the file without decrypting it when every item is being
+// removed, so logout and profile removal work without the passphrase.
+func (f encryptedFileBackend) remove(keys []string) error {
+	if removesAll(keys) {
+		path, err := f.path()
+		if err != nil {
+			return fmt.Errorf("resolve credentials path: %w", err)
+		}
+		return f.write(path, nil)
+	}
+	path, items, err := f.read()
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		delete(items, k)
+	}
+	return f.write(path, items)
+}
+
+func removesAll(keys []string) bool {
+	removed := map[string]bool{}
+	for _, k := range keys {
+		removed[k] = true
+	}
+	return removed[userKey] && removed[passKey] && removed[totpKey] && removed[sessionKey]
+}
+
+// Rekey re-encrypts the profile's encrypted-file credentials under a new
+// passphrase. The current passphrase is resolved as for any read.
+func (s Store) Rekey(newPassphrase []byte) error {
+	if len(newPassphrase) == 0 {
+		return errors.New("new passphrase is empty")
+	}
+	f := encryptedFileBackend{name: s.encryptedFileName(), prompt: s.Prompt}
+	path, items, err := f.read()
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return fmt.Errorf("no encrypted credentials to rekey at %s", path)
+	}
+	next, err := newDerivedKey(newPassphrase)
+	if err != nil {
+		return err
+	}
+	if err := writeEncrypted(path, next, items); err != nil {
+		return err
+	}
+	forgetKey(path)
+	storeKey(path, next.salt, next.key)
+	return nil
+}
+
+func newDerivedKey(passphrase []byte) (derivedKey, error) {
+	salt := make([]byte, saltLength)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return derivedKey{}, fmt.Errorf("generate salt: %w", err)
+	}
+	key, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, keyLength)
+	if err != nil {
+		return derivedKey{}, fmt.Errorf("derive key: %w", err)
+	}
+	return derivedKey{salt: salt, key: key}, nil
+}
+
+func writeEncrypted(path string, dk derivedKey, items map[string]string) error {
+	plaintext, err := yaml.Marshal(items)
+	if err != nil {
+		return fmt.Errorf("marshal credentials: %w", err)
+	}
+	envelope := encryptedFile{Version: encryptedFormatVersion, KDF: "scrypt", N: scryptN, R: scryptR, P: scryptP, Salt: dk.salt}
+	aead, err := newAEAD(dk.key)
+	if err != nil {
+		return err
+	}
+	envelope.Nonce = make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, envelope.Nonce); err != nil {
+		return fmt.Errorf("generate nonce: %w", err)
+	}
+	envelope.Ciphertext = aead.Seal(nil, envelope.Nonce, plaintext, envelope.additionalData())
+
+	data, err := json.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal encrypted credentials: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create credentials dir: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write encrypted credentials file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write encrypted credentials file: %w", err)
+	}
+	return nil
+}
+
+func openSealed(key []byte, envelope encryptedFile) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(envelope.Nonce) != aead.NonceSize() {
+		return nil, ErrWrongPassphrase
+	}
+	plaintext, err := aead.Open(nil, envelope.Nonce, envelope.Ciphertext, envelope.additionalData())
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func cachedKey(path string, salt []byte) ([]byte, bool) {
+	keyCache.Lock()
+	defer keyCache.Unlock()
+	cached, ok := keyCache.byPath[path]
+	if !ok || string(cached.salt) != string(salt) {
+		return nil, false
+	}
+	return cached.key, true
+}
+
+func storeKey(path string, salt, key []byte) {
+	keyCache.Lock()
+	defer keyCache.Unlock()
+	keyCache.byPath[path] = derivedKey{salt: salt, key: key}
+}
+
+func forgetKey(path string) {
+	keyCache.Lock()
+	defer keyCache.Unlock()
+	delete(keyCache.byPath, path)
+}
+
+// resolvePassphrase reads the passphrase from MAGNIT_CREDENTIAL_PASSPHRASE,
+// then the descriptor in MAGNIT_CREDENTIAL_PASSPHRASE_FD, then prompt.
+func resolvePassphrase(prompt PromptFunc, confirm bool) ([]byte, error) {
+	if value := os.Getenv(PassphraseEnvVar); value != "" {
+		return []byte(value), nil
+	}
+	if fd := strings.TrimSpace(os.Getenv(PassphraseFDEnvVar)); fd != "" {
+		return readPassphraseFD(fd)
+	}
+	if prompt == nil {
+		return nil, fmt.Errorf("encrypted-file credential store needs a passphrase; set %s or %s, or run interactively", PassphraseEnvVar, PassphraseFDEnvVar)
+	}
+	passphrase, err := prompt("Credential store passphrase", confirm)
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase is empty")
+	}
+	return passphrase, nil
+}
+
+// readPassphraseFD reads the first line from the descriptor once per
+// process, since a pipe cannot be read twice.
+func readPassphraseFD(value string) ([]byte, error) {
+	fdPassphrase.once.Do(func() {
+		fd, err := strconv.Atoi(value)
+		if err != nil || fd < 0 {
+			fdPassphrase.err = fmt.Errorf("invalid %s %q", PassphraseFDEnvVar, value)
+			return
+		}
+		file := os.NewFile(uintptr(fd), "passphrase-fd")
+		if file == nil {
+			fdPassphrase.err = fmt.Errorf("invalid %s %q", PassphraseFDEnvVar, value)
+			return
+		}
+		fdPassphrase.file = file
+		data, err := io.ReadAll(io.LimitReader(file, 64*1024))
+		if err != nil {
+			fdPassphrase.err = fmt.Errorf("read passphrase from fd %d: %w", fd, err)
+			return
+		}
+		line, _, _ := strings.Cut(string(data), "\n")
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			fdPassphrase.err = fmt.Errorf("passphrase from fd %d is empty", fd)
+			return
+		}
+		fdPassphrase.value = []byte(line)
+	})
+	return fdPassphrase.value, fdPassphrase.err
+}
@@ -0,0 +1,146 @@
+package keyring
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ihildy/magnit-vms-cli/internal/config"
+)
+
+func encryptedPath(t *testing.T, name string) string {
+	t.Helper()
+	cfgPath, err := config.ConfigPath()
+	if err != nil {
+		t.Fatalf("config path: %v", err)
+	}
+	return filepath.Join(filepath.Dir(cfgPath), name)
+}
+
+func TestEncryptedFileRoundTripAndWrongPassphrase(t *testing.T) {
+	isolateConfigHome(t)
+	t.Setenv(CredentialStoreEnvVar, "")
+	t.Setenv(PassphraseFDEnvVar, "")
+	t.Setenv(PassphraseEnvVar, "correct horse")
+
+	store := Store{Backend: StoreEncryptedFile}
+	want := Credentials{Username: "user@example.com", Password: "hunter2"}
+	if err := store.SaveCredentials(want); err != nil {
+		t.Fatalf("save credentials: %v", err)
+	}
+	if got, err := store.LoadCredentials(); err != nil || got != want {
+		t.Fatalf("load credentials: %+v, %v", got, err)
+	}
+
+	path := encryptedPath(t, encryptedFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read encrypted file: %v", err)
+	}
+	if strings.Contains(string(data), "hunter2") || strings.Contains(string(data), "user@example.com") {
+		t.Fatalf("encrypted file contains plaintext: %s", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Fatalf("encrypted file permissions %#o", info.Mode().Perm())
+	}
+
+	forgetKey(path)
+	t.Setenv(PassphraseEnvVar, "wrong")
+	if _, err := store.LoadCredentials(); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected ErrWrongPassphrase, got %v", err)
+	}
+}
+
+func TestEncryptedFilePromptAndRekey(t *testing.T) {
+	isolateConfigHome(t)
+	t.Setenv(CredentialStoreEnvVar, "")
+	t.Setenv(PassphraseFDEnvVar, "")
+	t.Setenv(PassphraseEnvVar, "")
+
+	var confirmed []bool
+	passphrase := "first"
+	store := Store{Backend: StoreEncryptedFile, Profile: "work2", Prompt: func(label string, confirm bool) ([]byte, error) {
+		confirmed = append(confirmed, confirm)
+		return []byte(passphrase), nil
+	}}
+	if err := store.SaveSession(`{"cookies":[]}`); err != nil {
+		t.Fatalf("save session: %v", err)
+	}
+	if len(confirmed) != 1 || !confirmed[0] {
+		t.Fatalf("expected one confirmed prompt for a new file, got %v", confirmed)
+	}
+	if _, err := store.LoadSession(); err != nil || len(confirmed) != 1 {
+		t.Fatalf("expected the derived key to be reused, prompts %v, err %v", confirmed, err)
+	}
+
+	if err := store.Rekey([]byte("second")); err != nil {
+		t.Fatalf("rekey: %v", err)
+	}
+	path := encryptedPath(t, "credentials-work2.enc")
+	forgetKey(path)
+	if _, err := store.LoadSession(); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected the old passphrase to fail after rekey, got %v", err)
+	}
+	passphrase = "second"
+	if got, err := store.LoadSession(); err != nil || got != `{"cookies":[]}` {
+		t.Fatalf("load after rekey: %q, %v", got, err)
+	}
+}
+
+func TestEncryptedFilePassphraseFromFD(t *testing.T) {
+	isolateConfigHome(t)
+	t.Setenv(CredentialStoreEnvVar, "")
+	t.Setenv(PassphraseEnvVar, "")
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	if _, err := w.WriteString("from-fd\n"); err != nil {
+		t.Fatalf("write pipe: %v", err)
+	}
+	w.Close()
+	t.Setenv(PassphraseFDEnvVar, strconv.Itoa(int(r.Fd())))
+
+	store := Store{Backend: StoreEncryptedFile}
+	if err := store.SaveTOTPSecret("JBSWY3DPEHPK3PXP"); err != nil {
+		t.Fatalf("save with fd passphrase: %v", err)
+	}
+	forgetKey(encryptedPath(t, encryptedFileName))
+	t.Setenv(PassphraseFDEnvVar, "")
+	t.Setenv(PassphraseEnvVar, "from-fd")
+	if got, err := store.LoadTOTPSecret(); err != nil || got != "JBSWY3DPEHPK3PXP" {
+		t.Fatalf("load with env passphrase: %q, %v", got, err)
+	}
+}
+
+func TestEncryptedFileDeleteCredentialsNeedsNoPassphrase(t *testing.T) {
+	isolateConfigHome(t)
+	t.Setenv(CredentialStoreEnvVar, "")
+	t.Setenv(PassphraseFDEnvVar, "")
+	t.Setenv(PassphraseEnvVar, "")
+
+	prompts := 0
+	store := Store{Backend: StoreEncryptedFile, Profile: "gone", Prompt: func(string, bool) ([]byte, error) {
+		prompts++
+		return []byte("pass"), nil
+	}}
+	if err := store.SaveCredentials(Credentials{Username: "u", Password: "p"}); err != nil {
+		t.Fatalf("save credentials: %v", err)
+	}
+	path := encryptedPath(t, "credentials-gone.enc")
+	forgetKey(path)
+	prompts = 0
+	if err := store.DeleteCredentials(); err != nil {
+		t.Fatalf("delete credentials: %v", err)
+	}
+	if prompts != 0 {
+		t.Fatalf("expected no passphrase prompt, got %d", prompts)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected %s to be removed, got %v", path, err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/ihildy/magnit-vms-cli/internal/config"
@@ -16,10 +17,15 @@ const (
 	serviceName              = "magnit-vms-cli"
 	userKey                  = "username"
 	passKey                  = "password"
+	sessionKey               = "session"
+	totpKey                  = "totp_secret"
 	credentialsFileName      = "credentials.yaml"
+	encryptedFileName        = "credentials.enc"
 	StoreAuto                = "auto"
 	StoreKeyring             = "keyring"
 	StoreFile                = "file"
+	StoreEncryptedFile       = "encrypted-file"
+	StoreCommand             = "command"
 	CredentialStoreEnvVar    = "MAGNIT_CREDENTIAL_STORE"
 	defaultCredentialBackend = StoreAuto
 )
@@ -29,182 +35,420 @@ type Credentials struct {
 	Password string
 }
 
-var ErrCredentialsNotFound = errors.New("credentials not found")
+var (
+	ErrCredentialsNotFound = errors.New("credentials not found")
+	ErrSessionNotFound     = errors.New("saved session not found")
+	ErrTOTPSecretNotFound  = errors.New("TOTP secret not found")
+	errItemNotFound        = errors.New("item not found")
+)
+
+// Store selects the backend ("" for the configured default) and the profile
+// whose entries are used. The default profile keeps the original keyring
+// service and credentials.yaml, so logins from before profiles still load;
+// other profiles get their own service and credentials-<profile>.yaml.
+type Store struct {
+	Backend string
+	Profile string
+	// Prompt asks for the encrypted-file passphrase when neither
+	// MAGNIT_CREDENTIAL_PASSPHRASE nor MAGNIT_CREDENTIAL_PASSPHRASE_FD is set.
+	Prompt PromptFunc
+	// Helper is the command line of the command store's helper;
+	// MAGNIT_CREDENTIAL_HELPER overrides it.
+	Helper string
+}
 
 func SaveCredentials(creds Credentials) error {
 	return SaveCredentialsWithStore(creds, "")
 }
 
 func SaveCredentialsWithStore(creds Credentials, preferredStore string) error {
+	return Store{Backend: preferredStore}.SaveCredentials(creds)
+}
+
+func (s Store) SaveCredentials(creds Credentials) error {
 	if creds.Username == "" {
 		return errors.New("username is required")
 	}
 	if creds.Password == "" {
 		return errors.New("password is required")
 	}
+	if err := s.saveItems(map[string]string{userKey: creds.Username, passKey: creds.Password}); err != nil {
+		return fmt.Errorf("save credentials: %w", err)
+	}
+	return nil
+}
+
+func LoadCredentials() (Credentials, error) {
+	return LoadCredentialsWithStore("")
+}
 
-	store, err := resolveStore(preferredStore)
+func LoadCredentialsWithStore(preferredStore string) (Credentials, error) {
+	return Store{Backend: preferredStore}.LoadCredentials()
+}
+
+func (s Store) LoadCredentials() (Credentials, error) {
+	items, err := s.loadItems(userKey, passKey)
 	if err != nil {
-		return err
+		if errors.Is(err, errItemNotFound) {
+			return Credentials{}, ErrCredentialsNotFound
+		}
+		return Credentials{}, err
+	}
+	if strings.TrimSpace(items[userKey]) == "" || items[passKey] == "" {
+		return Credentials{}, fmt.Errorf("stored credentials are missing required fields")
 	}
+	return Credentials{Username: items[userKey], Password: items[passKey]}, nil
+}
 
-	switch store {
-	case StoreKeyring:
-		return saveToKeyring(creds)
-	case StoreFile:
-		return saveToFile(creds)
-	case StoreAuto:
-		keyringErr := saveToKeyring(creds)
-		if keyringErr == nil {
-			return nil
+func DeleteCredentials() error {
+	return DeleteCredentialsWithStore("")
+}
+
+func DeleteCredentialsWithStore(preferredStore string) error {
+	return Store{Backend: preferredStore}.DeleteCredentials()
+}
+
+// DeleteCredentials removes the username, password, TOTP secret and any
+// saved session.
+func (s Store) DeleteCredentials() error {
+	if err := s.deleteItems(userKey, passKey, totpKey, sessionKey); err != nil {
+		return fmt.Errorf("delete credentials: %w", err)
+	}
+	return nil
+}
+
+func SaveSessionWithStore(session string, preferredStore string) error {
+	return Store{Backend: preferredStore}.SaveSession(session)
+}
+
+// SaveSession stores an opaque serialized session next to the credentials.
+func (s Store) SaveSession(session string) error {
+	if session == "" {
+		return errors.New("session is empty")
+	}
+	if err := s.saveItems(map[string]string{sessionKey: session}); err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+	return nil
+}
+
+func LoadSessionWithStore(preferredStore string) (string, error) {
+	return Store{Backend: preferredStore}.LoadSession()
+}
+
+func (s Store) LoadSession() (string, error) {
+	items, err := s.loadItems(sessionKey)
+	if err != nil {
+		if errors.Is(err, errItemNotFound) {
+			return "", ErrSessionNotFound
 		}
-		if fileErr := saveToFile(creds); fileErr != nil {
-			return fmt.Errorf("save credentials failed (keyring: %v, file: %w)", keyringErr, fileErr)
+		return "", err
+	}
+	return items[sessionKey], nil
+}
+
+func DeleteSessionWithStore(preferredStore string) error {
+	return Store{Backend: preferredStore}.DeleteSession()
+}
+
+func (s Store) DeleteSession() error {
+	if err := s.deleteItems(sessionKey); err != nil {
+		return fmt.Errorf("delete session: %w", err)
+	}
+	return nil
+}
+
+func SaveTOTPSecretWithStore(secret string, preferredStore string) error {
+	return Store{Backend: preferredStore}.SaveTOTPSecret(secret)
+}
+
+// SaveTOTPSecret stores the base32 secret used to generate MFA codes at
+// login.
+func (s Store) SaveTOTPSecret(secret string) error {
+	if secret == "" {
+		return errors.New("TOTP secret is empty")
+	}
+	if err := s.saveItems(map[string]string{totpKey: secret}); err != nil {
+		return fmt.Errorf("save TOTP secret: %w", err)
+	}
+	return nil
+}
+
+func LoadTOTPSecretWithStore(preferredStore string) (string, error) {
+	return Store{Backend: preferredStore}.LoadTOTPSecret()
+}
+
+func (s Store) LoadTOTPSecret() (string, error) {
+	items, err := s.loadItems(totpKey)
+	if err != nil {
+		if errors.Is(err, errItemNotFound) {
+			return "", ErrTOTPSecretNotFound
 		}
+		return "", err
+	}
+	return items[totpKey], nil
+}
+
+func DeleteTOTPSecretWithStore(preferredStore string) error {
+	return Store{Backend: preferredStore}.DeleteTOTPSecret()
+}
+
+func (s Store) DeleteTOTPSecret() error {
+	if err := s.deleteItems(totpKey); err != nil {
+		return fmt.Errorf("delete TOTP secret: %w", err)
+	}
+	return nil
+}
+
+func ValidateCredentialStore(store string) error {
+	switch normalizeStore(store) {
+	case StoreAuto, StoreKeyring, StoreFile, StoreEncryptedFile, StoreCommand:
 		return nil
 	default:
-		return fmt.Errorf("unsupported credential store %q", store)
+		return fmt.Errorf("invalid credential store %q (allowed: %s, %s, %s, %s, %s)", store, StoreAuto, StoreKeyring, StoreFile, StoreEncryptedFile, StoreCommand)
 	}
 }
 
-func LoadCredentials() (Credentials, error) {
-	return LoadCredentialsWithStore("")
+func NormalizeCredentialStore(store string) string {
+	return normalizeStore(store)
 }
 
-func LoadCredentialsWithStore(preferredStore string) (Credentials, error) {
-	store, err := resolveStore(preferredStore)
-	if err != nil {
-		return Credentials{}, err
+// backend stores named string items. get returns errItemNotFound for
+// missing keys.
+type backend interface {
+	get(key string) (string, error)
+	set(items map[string]string) error
+	remove(keys []string) error
+}
+
+func (s Store) service() string {
+	if s.isDefaultProfile() {
+		return serviceName
+	}
+	return serviceName + ":" + s.Profile
+}
+
+func (s Store) fileName() string {
+	if s.isDefaultProfile() {
+		return credentialsFileName
+	}
+	return "credentials-" + s.Profile + ".yaml"
+}
+
+func (s Store) encryptedFileName() string {
+	if s.isDefaultProfile() {
+		return encryptedFileName
 	}
+	return "credentials-" + s.Profile + ".enc"
+}
+
+func (s Store) isDefaultProfile() bool {
+	return s.Profile == "" || s.Profile == config.DefaultProfile
+}
 
+func (s Store) backendFor(store string) (backend, error) {
 	switch store {
 	case StoreKeyring:
-		return loadFromKeyring()
+		return keyringBackend{service: s.service()}, nil
 	case StoreFile:
-		return loadFromFile()
-	case StoreAuto:
-		creds, err := loadFromKeyring()
-		if err == nil {
-			return creds, nil
+		return fileBackend{name: s.fileName()}, nil
+	case StoreEncryptedFile:
+		return encryptedFileBackend{name: s.encryptedFileName(), prompt: s.Prompt}, nil
+	case StoreCommand:
+		helper := strings.TrimSpace(os.Getenv(CredentialHelperEnvVar))
+		if helper == "" {
+			helper = s.Helper
 		}
-		fileCreds, fileErr := loadFromFile()
-		if fileErr == nil {
-			return fileCreds, nil
+		profile := s.Profile
+		if s.isDefaultProfile() {
+			profile = config.DefaultProfile
 		}
-		if errors.Is(err, ErrCredentialsNotFound) && errors.Is(fileErr, ErrCredentialsNotFound) {
-			return Credentials{}, ErrCredentialsNotFound
+		return commandBackend{helper: helper, service: serviceName, profile: profile}, nil
+	default:
+		return nil, fmt.Errorf("unsupported credential store %q", store)
+	}
+}
+
+func (s Store) saveItems(items map[string]string) error {
+	store, err := resolveStore(s.Backend)
+	if err != nil {
+		return err
+	}
+	if store == StoreAuto {
+		keyringErr := keyringBackend{service: s.service()}.set(items)
+		if keyringErr == nil {
+			return nil
 		}
-		if errors.Is(err, ErrCredentialsNotFound) {
-			return Credentials{}, fileErr
+		// Session cookies are bearer tokens. Only add them to the plaintext
+		// file when auto already keeps the password there, e.g. on machines
+		// without Secret Service, not when the keyring merely refused them.
+		if _, ok := items[sessionKey]; ok {
+			if _, err := (fileBackend{name: s.fileName()}).get(userKey); err != nil {
+				return fmt.Errorf("keyring: %v; not writing the session to the plaintext credentials file (set the credential store to file to allow it)", keyringErr)
+			}
 		}
-		if errors.Is(fileErr, ErrCredentialsNotFound) {
-			return Credentials{}, err
+		if fileErr := (fileBackend{name: s.fileName()}).set(items); fileErr != nil {
+			return fmt.Errorf("keyring: %v, file: %w", keyringErr, fileErr)
 		}
-		return Credentials{}, fmt.Errorf("load credentials failed (keyring: %v, file: %w)", err, fileErr)
-	default:
-		return Credentials{}, fmt.Errorf("unsupported credential store %q", store)
+		return nil
 	}
+	b, err := s.backendFor(store)
+	if err != nil {
+		return err
+	}
+	return b.set(items)
 }
 
-func DeleteCredentials() error {
-	return DeleteCredentialsWithStore("")
+func (s Store) loadItems(keys ...string) (map[string]string, error) {
+	store, err := resolveStore(s.Backend)
+	if err != nil {
+		return nil, err
+	}
+	if store == StoreAuto {
+		items, err := getAll(keyringBackend{service: s.service()}, keys)
+		if err == nil {
+			return items, nil
+		}
+		fileItems, fileErr := getAll(fileBackend{name: s.fileName()}, keys)
+		if fileErr == nil {
+			return fileItems, nil
+		}
+		switch {
+		case errors.Is(err, errItemNotFound) && errors.Is(fileErr, errItemNotFound):
+			return nil, errItemNotFound
+		case errors.Is(err, errItemNotFound):
+			return nil, fileErr
+		case errors.Is(fileErr, errItemNotFound):
+			return nil, err
+		}
+		return nil, fmt.Errorf("load failed (keyring: %v, file: %w)", err, fileErr)
+	}
+	b, err := s.backendFor(store)
+	if err != nil {
+		return nil, err
+	}
+	return getAll(b, keys)
 }
 
-func DeleteCredentialsWithStore(preferredStore string) error {
-	store, err := resolveStore(preferredStore)
+func (s Store) deleteItems(keys ...string) error {
+	store, err := resolveStore(s.Backend)
 	if err != nil {
 		return err
 	}
-
-	switch store {
-	case StoreKeyring:
-		return deleteFromKeyring()
-	case StoreFile:
-		return deleteFromFile()
-	case StoreAuto:
-		keyringErr := deleteFromKeyring()
-		fileErr := deleteFromFile()
+	if store == StoreAuto {
+		keyringErr := keyringBackend{service: s.service()}.remove(keys)
+		fileErr := fileBackend{name: s.fileName()}.remove(keys)
 		if keyringErr != nil && fileErr != nil {
-			return fmt.Errorf("delete credentials failed (keyring: %v, file: %w)", keyringErr, fileErr)
+			return fmt.Errorf("keyring: %v, file: %w", keyringErr, fileErr)
 		}
 		return nil
-	default:
-		return fmt.Errorf("unsupported credential store %q", store)
 	}
-}
-
-func ValidateCredentialStore(store string) error {
-	switch normalizeStore(store) {
-	case StoreAuto, StoreKeyring, StoreFile:
-		return nil
-	default:
-		return fmt.Errorf("invalid credential store %q (allowed: %s, %s, %s)", store, StoreAuto, StoreKeyring, StoreFile)
+	b, err := s.backendFor(store)
+	if err != nil {
+		return err
 	}
+	return b.remove(keys)
 }
 
-func NormalizeCredentialStore(store string) string {
-	return normalizeStore(store)
+// batchGetter is implemented by backends that fetch several keys at once,
+// such as the command store, where each get starts a process.
+type batchGetter interface {
+	getMany(keys []string) (map[string]string, error)
 }
 
-func saveToKeyring(creds Credentials) error {
-	if err := zk.Set(serviceName, userKey, creds.Username); err != nil {
-		return fmt.Errorf("save username to keyring: %w", err)
+func getAll(b backend, keys []string) (map[string]string, error) {
+	if batch, ok := b.(batchGetter); ok {
+		return batch.getMany(keys)
 	}
-	if err := zk.Set(serviceName, passKey, creds.Password); err != nil {
-		return fmt.Errorf("save password to keyring: %w", err)
+	out := make(map[string]string, len(keys))
+	for _, key := range keys {
+		value, err := b.get(key)
+		if err != nil {
+			return nil, err
+		}
+		out[key] = value
 	}
-	return nil
+	return out, nil
+}
+
+type keyringBackend struct {
+	service string
 }
 
-func loadFromKeyring() (Credentials, error) {
-	username, err := zk.Get(serviceName, userKey)
+func (k keyringBackend) get(key string) (string, error) {
+	value, err := zk.Get(k.service, key)
 	if err != nil {
 		if errors.Is(err, zk.ErrNotFound) {
-			return Credentials{}, ErrCredentialsNotFound
+			return "", errItemNotFound
 		}
-		return Credentials{}, fmt.Errorf("read username from keyring: %w", err)
+		return "", fmt.Errorf("read %s from keyring: %w", key, err)
 	}
+	return value, nil
+}
 
-	password, err := zk.Get(serviceName, passKey)
-	if err != nil {
-		if errors.Is(err, zk.ErrNotFound) {
-			return Credentials{}, ErrCredentialsNotFound
+func (k keyringBackend) set(items map[string]string) error {
+	for _, key := range sortedKeys(items) {
+		if err := zk.Set(k.service, key, items[key]); err != nil {
+			return fmt.Errorf("save %s to keyring: %w", key, err)
 		}
-		return Credentials{}, fmt.Errorf("read password from keyring: %w", err)
 	}
-
-	return Credentials{Username: username, Password: password}, nil
+	return nil
 }
 
-func deleteFromKeyring() error {
-	userErr := zk.Delete(serviceName, userKey)
-	passErr := zk.Delete(serviceName, passKey)
-	if userErr != nil && !errors.Is(userErr, zk.ErrNotFound) {
-		return fmt.Errorf("delete username from keyring: %w", userErr)
-	}
-	if passErr != nil && !errors.Is(passErr, zk.ErrNotFound) {
-		return fmt.Errorf("delete password from keyring: %w", passErr)
+func (k keyringBackend) remove(keys []string) error {
+	for _, key := range keys {
+		if err := zk.Delete(k.service, key); err != nil && !errors.Is(err, zk.ErrNotFound) {
+			return fmt.Errorf("delete %s from keyring: %w", key, err)
+		}
 	}
 	return nil
 }
 
-func credentialsFilePath() (string, error) {
+// fileBackend keeps items as a flat YAML map in the named file next to the
+// config, so files written before sessions were stored (username and
+// password only) still load.
+type fileBackend struct {
+	name string
+}
+
+func (f fileBackend) path() (string, error) {
 	cfgPath, err := config.ConfigPath()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(filepath.Dir(cfgPath), credentialsFileName), nil
+	return filepath.Join(filepath.Dir(cfgPath), f.name), nil
 }
 
-func saveToFile(creds Credentials) error {
-	path, err := credentialsFilePath()
+func (f fileBackend) read() (string, map[string]string, error) {
+	path, err := f.path()
 	if err != nil {
-		return fmt.Errorf("resolve credentials path: %w", err)
+		return "", nil, fmt.Errorf("resolve credentials path: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return path, map[string]string{}, nil
+		}
+		return "", nil, fmt.Errorf("read credentials file: %w", err)
+	}
+	items := map[string]string{}
+	if err := yaml.Unmarshal(data, &items); err != nil {
+		return "", nil, fmt.Errorf("parse credentials file: %w", err)
+	}
+	return path, items, nil
+}
+
+func (fileBackend) write(path string, items map[string]string) error {
+	if len(items) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("delete credentials file: %w", err)
+		}
+		return nil
 	}
 	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
 		return fmt.Errorf("create credentials dir: %w", err)
 	}
-	data, err := yaml.Marshal(&creds)
+	data, err := yaml.Marshal(items)
 	if err != nil {
 		return fmt.Errorf("marshal credentials: %w", err)
 	}
@@ -214,40 +458,47 @@ func saveToFile(creds Credentials) error {
 	return nil
 }
 
-func loadFromFile() (Credentials, error) {
-	path, err := credentialsFilePath()
+func (f fileBackend) get(key string) (string, error) {
+	_, items, err := f.read()
 	if err != nil {
-		return Credentials{}, fmt.Errorf("resolve credentials path: %w", err)
+		return "", err
 	}
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return Credentials{}, ErrCredentialsNotFound
-		}
-		return Credentials{}, fmt.Errorf("read credentials file: %w", err)
+	value, ok := items[key]
+	if !ok {
+		return "", errItemNotFound
 	}
-	var creds Credentials
-	if err := yaml.Unmarshal(data, &creds); err != nil {
-		return Credentials{}, fmt.Errorf("parse credentials file: %w", err)
+	return value, nil
+}
+
+func (f fileBackend) set(updates map[string]string) error {
+	path, items, err := f.read()
+	if err != nil {
+		return err
 	}
-	if strings.TrimSpace(creds.Username) == "" || creds.Password == "" {
-		return Credentials{}, fmt.Errorf("credentials file is missing required fields")
+	for k, v := range updates {
+		items[k] = v
 	}
-	return creds, nil
+	return f.write(path, items)
 }
 
-func deleteFromFile() error {
-	path, err := credentialsFilePath()
+func (f fileBackend) remove(keys []string) error {
+	path, items, err := f.read()
 	if err != nil {
-		return fmt.Errorf("resolve credentials path: %w", err)
+		return err
 	}
-	if err := os.Remove(path); err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return nil
-		}
-		return fmt.Errorf("delete credentials file: %w", err)
+	for _, k := range keys {
+		delete(items, k)
 	}
-	return nil
+	return f.write(path, items)
+}
+
+func sortedKeys(items map[string]string) []string {
+	keys := make([]string, 0, len(items))
+	for k := range items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 func resolveStore(preferredStore string) (string, error) {
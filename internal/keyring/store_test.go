@@ -1,6 +1,7 @@
 package keyring
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -77,7 +78,7 @@ func TestDeleteFromFileNotFound(t *testing.T) {
 }
 
 func TestValidateCredentialStore(t *testing.T) {
-	valid := []string{"", "AUTO", StoreAuto, StoreKeyring, StoreFile}
+	valid := []string{"", "AUTO", StoreAuto, StoreKeyring, StoreFile, StoreEncryptedFile, StoreCommand}
 	for _, input := range valid {
 		if err := ValidateCredentialStore(input); err != nil {
 			t.Fatalf("expected valid store %q, got error: %v", input, err)
@@ -87,3 +88,98 @@ func TestValidateCredentialStore(t *testing.T) {
 		t.Fatalf("expected validation error for invalid store")
 	}
 }
+
+func TestFileStoreKeepsSessionAlongsideCredentials(t *testing.T) {
+	isolateConfigHome(t)
+	t.Setenv(CredentialStoreEnvVar, "")
+
+	creds := Credentials{Username: "user@example.com", Password: "secret"}
+	if err := SaveCredentialsWithStore(creds, StoreFile); err != nil {
+		t.Fatalf("save credentials: %v", err)
+	}
+	if err := SaveSessionWithStore(`{"cookies":[]}`, StoreFile); err != nil {
+		t.Fatalf("save session: %v", err)
+	}
+	if got, err := LoadCredentialsWithStore(StoreFile); err != nil || got != creds {
+		t.Fatalf("credentials after session save: %+v, %v", got, err)
+	}
+	if got, err := LoadSessionWithStore(StoreFile); err != nil || got != `{"cookies":[]}` {
+		t.Fatalf("load session: %q, %v", got, err)
+	}
+
+	if err := DeleteCredentialsWithStore(StoreFile); err != nil {
+		t.Fatalf("delete credentials: %v", err)
+	}
+	if _, err := LoadSessionWithStore(StoreFile); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("expected session removed with credentials, got %v", err)
+	}
+}
+
+func TestProfilesUseSeparateEntries(t *testing.T) {
+	isolateConfigHome(t)
+	t.Setenv(CredentialStoreEnvVar, "")
+
+	defaults := Credentials{Username: "first@example.com", Password: "one"}
+	other := Credentials{Username: "second@example.com", Password: "two"}
+	if err := (Store{Backend: StoreFile}).SaveCredentials(defaults); err != nil {
+		t.Fatalf("save default profile: %v", err)
+	}
+	work := Store{Backend: StoreFile, Profile: "work2"}
+	if err := work.SaveCredentials(other); err != nil {
+		t.Fatalf("save work2 profile: %v", err)
+	}
+
+	if got, err := LoadCredentialsWithStore(StoreFile); err != nil || got != defaults {
+		t.Fatalf("default profile: %+v, %v", got, err)
+	}
+	if got, err := work.LoadCredentials(); err != nil || got != other {
+		t.Fatalf("work2 profile: %+v, %v", got, err)
+	}
+
+	cfgPath, err := config.ConfigPath()
+	if err != nil {
+		t.Fatalf("config path: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(cfgPath), "credentials-work2.yaml")); err != nil {
+		t.Fatalf("expected a per-profile credentials file: %v", err)
+	}
+
+	if err := work.DeleteCredentials(); err != nil {
+		t.Fatalf("delete work2: %v", err)
+	}
+	if _, err := work.LoadCredentials(); !errors.Is(err, ErrCredentialsNotFound) {
+		t.Fatalf("expected work2 credentials removed, got %v", err)
+	}
+	if _, err := LoadCredentialsWithStore(StoreFile); err != nil {
+		t.Fatalf("default profile credentials were removed: %v", err)
+	}
+}
+
+func TestAutoStoreKeepsSessionOutOfPlaintextFile(t *testing.T) {
+	isolateConfigHome(t)
+	t.Setenv(CredentialStoreEnvVar, "")
+	probe := keyringBackend{service: serviceName + "-probe"}
+	if err := probe.set(map[string]string{userKey: "probe"}); err == nil {
+		_ = probe.remove([]string{userKey})
+		t.Skip("a working keyring is available; the file fallback is not used")
+	}
+
+	store := Store{Backend: StoreAuto}
+	if err := store.SaveSession(`{"cookies":[]}`); err == nil {
+		t.Fatalf("expected the session not to fall back to the plaintext file")
+	}
+	cfgPath, err := config.ConfigPath()
+	if err != nil {
+		t.Fatalf("config path: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(cfgPath), credentialsFileName)); !os.IsNotExist(err) {
+		t.Fatalf("expected no credentials file, got %v", err)
+	}
+
+	if err := store.SaveCredentials(Credentials{Username: "u", Password: "p"}); err != nil {
+		t.Fatalf("save credentials: %v", err)
+	}
+	if err := store.SaveSession(`{"cookies":[]}`); err != nil {
+		t.Fatalf("expected the session to join credentials already in the file: %v", err)
+	}
+}
@@ -1,11 +1,39 @@
 package output
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
 )
 
+type Format string
+
+const (
+	FormatHuman  Format = "human"
+	FormatJSON   Format = "json"
+	FormatYAML   Format = "yaml"
+	FormatNDJSON Format = "ndjson"
+	FormatTable  Format = "table"
+	FormatCSV    Format = "csv"
+)
+
+var formats = []Format{FormatHuman, FormatJSON, FormatYAML, FormatNDJSON, FormatTable, FormatCSV}
+
+// Options selects how command results are rendered. A non-nil Template takes
+// precedence over Format.
+type Options struct {
+	Format   Format
+	Template *template.Template
+}
+
 type ErrorPayload struct {
 	OK      bool   `json:"ok"`
 	Code    string `json:"code"`
@@ -13,14 +41,57 @@ type ErrorPayload struct {
 	Details any    `json:"details,omitempty"`
 }
 
-func Write(w io.Writer, asJSON bool, human string, payload any) error {
-	if asJSON {
-		enc := json.NewEncoder(w)
-		enc.SetIndent("", "  ")
-		return enc.Encode(payload)
+func ParseFormat(s string) (Format, error) {
+	value := Format(strings.ToLower(strings.TrimSpace(s)))
+	if value == "" {
+		return FormatHuman, nil
+	}
+	for _, f := range formats {
+		if f == value {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("invalid output format %q (allowed: %s)", s, FormatNames())
+}
+
+func FormatNames() string {
+	names := make([]string, 0, len(formats))
+	for _, f := range formats {
+		names = append(names, string(f))
+	}
+	return strings.Join(names, ", ")
+}
+
+func (f Format) IsHuman() bool {
+	return f == "" || f == FormatHuman
+}
+
+// IsHuman reports whether the caller should render its own human text.
+func (o Options) IsHuman() bool {
+	return o.Template == nil && o.Format.IsHuman()
+}
+
+func Write(w io.Writer, opts Options, human string, payload any) error {
+	if opts.Template != nil {
+		return writeTemplate(w, opts.Template, payload)
+	}
+	switch format := opts.Format; format {
+	case "", FormatHuman:
+		_, err := fmt.Fprintln(w, human)
+		return err
+	case FormatJSON:
+		return WriteJSON(w, payload)
+	case FormatYAML:
+		return writeYAML(w, payload)
+	case FormatNDJSON:
+		return writeNDJSON(w, payload)
+	case FormatTable:
+		return writeTable(w, payload)
+	case FormatCSV:
+		return writeCSV(w, payload)
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
 	}
-	_, err := fmt.Fprintln(w, human)
-	return err
 }
 
 func WriteJSON(w io.Writer, payload any) error {
@@ -37,3 +108,257 @@ func NewErrorPayload(code, message string, details any) ErrorPayload {
 		Details: details,
 	}
 }
+
+func writeYAML(w io.Writer, payload any) error {
+	generic, err := toGeneric(payload)
+	if err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(generic); err != nil {
+		return fmt.Errorf("encode yaml output: %w", err)
+	}
+	return enc.Close()
+}
+
+// writeNDJSON emits one compact JSON document per record. Payloads that wrap
+// a single list of objects (e.g. engagement list) are streamed item by item.
+func writeNDJSON(w io.Writer, payload any) error {
+	generic, err := toGeneric(payload)
+	if err != nil {
+		return err
+	}
+	records := []any{generic}
+	if _, items, ok := primaryCollection(generic); ok {
+		records = items
+	}
+	enc := json.NewEncoder(w)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTable(w io.Writer, payload any) error {
+	header, rows, vertical, err := tabulate(payload)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if vertical {
+		fmt.Fprintln(tw, "FIELD\tVALUE")
+		for i, key := range header {
+			fmt.Fprintf(tw, "%s\t%s\n", key, rows[0][i])
+		}
+		return tw.Flush()
+	}
+	upper := make([]string, len(header))
+	for i, h := range header {
+		upper[i] = strings.ToUpper(h)
+	}
+	fmt.Fprintln(tw, strings.Join(upper, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+func writeCSV(w io.Writer, payload any) error {
+	header, rows, _, err := tabulate(payload)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// tabulate flattens a payload into a header and rows. When the payload holds a
+// single list of objects those become the rows; otherwise the payload itself is
+// a single row and vertical reports that a key/value layout reads better.
+func tabulate(payload any) ([]string, [][]string, bool, error) {
+	generic, err := toGeneric(payload)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	if _, items, ok := primaryCollection(generic); ok {
+		flat := make([]map[string]string, 0, len(items))
+		var header []string
+		seen := map[string]struct{}{}
+		for _, item := range items {
+			row := map[string]string{}
+			flatten("", item, row)
+			for _, key := range sortedKeys(row) {
+				if _, ok := seen[key]; !ok {
+					seen[key] = struct{}{}
+					header = append(header, key)
+				}
+			}
+			flat = append(flat, row)
+		}
+		rows := make([][]string, 0, len(flat))
+		for _, row := range flat {
+			values := make([]string, len(header))
+			for i, key := range header {
+				values[i] = row[key]
+			}
+			rows = append(rows, values)
+		}
+		return header, rows, false, nil
+	}
+
+	row := map[string]string{}
+	flatten("", generic, row)
+	header := sortedKeys(row)
+	values := make([]string, len(header))
+	for i, key := range header {
+		values[i] = row[key]
+	}
+	return header, [][]string{values}, true, nil
+}
+
+// primaryCollection finds the only top-level field holding a list of objects.
+func primaryCollection(v any) (string, []any, bool) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		if items, ok := v.([]any); ok {
+			return "", items, true
+		}
+		return "", nil, false
+	}
+	var key string
+	var found []any
+	count := 0
+	for k, val := range m {
+		items, ok := val.([]any)
+		if !ok || len(items) == 0 {
+			continue
+		}
+		if _, ok := items[0].(map[string]any); !ok {
+			continue
+		}
+		key, found = k, items
+		count++
+	}
+	if count != 1 {
+		return "", nil, false
+	}
+	return key, found, true
+}
+
+func flatten(prefix string, v any, out map[string]string) {
+	switch t := v.(type) {
+	case map[string]any:
+		if len(t) == 0 && prefix != "" {
+			out[prefix] = ""
+		}
+		for k, val := range t {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			flatten(key, val, out)
+		}
+	case []any:
+		if prefix == "" {
+			prefix = "value"
+		}
+		allScalar := true
+		for _, item := range t {
+			switch item.(type) {
+			case map[string]any, []any:
+				allScalar = false
+			}
+		}
+		if allScalar {
+			parts := make([]string, 0, len(t))
+			for _, item := range t {
+				parts = append(parts, scalarString(item))
+			}
+			out[prefix] = strings.Join(parts, ";")
+			return
+		}
+		for i, item := range t {
+			flatten(fmt.Sprintf("%s.%d", prefix, i), item, out)
+		}
+	default:
+		if prefix == "" {
+			prefix = "value"
+		}
+		out[prefix] = scalarString(t)
+	}
+}
+
+func scalarString(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case json.Number:
+		return t.String()
+	default:
+		return fmt.Sprintf("%v", t)
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// toGeneric round-trips the payload through JSON so struct tags are honored
+// by every format and numbers keep their JSON representation.
+func toGeneric(payload any) (any, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal output payload: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out any
+	if err := dec.Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode output payload: %w", err)
+	}
+	return normalizeNumbers(out), nil
+}
+
+// normalizeNumbers converts json.Number into int64 or float64 so the YAML
+// encoder does not quote them as strings.
+func normalizeNumbers(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			t[k] = normalizeNumbers(val)
+		}
+		return t
+	case []any:
+		for i, val := range t {
+			t[i] = normalizeNumbers(val)
+		}
+		return t
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		if f, err := t.Float64(); err == nil {
+			return f
+		}
+		return t.String()
+	default:
+		return v
+	}
+}
@@ -0,0 +1,88 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	got, err := ParseFormat(" YAML ")
+	if err != nil {
+		t.Fatalf("parse format: %v", err)
+	}
+	if got != FormatYAML {
+		t.Fatalf("expected yaml, got %q", got)
+	}
+	if got, _ := ParseFormat(""); got != FormatHuman {
+		t.Fatalf("expected empty format to default to human, got %q", got)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Fatalf("expected error for unsupported format")
+	}
+}
+
+func TestWriteCSVUsesPrimaryCollection(t *testing.T) {
+	payload := map[string]any{
+		"ok":    true,
+		"count": 2,
+		"engagements": []map[string]any{
+			{"id": 1, "status": "Active"},
+			{"id": 2, "status": "Closed"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, Options{Format: FormatCSV}, "", payload); err != nil {
+		t.Fatalf("write csv: %v", err)
+	}
+	want := "id,status\n1,Active\n2,Closed\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected csv:\n%s", buf.String())
+	}
+}
+
+func TestWriteNDJSONStreamsItems(t *testing.T) {
+	payload := map[string]any{
+		"ok":    true,
+		"items": []map[string]any{{"id": 1}, {"id": 2}},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, Options{Format: FormatNDJSON}, "", payload); err != nil {
+		t.Fatalf("write ndjson: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || lines[0] != `{"id":1}` || lines[1] != `{"id":2}` {
+		t.Fatalf("unexpected ndjson: %q", buf.String())
+	}
+}
+
+func TestWriteTableFlattensSingleObject(t *testing.T) {
+	payload := map[string]any{
+		"ok":      true,
+		"summary": map[string]any{"worked_date": "02/18/2026", "did_not_work": false},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, Options{Format: FormatTable}, "", payload); err != nil {
+		t.Fatalf("write table: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"FIELD", "summary.worked_date", "02/18/2026", "summary.did_not_work"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in table output:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteYAMLKeepsNumbersUnquoted(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, Options{Format: FormatYAML}, "", map[string]any{"engagement_id": int64(12345678), "hours": 7.5}); err != nil {
+		t.Fatalf("write yaml: %v", err)
+	}
+	want := "engagement_id: 12345678\nhours: 7.5\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected yaml:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,118 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+)
+
+// ParseTemplate compiles a --format template with the timecard helpers
+// available as functions.
+func ParseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("format").Funcs(TemplateFuncs()).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --format template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// TemplateFuncs lists the helpers usable from --format templates:
+//
+//	hours SPANS        labor hours of a span list (e.g. .summary.spans)
+//	spanHours SPAN     length of one span in hours
+//	duration VALUE     hours or a span rendered as 7h30m
+//	date LAYOUT VALUE  reformat an MM/DD/YYYY or YYYY-MM-DD date with a Go layout
+//	json VALUE         compact JSON encoding
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"hours": func(v any) (float64, error) {
+			spans, err := toSpanSummaries(v)
+			if err != nil {
+				return 0, err
+			}
+			return timecard.LaborHours(spans), nil
+		},
+		"spanHours": func(v any) (float64, error) {
+			span, err := toSpanSummary(v)
+			if err != nil {
+				return 0, err
+			}
+			return timecard.SpanHours(span), nil
+		},
+		"duration": func(v any) (string, error) {
+			switch t := v.(type) {
+			case float64:
+				return timecard.FormatDuration(t), nil
+			case int64:
+				return timecard.FormatDuration(float64(t)), nil
+			case int:
+				return timecard.FormatDuration(float64(t)), nil
+			}
+			span, err := toSpanSummary(v)
+			if err != nil {
+				return "", fmt.Errorf("duration expects hours or a span: %w", err)
+			}
+			return timecard.FormatDuration(timecard.SpanHours(span)), nil
+		},
+		"date": func(layout string, v any) (string, error) {
+			t, err := timecard.ParseWorkedDate(scalarString(v), time.UTC)
+			if err != nil {
+				return "", err
+			}
+			return t.Format(layout), nil
+		},
+		"json": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			if err != nil {
+				return "", err
+			}
+			return string(data), nil
+		},
+	}
+}
+
+func writeTemplate(w io.Writer, tmpl *template.Template, payload any) error {
+	generic, err := toGeneric(payload)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, generic); err != nil {
+		return fmt.Errorf("render --format template: %w", err)
+	}
+	out := b.String()
+	if !strings.HasSuffix(out, "\n") {
+		out += "\n"
+	}
+	_, err = io.WriteString(w, out)
+	return err
+}
+
+func toSpanSummaries(v any) ([]timecard.SpanSummary, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var spans []timecard.SpanSummary
+	if err := json.Unmarshal(data, &spans); err != nil {
+		return nil, fmt.Errorf("expected a list of spans")
+	}
+	return spans, nil
+}
+
+func toSpanSummary(v any) (timecard.SpanSummary, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return timecard.SpanSummary{}, err
+	}
+	var span timecard.SpanSummary
+	if err := json.Unmarshal(data, &span); err != nil {
+		return timecard.SpanSummary{}, fmt.Errorf("expected a span")
+	}
+	return span, nil
+}
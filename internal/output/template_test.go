@@ -0,0 +1,39 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+)
+
+func TestWriteTemplateRendersPayloadWithHelpers(t *testing.T) {
+	tmpl, err := ParseTemplate(`{{date "Mon Jan 2" .summary.worked_date}} {{hours .summary.spans}} {{duration (hours .summary.spans)}}`)
+	if err != nil {
+		t.Fatalf("parse template: %v", err)
+	}
+	payload := map[string]any{
+		"summary": timecard.DaySummary{
+			WorkedDate: "02/18/2026",
+			Spans: []timecard.SpanSummary{
+				{Type: timecard.SpanTypeLabor, Start: "09:00", End: "12:00"},
+				{Type: timecard.SpanTypeLunch, Start: "12:00", End: "12:30"},
+				{Type: timecard.SpanTypeLabor, Start: "12:30", End: "17:00"},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, Options{Template: tmpl}, "", payload); err != nil {
+		t.Fatalf("write template: %v", err)
+	}
+	if got, want := buf.String(), "Wed Feb 18 7.5 7h30m\n"; got != want {
+		t.Fatalf("unexpected template output: got %q want %q", got, want)
+	}
+}
+
+func TestParseTemplateRejectsInvalidSyntax(t *testing.T) {
+	if _, err := ParseTemplate("{{.summary"); err == nil {
+		t.Fatalf("expected template parse error")
+	}
+}
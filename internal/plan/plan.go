@@ -0,0 +1,85 @@
+// Package plan reads and writes reviewable change plans: the proposed day
+// changes for each week together with a fingerprint of the server state they
+// were computed from.
+package plan
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+)
+
+// Version is bumped whenever the plan file layout changes incompatibly.
+const Version = 1
+
+// File is a saved plan.
+type File struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	BaseURL   string    `json:"base_url"`
+	Profile   string    `json:"profile,omitempty"`
+	Weeks     []Week    `json:"weeks"`
+}
+
+// Week holds the patched payload for one weekly timecard and the fingerprint
+// of the metadata it was computed from.
+type Week struct {
+	EngagementID int64                `json:"engagement_id"`
+	WeekStart    string               `json:"week_start"`
+	Fingerprint  string               `json:"fingerprint"`
+	Changes      []timecard.DayChange `json:"changes"`
+	Payload      map[string]any       `json:"payload"`
+}
+
+// Fingerprint hashes metadata in canonical JSON form (sorted keys) so any
+// server-side edit to the week changes it.
+func Fingerprint(metadata map[string]any) (string, error) {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return "", fmt.Errorf("fingerprint metadata: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// Write saves f as indented JSON.
+func Write(path string, f File) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal plan: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write plan: %w", err)
+	}
+	return nil
+}
+
+// Read loads and validates a plan file.
+func Read(path string) (File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return File{}, fmt.Errorf("read plan: %w", err)
+	}
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return File{}, fmt.Errorf("parse plan %s: %w", path, err)
+	}
+	if f.Version != Version {
+		return File{}, fmt.Errorf("plan %s has version %d, this build supports %d", path, f.Version, Version)
+	}
+	if len(f.Weeks) == 0 {
+		return File{}, errors.New("plan contains no weeks")
+	}
+	for i, w := range f.Weeks {
+		if w.EngagementID <= 0 || w.WeekStart == "" || w.Fingerprint == "" || w.Payload == nil {
+			return File{}, fmt.Errorf("plan week %d is incomplete", i+1)
+		}
+	}
+	return f, nil
+}
@@ -0,0 +1,58 @@
+package plan
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFingerprintIgnoresKeyOrderButNotValues(t *testing.T) {
+	a := map[string]any{"b": 1.0, "a": map[string]any{"y": "x", "z": []any{1.0, 2.0}}}
+	b := map[string]any{"a": map[string]any{"z": []any{1.0, 2.0}, "y": "x"}, "b": 1.0}
+	fa, err := Fingerprint(a)
+	if err != nil {
+		t.Fatalf("fingerprint: %v", err)
+	}
+	fb, _ := Fingerprint(b)
+	if fa != fb || !strings.HasPrefix(fa, "sha256:") {
+		t.Fatalf("fingerprints differ for equal maps: %s vs %s", fa, fb)
+	}
+	b["b"] = 2.0
+	if fc, _ := Fingerprint(b); fc == fa {
+		t.Fatal("fingerprint did not change with value")
+	}
+}
+
+func TestWriteReadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	want := File{
+		Version:   Version,
+		CreatedAt: time.Date(2026, 2, 18, 12, 0, 0, 0, time.UTC),
+		BaseURL:   "https://example.test",
+		Weeks: []Week{{
+			EngagementID: 42,
+			WeekStart:    "02/16/2026",
+			Fingerprint:  "sha256:abc",
+			Payload:      map[string]any{"billingItemDetails": []any{}},
+		}},
+	}
+	if err := Write(path, want); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	got, err := Read(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if got.BaseURL != want.BaseURL || len(got.Weeks) != 1 || got.Weeks[0].EngagementID != 42 || !got.CreatedAt.Equal(want.CreatedAt) {
+		t.Fatalf("unexpected plan: %+v", got)
+	}
+
+	want.Version = Version + 1
+	if err := Write(path, want); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := Read(path); err == nil || !strings.Contains(err.Error(), "version") {
+		t.Fatalf("expected version error, got %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+// Package queue keeps day edits that could not be sent because the server
+// was unreachable, so they can be synced later.
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/config"
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+)
+
+const fileName = "queue.json"
+
+const (
+	OpSet     = "set"
+	OpMarkDNW = "mark_dnw"
+)
+
+// Entry is one queued day edit. Spans use the --span syntax
+// (type:HH:MM-HH:MM); a nil Notes leaves the day's notes untouched. Profile
+// is empty for the default profile. Baseline is the day as last fetched from
+// the server before the edit was queued, when the connection dropped after
+// the fetch; sync compares against it to detect changes made since.
+type Entry struct {
+	ID           int       `json:"id"`
+	QueuedAt     time.Time `json:"queued_at"`
+	BaseURL      string    `json:"base_url"`
+	Profile      string    `json:"profile,omitempty"`
+	Operation    string    `json:"operation"`
+	EngagementID int64     `json:"engagement_id"`
+	Date         string    `json:"date"`
+	Spans        []string  `json:"spans,omitempty"`
+	DidNotWork   bool      `json:"did_not_work,omitempty"`
+	Notes        *string   `json:"notes,omitempty"`
+	Reason       string    `json:"reason,omitempty"`
+
+	Baseline *timecard.DaySummary `json:"baseline,omitempty"`
+}
+
+// Queue is the on-disk list of pending entries in the order they were
+// queued.
+type Queue struct {
+	NextID  int     `json:"next_id"`
+	Entries []Entry `json:"entries"`
+}
+
+// Path returns the queue file path next to the config file.
+func Path() (string, error) {
+	cfgPath, err := config.ConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(cfgPath), fileName), nil
+}
+
+// Load reads the queue; a missing file is an empty queue.
+func Load(path string) (Queue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Queue{}, nil
+		}
+		return Queue{}, fmt.Errorf("read queue: %w", err)
+	}
+	var q Queue
+	if err := json.Unmarshal(data, &q); err != nil {
+		return Queue{}, fmt.Errorf("parse queue: %w", err)
+	}
+	return q, nil
+}
+
+// Save writes the queue atomically.
+func Save(path string, q Queue) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create queue dir: %w", err)
+	}
+	data, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal queue: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write queue: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("write queue: %w", err)
+	}
+	return nil
+}
+
+// Add assigns e the next ID and appends it. A pending entry for the same
+// server, engagement and date is replaced, since only the latest intent
+// should be synced; replaced reports whether that happened.
+func (q *Queue) Add(e Entry) (added Entry, replaced bool) {
+	if q.NextID < 1 {
+		q.NextID = 1
+	}
+	e.ID = q.NextID
+	q.NextID++
+
+	kept := q.Entries[:0]
+	for _, existing := range q.Entries {
+		if existing.sameTarget(e) {
+			replaced = true
+			continue
+		}
+		kept = append(kept, existing)
+	}
+	q.Entries = append(kept, e)
+	return e, replaced
+}
+
+// Remove drops the entries with the given IDs and returns any IDs that were
+// not queued.
+func (q *Queue) Remove(ids ...int) []int {
+	drop := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		drop[id] = true
+	}
+	kept := q.Entries[:0]
+	for _, e := range q.Entries {
+		if drop[e.ID] {
+			delete(drop, e.ID)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	q.Entries = kept
+
+	var missing []int
+	for _, id := range ids {
+		if drop[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
+func (e Entry) sameTarget(o Entry) bool {
+	return strings.EqualFold(strings.TrimRight(e.BaseURL, "/"), strings.TrimRight(o.BaseURL, "/")) &&
+		e.Profile == o.Profile && e.EngagementID == o.EngagementID && e.Date == o.Date
+}
+
+// Edit converts the entry into a day edit. Source is "queue:<id>" so sync
+// can map changes back to entries.
+func (e Entry) Edit(loc *time.Location) (timecard.DayEdit, error) {
+	date, err := timecard.ParseDateYYYYMMDD(e.Date, loc)
+	if err != nil {
+		return timecard.DayEdit{}, fmt.Errorf("queued entry %d: %w", e.ID, err)
+	}
+	edit := timecard.DayEdit{
+		EngagementID: e.EngagementID,
+		Date:         date,
+		DidNotWork:   e.DidNotWork,
+		Notes:        e.Notes,
+		Source:       fmt.Sprintf("queue:%d", e.ID),
+	}
+	if e.DidNotWork {
+		return edit, nil
+	}
+	spans := make([]timecard.Span, 0, len(e.Spans))
+	for _, arg := range e.Spans {
+		span, err := timecard.ParseSpanArg(arg)
+		if err != nil {
+			return timecard.DayEdit{}, fmt.Errorf("queued entry %d: %w", e.ID, err)
+		}
+		spans = append(spans, span)
+	}
+	edit.Spans, err = timecard.ValidateSpans(spans)
+	if err != nil {
+		return timecard.DayEdit{}, fmt.Errorf("queued entry %d: %w", e.ID, err)
+	}
+	return edit, nil
+}
@@ -0,0 +1,52 @@
+package queue
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAddReplacesSameDayAndRemove(t *testing.T) {
+	var q Queue
+	first, _ := q.Add(Entry{BaseURL: "https://example.com", EngagementID: 1, Date: "2026-02-18", Spans: []string{"labor:09:00-17:00"}})
+	q.Add(Entry{BaseURL: "https://example.com", EngagementID: 1, Date: "2026-02-19", DidNotWork: true})
+	third, replaced := q.Add(Entry{BaseURL: "https://example.com/", EngagementID: 1, Date: "2026-02-18", Spans: []string{"labor:10:00-18:00"}})
+
+	if !replaced || len(q.Entries) != 2 || third.ID != 3 || q.Entries[1].ID != 3 {
+		t.Fatalf("expected entry %d to be replaced by 3, got %+v", first.ID, q.Entries)
+	}
+	if missing := q.Remove(2, 7); len(missing) != 1 || missing[0] != 7 || len(q.Entries) != 1 {
+		t.Fatalf("unexpected remove result %v, entries %+v", missing, q.Entries)
+	}
+}
+
+func TestSaveLoadAndEdit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	empty, err := Load(path)
+	if err != nil || len(empty.Entries) != 0 {
+		t.Fatalf("missing queue should load empty: %+v, %v", empty, err)
+	}
+
+	var q Queue
+	q.Add(Entry{EngagementID: 1, Date: "2026-02-18", Spans: []string{"lunch:12:00-12:30", "labor:09:00-12:00"}})
+	if err := Save(path, q); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil || len(loaded.Entries) != 1 || loaded.NextID != 2 {
+		t.Fatalf("load: %+v, %v", loaded, err)
+	}
+
+	edit, err := loaded.Entries[0].Edit(time.UTC)
+	if err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	if edit.Source != "queue:1" || len(edit.Spans) != 2 || edit.Spans[0].Start != "09:00" {
+		t.Fatalf("unexpected edit %+v", edit)
+	}
+
+	bad := Entry{ID: 9, Date: "2026-02-18", Spans: []string{"labor:09:00-12:00", "labor:11:00-13:00"}}
+	if _, err := bad.Edit(time.UTC); err == nil {
+		t.Fatalf("expected overlapping spans to be rejected")
+	}
+}
@@ -0,0 +1,264 @@
+// Package recorder captures HTTP exchanges to a directory with secrets
+// redacted and serves them back later without touching the network, so a
+// session can be shared and replayed when debugging or writing tests.
+package recorder
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/ihildy/magnit-vms-cli/internal/redact"
+)
+
+// Exchange is one recorded request and its response. Bodies that are not
+// valid UTF-8 are stored base64-encoded.
+type Exchange struct {
+	Method           string      `json:"method"`
+	URL              string      `json:"url"`
+	RequestHeader    http.Header `json:"request_header,omitempty"`
+	RequestBody      string      `json:"request_body,omitempty"`
+	Status           int         `json:"status"`
+	ResponseHeader   http.Header `json:"response_header,omitempty"`
+	ResponseBody     string      `json:"response_body,omitempty"`
+	ResponseEncoding string      `json:"response_encoding,omitempty"`
+}
+
+const (
+	exchangeExt    = ".json"
+	encodingBase64 = "base64"
+)
+
+// Recorder is an http.RoundTripper that forwards requests to Next and writes
+// every exchange to Dir as numbered JSON files.
+type Recorder struct {
+	Dir  string
+	Next http.RoundTripper
+
+	mu    sync.Mutex
+	count int
+}
+
+// NewRecorder creates dir if needed and refuses one that already holds a
+// recording, so two sessions are never mixed.
+func NewRecorder(dir string, next http.RoundTripper) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create record directory: %w", err)
+	}
+	existing, err := exchangeFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		return nil, fmt.Errorf("record directory %s already contains a recording", dir)
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{Dir: dir, Next: next}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read request body: %w", err)
+		}
+		reqBody = data
+		cp := req.Clone(req.Context())
+		cp.Body = io.NopCloser(bytes.NewReader(data))
+		req = cp
+	}
+
+	resp, err := r.Next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	ex := Exchange{
+		Method:         req.Method,
+		URL:            redact.URL(req.URL),
+		RequestHeader:  redact.Header(req.Header),
+		RequestBody:    string(redact.Body(req.Header.Get("Content-Type"), reqBody)),
+		Status:         resp.StatusCode,
+		ResponseHeader: redact.Header(resp.Header),
+	}
+	masked := redact.Body(resp.Header.Get("Content-Type"), respBody)
+	if utf8.Valid(masked) {
+		ex.ResponseBody = string(masked)
+	} else {
+		ex.ResponseBody = base64.StdEncoding.EncodeToString(masked)
+		ex.ResponseEncoding = encodingBase64
+	}
+	if err := r.write(ex); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (r *Recorder) write(ex Exchange) error {
+	data, err := json.MarshalIndent(ex, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode exchange: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.count++
+	path := filepath.Join(r.Dir, fmt.Sprintf("%04d%s", r.count, exchangeExt))
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write exchange: %w", err)
+	}
+	return nil
+}
+
+// Replayer is an http.RoundTripper that answers from a recording. A request
+// matches the first unused exchange with the same method, path and query, so
+// the host may differ from the recording and concurrent requests for
+// different weeks still find their own responses.
+type Replayer struct {
+	mu        sync.Mutex
+	exchanges []Exchange
+	used      []bool
+}
+
+// NewReplayer loads the recording in dir.
+func NewReplayer(dir string) (*Replayer, error) {
+	files, err := exchangeFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("replay directory %s contains no recording", dir)
+	}
+	r := &Replayer{}
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read exchange: %w", err)
+		}
+		var ex Exchange
+		if err := json.Unmarshal(data, &ex); err != nil {
+			return nil, fmt.Errorf("parse exchange %s: %w", path, err)
+		}
+		r.exchanges = append(r.exchanges, ex)
+	}
+	r.used = make([]bool, len(r.exchanges))
+	return r, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	key := requestKey(req.Method, req.URL)
+
+	r.mu.Lock()
+	idx := -1
+	for i, ex := range r.exchanges {
+		if r.used[i] {
+			continue
+		}
+		u, err := url.Parse(ex.URL)
+		if err == nil && requestKey(ex.Method, u) == key {
+			idx = i
+			r.used[i] = true
+			break
+		}
+	}
+	r.mu.Unlock()
+	if idx < 0 {
+		return nil, fmt.Errorf("replay: no recorded response left for %s", key)
+	}
+
+	ex := r.exchanges[idx]
+	body := []byte(ex.ResponseBody)
+	if ex.ResponseEncoding == encodingBase64 {
+		decoded, err := base64.StdEncoding.DecodeString(ex.ResponseBody)
+		if err != nil {
+			return nil, fmt.Errorf("replay: decode response body: %w", err)
+		}
+		body = decoded
+	}
+	header := ex.ResponseHeader.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	rewriteLocation(header, ex.URL, req.URL)
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", ex.Status, http.StatusText(ex.Status)),
+		StatusCode:    ex.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// requestKey ignores scheme and host so a recording can be replayed against
+// another base URL.
+func requestKey(method string, u *url.URL) string {
+	key := strings.ToUpper(method) + " " + u.EscapedPath()
+	if u.RawQuery != "" {
+		key += "?" + redact.Values(u.Query()).Encode()
+	}
+	return key
+}
+
+// rewriteLocation points absolute redirects at the recorded host to the host
+// being replayed against.
+func rewriteLocation(header http.Header, recordedURL string, target *url.URL) {
+	location := header.Get("Location")
+	if location == "" {
+		return
+	}
+	loc, err := url.Parse(location)
+	if err != nil || !loc.IsAbs() {
+		return
+	}
+	recorded, err := url.Parse(recordedURL)
+	if err != nil || !strings.EqualFold(loc.Host, recorded.Host) {
+		return
+	}
+	loc.Scheme = target.Scheme
+	loc.Host = target.Host
+	header.Set("Location", loc.String())
+}
+
+func exchangeFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read recording directory: %w", err)
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), exchangeExt) {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,79 @@
+package recorder
+
+import (
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordThenReplayWithoutNetwork(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login.html":
+			http.SetCookie(w, &http.Cookie{Name: "productionaccess_token", Value: "secret-token", Path: "/"})
+			http.Redirect(w, r, "http://"+r.Host+"/home", http.StatusFound)
+		case "/home":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"week":"` + r.URL.Query().Get("week") + `","accessToken":"secret-token"}`))
+		}
+	}))
+
+	dir := t.TempDir()
+	rec, err := NewRecorder(dir, srv.Client().Transport)
+	if err != nil {
+		t.Fatalf("new recorder: %v", err)
+	}
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Transport: rec, Jar: jar}
+	form := url.Values{"username": {"me"}, "password_login": {"hunter2"}}
+	resp, err := client.PostForm(srv.URL+"/login.html?week=1", form)
+	if err != nil {
+		t.Fatalf("record login: %v", err)
+	}
+	resp.Body.Close()
+	srv.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 2 {
+		t.Fatalf("expected 2 recorded exchanges, got %d", len(files))
+	}
+	for _, f := range files {
+		data, _ := os.ReadFile(f)
+		if strings.Contains(string(data), "hunter2") || strings.Contains(string(data), "secret-token") {
+			t.Fatalf("%s leaks a secret:\n%s", f, data)
+		}
+	}
+	if _, err := NewRecorder(dir, nil); err == nil {
+		t.Fatalf("expected recording into a used directory to fail")
+	}
+
+	replayer, err := NewReplayer(dir)
+	if err != nil {
+		t.Fatalf("new replayer: %v", err)
+	}
+	jar, _ = cookiejar.New(nil)
+	client = &http.Client{Transport: replayer, Jar: jar}
+	resp, err = client.PostForm("http://replay.invalid/login.html?week=1", url.Values{"password_login": {"other"}})
+	if err != nil {
+		t.Fatalf("replay login: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.Request.URL.Host != "replay.invalid" || !strings.Contains(string(body), `"accessToken":"REDACTED"`) {
+		t.Fatalf("unexpected replayed response from %s: %s", resp.Request.URL, body)
+	}
+	u, _ := url.Parse("http://replay.invalid/")
+	if cookies := jar.Cookies(u); len(cookies) != 1 || cookies[0].Value != "REDACTED" {
+		t.Fatalf("expected redacted session cookie, got %v", cookies)
+	}
+
+	if _, err := client.Get("http://replay.invalid/home"); err == nil {
+		t.Fatalf("expected replay to fail once the recording is used up")
+	}
+}
@@ -0,0 +1,189 @@
+// Package redact masks passwords, cookies and tokens in HTTP traffic before
+// it is written anywhere a user might share it.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Placeholder replaces every redacted value.
+const Placeholder = "REDACTED"
+
+var sensitiveParts = []string{"password", "passwd", "secret", "token", "authorization", "apikey", "api_key", "credential", "otp", "passcode"}
+
+// SensitiveName reports whether a header, form field, query parameter or
+// JSON key with this name holds a secret.
+func SensitiveName(name string) bool {
+	lower := strings.ToLower(name)
+	for _, part := range sensitiveParts {
+		if strings.Contains(lower, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// Header returns a copy of h with cookie values, credentials in
+// Authorization headers and other sensitive headers masked. Cookie names and
+// attributes are kept so a replayed session still has the expected shape.
+func Header(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for name, values := range h {
+		masked := make([]string, len(values))
+		for i, v := range values {
+			switch canonical := http.CanonicalHeaderKey(name); {
+			case canonical == "Cookie":
+				masked[i] = cookieHeader(v)
+			case canonical == "Set-Cookie":
+				masked[i] = setCookieHeader(v)
+			case canonical == "Authorization" || canonical == "Proxy-Authorization":
+				masked[i] = authorization(v)
+			case SensitiveName(name):
+				masked[i] = Placeholder
+			default:
+				masked[i] = v
+			}
+		}
+		out[name] = masked
+	}
+	return out
+}
+
+func cookieHeader(v string) string {
+	parts := strings.Split(v, ";")
+	for i, part := range parts {
+		name, _, _ := strings.Cut(strings.TrimSpace(part), "=")
+		parts[i] = name + "=" + Placeholder
+	}
+	return strings.Join(parts, "; ")
+}
+
+func setCookieHeader(v string) string {
+	pair, attrs, hasAttrs := strings.Cut(v, ";")
+	name, _, _ := strings.Cut(strings.TrimSpace(pair), "=")
+	if !hasAttrs {
+		return name + "=" + Placeholder
+	}
+	return name + "=" + Placeholder + ";" + attrs
+}
+
+func authorization(v string) string {
+	if scheme, _, ok := strings.Cut(v, " "); ok {
+		return scheme + " " + Placeholder
+	}
+	return Placeholder
+}
+
+// URL returns u as a string with sensitive query parameters masked.
+func URL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.String()
+	}
+	cp := *u
+	cp.RawQuery = Values(u.Query()).Encode()
+	return cp.String()
+}
+
+// Values returns a copy of v with sensitive fields masked.
+func Values(v url.Values) url.Values {
+	out := make(url.Values, len(v))
+	for name, values := range v {
+		if !SensitiveName(name) {
+			out[name] = append([]string(nil), values...)
+			continue
+		}
+		masked := make([]string, len(values))
+		for i := range masked {
+			masked[i] = Placeholder
+		}
+		out[name] = masked
+	}
+	return out
+}
+
+// Body masks sensitive fields in form-encoded and JSON bodies. Other content
+// types are returned unchanged.
+func Body(contentType string, body []byte) []byte {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return body
+		}
+		return []byte(Values(values).Encode())
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return JSON(body)
+	}
+	return body
+}
+
+// ErrorBody redacts a response body before it is embedded in an error
+// message: structured bodies field by field, anything else with Text.
+func ErrorBody(contentType string, body []byte) string {
+	masked := Body(contentType, body)
+	return Text(strings.TrimSpace(string(masked)))
+}
+
+var (
+	bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)[^\s"',;]+`)
+	assignPattern = regexp.MustCompile(`(?i)([a-z0-9_-]*(?:password|passwd|secret|token|apikey|api_key)[a-z0-9_-]*"?\s*[=:]\s*"?)[^"&\s;,<]+`)
+)
+
+// Text masks bearer tokens and name=value or "name": "value" pairs with a
+// sensitive name in free-form text such as HTML error pages.
+func Text(s string) string {
+	s = bearerPattern.ReplaceAllString(s, "${1}"+Placeholder)
+	return assignPattern.ReplaceAllString(s, "${1}"+Placeholder)
+}
+
+// JSON masks the values of sensitive keys anywhere in a JSON document. Bodies
+// that do not parse are returned unchanged.
+func JSON(body []byte) []byte {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return body
+	}
+	if !maskJSON(doc) {
+		return body
+	}
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+func maskJSON(v any) bool {
+	changed := false
+	switch t := v.(type) {
+	case map[string]any:
+		for k, child := range t {
+			if SensitiveName(k) && child != nil {
+				if _, nested := child.(map[string]any); !nested {
+					t[k] = Placeholder
+					changed = true
+					continue
+				}
+			}
+			if maskJSON(child) {
+				changed = true
+			}
+		}
+	case []any:
+		for _, child := range t {
+			if maskJSON(child) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
@@ -0,0 +1,75 @@
+package redact
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHeaderMasksCookiesAndCredentials(t *testing.T) {
+	h := http.Header{
+		"Cookie":        {"productionaccess_token=abc; XSRF-TOKEN=xyz"},
+		"Set-Cookie":    {"productionaccess_token=abc; Path=/; HttpOnly"},
+		"Authorization": {"Bearer abc"},
+		"X-Xsrf-Token":  {"xyz"},
+		"Accept":        {"application/json"},
+	}
+	got := Header(h)
+
+	want := map[string]string{
+		"Cookie":        "productionaccess_token=REDACTED; XSRF-TOKEN=REDACTED",
+		"Set-Cookie":    "productionaccess_token=REDACTED; Path=/; HttpOnly",
+		"Authorization": "Bearer REDACTED",
+		"X-Xsrf-Token":  "REDACTED",
+		"Accept":        "application/json",
+	}
+	for name, value := range want {
+		if got.Get(name) != value {
+			t.Fatalf("%s: got %q, want %q", name, got.Get(name), value)
+		}
+	}
+	if h.Get("Authorization") != "Bearer abc" {
+		t.Fatalf("input header was modified")
+	}
+}
+
+func TestBodyMasksFormAndJSON(t *testing.T) {
+	form := Body("application/x-www-form-urlencoded", []byte("username=me%40example.com&password_login=hunter2"))
+	values, err := url.ParseQuery(string(form))
+	if err != nil {
+		t.Fatalf("parse form: %v", err)
+	}
+	if values.Get("password_login") != Placeholder || values.Get("username") != "me@example.com" {
+		t.Fatalf("unexpected form %q", form)
+	}
+
+	body := JSON([]byte(`{"user":{"name":"x","accessToken":"abc"},"items":[{"clientSecret":"s","hours":8}]}`))
+	if strings.Contains(string(body), "abc") || strings.Contains(string(body), `"s"`) || !strings.Contains(string(body), `"hours":8`) {
+		t.Fatalf("unexpected json %s", body)
+	}
+
+	html := []byte("<input name=password_login>")
+	if got := Body("text/html; charset=utf-8", html); string(got) != string(html) {
+		t.Fatalf("html body changed: %s", got)
+	}
+}
+
+func TestURLMasksSensitiveQuery(t *testing.T) {
+	u, _ := url.Parse("https://example.com/sso?token=abc&next=%2Fhome")
+	if got := URL(u); got != "https://example.com/sso?next=%2Fhome&token=REDACTED" {
+		t.Fatalf("unexpected url %s", got)
+	}
+}
+
+func TestErrorBodyMasksFreeText(t *testing.T) {
+	got := ErrorBody("text/html", []byte(`<p>Authorization: Bearer abc.def</p><input name="password_login" value="x"> XSRF-TOKEN=xyz; path=/`))
+	for _, secret := range []string{"abc.def", "xyz"} {
+		if strings.Contains(got, secret) {
+			t.Fatalf("%q leaks %q", got, secret)
+		}
+	}
+	if !strings.Contains(got, "XSRF-TOKEN=REDACTED") {
+		t.Fatalf("unexpected redaction %q", got)
+	}
+}
@@ -0,0 +1,145 @@
+package timecard
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DayEdit is one intended change to a day: either a full set of spans or a
+// did-not-work mark, optionally with notes. A nil Notes leaves existing notes
+// untouched. Source records where the edit came from (e.g. "hours.csv:12") so
+// errors can point back at the input.
+type DayEdit struct {
+	EngagementID int64
+	Date         time.Time
+	Spans        []Span
+	DidNotWork   bool
+	Notes        *string
+	Source       string
+}
+
+// WeekEdits groups the edits that land in one weekly timecard.
+type WeekEdits struct {
+	EngagementID int64
+	WeekStart    time.Time
+	Edits        []DayEdit
+}
+
+// ApplyDayEdit patches the edit's day in metadata, including notes, and
+// returns the patched copy along with the resulting change.
+func ApplyDayEdit(metadata map[string]any, edit DayEdit) (map[string]any, DayChange, error) {
+	patched, change, err := PatchDay(metadata, edit.Date, edit.Spans, edit.DidNotWork)
+	if err != nil {
+		return nil, DayChange{}, err
+	}
+	if edit.Notes == nil {
+		return patched, change, nil
+	}
+
+	targetMDY := FormatMDY(edit.Date)
+	details, _ := anyToSlice(patched["billingItemDetails"])
+	for _, d := range details {
+		detail, ok := anyToMap(d)
+		if !ok || strings.TrimSpace(anyToString(detail["workedDate"])) != targetMDY {
+			continue
+		}
+		timeEntry, _ := anyToMap(detail["timeEntry"])
+		if timeEntry == nil {
+			timeEntry = map[string]any{}
+			detail["timeEntry"] = timeEntry
+		}
+		timeEntry["notes"] = *edit.Notes
+		change.Proposed = extractDaySummary(detail, targetMDY)
+		break
+	}
+	return patched, change, nil
+}
+
+// GroupEditsByWeek orders edits by engagement, week and date. Two edits for
+// the same engagement and day are rejected since the later one would silently
+// win.
+func GroupEditsByWeek(edits []DayEdit) ([]WeekEdits, error) {
+	sorted := append([]DayEdit(nil), edits...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].EngagementID != sorted[j].EngagementID {
+			return sorted[i].EngagementID < sorted[j].EngagementID
+		}
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+
+	var out []WeekEdits
+	seen := map[string]string{}
+	for _, edit := range sorted {
+		key := fmt.Sprintf("%d/%s", edit.EngagementID, edit.Date.Format("2006-01-02"))
+		if prev, ok := seen[key]; ok {
+			return nil, fmt.Errorf("duplicate entry for %s (engagement %d) at %s and %s",
+				edit.Date.Format("2006-01-02"), edit.EngagementID, prev, edit.Source)
+		}
+		seen[key] = edit.Source
+
+		weekStart := WeekStartMonday(edit.Date)
+		if n := len(out); n > 0 && out[n-1].EngagementID == edit.EngagementID && out[n-1].WeekStart.Equal(weekStart) {
+			out[n-1].Edits = append(out[n-1].Edits, edit)
+			continue
+		}
+		out = append(out, WeekEdits{EngagementID: edit.EngagementID, WeekStart: weekStart, Edits: []DayEdit{edit}})
+	}
+	return out, nil
+}
+
+// RestoreEdits returns the edits that put every day of current back to how
+// it looks in original. Days that already match are skipped. Restoring on top
+// of freshly fetched metadata keeps the server's current IDs intact.
+func RestoreEdits(original, current map[string]any) ([]DayEdit, error) {
+	want, err := WeekDaySummaries(original)
+	if err != nil {
+		return nil, fmt.Errorf("original: %w", err)
+	}
+	have, err := WeekDaySummaries(current)
+	if err != nil {
+		return nil, fmt.Errorf("current: %w", err)
+	}
+	currentByDate := make(map[string]DaySummary, len(have))
+	for _, d := range have {
+		currentByDate[d.WorkedDate] = d
+	}
+
+	var edits []DayEdit
+	for _, d := range want {
+		if cur, ok := currentByDate[d.WorkedDate]; ok && cur.Equal(d) {
+			continue
+		}
+		date, err := time.Parse("01/02/2006", d.WorkedDate)
+		if err != nil {
+			return nil, fmt.Errorf("original day %q: %w", d.WorkedDate, err)
+		}
+		notes := d.Notes
+		edit := DayEdit{Date: date, DidNotWork: d.DidNotWork, Notes: &notes}
+		if !d.DidNotWork {
+			for _, s := range d.Spans {
+				span, err := ParseSpanArg(s.Type + ":" + s.Start + "-" + s.End)
+				if err != nil {
+					return nil, fmt.Errorf("original day %s: %w", d.WorkedDate, err)
+				}
+				edit.Spans = append(edit.Spans, span)
+			}
+		}
+		edits = append(edits, edit)
+	}
+	return edits, nil
+}
+
+// Equal reports whether two summaries describe the same day contents.
+func (a DaySummary) Equal(b DaySummary) bool {
+	if a.DidNotWork != b.DidNotWork || a.Notes != b.Notes || len(a.Spans) != len(b.Spans) {
+		return false
+	}
+	for i := range a.Spans {
+		if a.Spans[i] != b.Spans[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+package timecard
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// SpanHours returns the length of a single span in hours, or 0 when the span
+// times cannot be parsed.
+func SpanHours(s SpanSummary) float64 {
+	start, err := parseHHMM(s.Start)
+	if err != nil {
+		return 0
+	}
+	end, err := parseHHMM(s.End)
+	if err != nil || end < start {
+		return 0
+	}
+	return float64(end-start) / 60.0
+}
+
+// FormatDuration renders fractional hours as e.g. "7h30m".
+func FormatDuration(hours float64) string {
+	minutes := int(math.Round(hours * 60))
+	sign := ""
+	if minutes < 0 {
+		sign = "-"
+		minutes = -minutes
+	}
+	return fmt.Sprintf("%s%dh%02dm", sign, minutes/60, minutes%60)
+}
+
+// ParseWorkedDate accepts the API's MM/DD/YYYY form as well as YYYY-MM-DD.
+func ParseWorkedDate(s string, loc *time.Location) (time.Time, error) {
+	value := strings.TrimSpace(s)
+	if t, err := time.ParseInLocation("01/02/2006", value, loc); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, expected MM/DD/YYYY or YYYY-MM-DD", s)
+}
@@ -0,0 +1,234 @@
+package timecard
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Interval is a timestamped block of work or lunch from an external source
+// such as a calendar or time tracker.
+type Interval struct {
+	Type   string
+	Start  time.Time
+	End    time.Time
+	Source string
+}
+
+// IntervalOptions tunes how raw intervals become spans. MergeGap joins labor
+// separated by at most that gap; a gap between labor of LunchGapMin to
+// LunchGapMax becomes a lunch span when the day has no explicit lunch.
+type IntervalOptions struct {
+	MergeGap    time.Duration
+	LunchGapMin time.Duration
+	LunchGapMax time.Duration
+}
+
+// IntervalsToEdits converts intervals into one DayEdit per local day. Labor
+// intervals that overlap or touch are merged, lunch intervals are carved out
+// of the labor they overlap, and intervals crossing midnight are rejected
+// because a span cannot leave its day. An interval ending exactly at midnight
+// ends at 23:59, the last time a span can have.
+func IntervalsToEdits(intervals []Interval, loc *time.Location, opts IntervalOptions) ([]DayEdit, error) {
+	type dayBucket struct {
+		date    time.Time
+		labor   [][2]int
+		lunch   [][2]int
+		sources []string
+	}
+	buckets := map[string]*dayBucket{}
+	var problems []string
+
+	for _, iv := range intervals {
+		start := iv.Start.In(loc).Round(time.Minute)
+		end := iv.End.In(loc).Round(time.Minute)
+		if !end.After(start) {
+			continue
+		}
+		startDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+		endDay := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, loc)
+		endMinutes := end.Hour()*60 + end.Minute()
+		if end.Equal(endDay) && endDay.Equal(startDay.AddDate(0, 0, 1)) {
+			endDay, endMinutes = startDay, 23*60+59
+		}
+		if !startDay.Equal(endDay) {
+			problems = append(problems, fmt.Sprintf("%s: %s %s-%s crosses midnight", iv.Source, iv.Type, start.Format("2006-01-02 15:04"), end.Format("2006-01-02 15:04")))
+			continue
+		}
+
+		key := startDay.Format("2006-01-02")
+		b, ok := buckets[key]
+		if !ok {
+			b = &dayBucket{date: startDay}
+			buckets[key] = b
+		}
+		r := [2]int{start.Hour()*60 + start.Minute(), endMinutes}
+		switch iv.Type {
+		case SpanTypeLunch:
+			b.lunch = append(b.lunch, r)
+		default:
+			b.labor = append(b.labor, r)
+		}
+		if iv.Source != "" {
+			b.sources = append(b.sources, iv.Source)
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("cannot convert intervals:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	keys := make([]string, 0, len(buckets))
+	for k := range buckets {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	edits := make([]DayEdit, 0, len(keys))
+	for _, k := range keys {
+		b := buckets[k]
+		labor := closeGaps(mergeRanges(b.labor), int(opts.MergeGap/time.Minute))
+		lunch := mergeRanges(b.lunch)
+		if len(labor) == 0 {
+			// Lunch alone does not make a working day.
+			continue
+		}
+		lunch = clipRanges(lunch, labor)
+		labor = subtractRanges(labor, lunch)
+		if len(lunch) == 0 && opts.LunchGapMax > 0 {
+			lunch = lunchFromGap(labor, int(opts.LunchGapMin/time.Minute), int(opts.LunchGapMax/time.Minute))
+		}
+
+		spans := make([]Span, 0, len(labor)+len(lunch))
+		for _, r := range labor {
+			spans = append(spans, newSpan(SpanTypeLabor, r[0], r[1]))
+		}
+		for _, r := range lunch {
+			spans = append(spans, newSpan(SpanTypeLunch, r[0], r[1]))
+		}
+		validated, err := ValidateSpans(spans)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+		edits = append(edits, DayEdit{
+			Date:   b.date,
+			Spans:  validated,
+			Source: strings.Join(uniqueStrings(b.sources), ", "),
+		})
+	}
+	return edits, nil
+}
+
+func newSpan(spanType string, startMinutes, endMinutes int) Span {
+	return Span{
+		Type:         spanType,
+		Start:        formatHHMM(startMinutes),
+		End:          formatHHMM(endMinutes),
+		startMinutes: startMinutes,
+		endMinutes:   endMinutes,
+	}
+}
+
+func formatHHMM(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// mergeRanges sorts minute ranges and joins those that overlap or touch.
+func mergeRanges(ranges [][2]int) [][2]int {
+	if len(ranges) == 0 {
+		return nil
+	}
+	sorted := append([][2]int(nil), ranges...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i][0] < sorted[j][0] })
+	out := [][2]int{sorted[0]}
+	for _, r := range sorted[1:] {
+		last := &out[len(out)-1]
+		if r[0] <= last[1] {
+			if r[1] > last[1] {
+				last[1] = r[1]
+			}
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// closeGaps joins merged ranges whose gap is at most maxGap minutes.
+func closeGaps(ranges [][2]int, maxGap int) [][2]int {
+	if len(ranges) == 0 || maxGap <= 0 {
+		return ranges
+	}
+	out := [][2]int{ranges[0]}
+	for _, r := range ranges[1:] {
+		last := &out[len(out)-1]
+		if r[0]-last[1] <= maxGap {
+			last[1] = r[1]
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// lunchFromGap turns the first gap between labor ranges whose length is
+// within [minGap, maxGap] minutes into a lunch range.
+func lunchFromGap(labor [][2]int, minGap, maxGap int) [][2]int {
+	for i := 1; i < len(labor); i++ {
+		gap := labor[i][0] - labor[i-1][1]
+		if gap >= minGap && gap <= maxGap {
+			return [][2]int{{labor[i-1][1], labor[i][0]}}
+		}
+	}
+	return nil
+}
+
+func clipRanges(ranges, bounds [][2]int) [][2]int {
+	var out [][2]int
+	for _, r := range ranges {
+		for _, b := range bounds {
+			start, end := max(r[0], b[0]), min(r[1], b[1])
+			if end > start {
+				out = append(out, [2]int{start, end})
+			}
+		}
+	}
+	return mergeRanges(out)
+}
+
+// subtractRanges removes holes from ranges; both inputs must be merged.
+func subtractRanges(ranges, holes [][2]int) [][2]int {
+	var out [][2]int
+	for _, r := range ranges {
+		cur := r
+		for _, h := range holes {
+			if h[1] <= cur[0] || h[0] >= cur[1] {
+				continue
+			}
+			if h[0] > cur[0] {
+				out = append(out, [2]int{cur[0], h[0]})
+			}
+			cur[0] = h[1]
+			if cur[0] >= cur[1] {
+				break
+			}
+		}
+		if cur[1] > cur[0] {
+			out = append(out, cur)
+		}
+	}
+	return out
+}
+
+func uniqueStrings(in []string) []string {
+	seen := map[string]struct{}{}
+	var out []string
+	for _, s := range in {
+		if _, ok := seen[s]; ok {
+			continue
+		}
+		seen[s] = struct{}{}
+		out = append(out, s)
+	}
+	return out
+}
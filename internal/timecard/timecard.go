@@ -32,6 +32,7 @@ type DaySummary struct {
 	WorkedDate string        `json:"worked_date"`
 	DidNotWork bool          `json:"did_not_work"`
 	Spans      []SpanSummary `json:"spans"`
+	Notes      string        `json:"notes,omitempty"`
 }
 
 type DayChange struct {
@@ -228,6 +229,37 @@ func FindDaySummary(metadata map[string]any, targetDate time.Time) (DaySummary,
 	return DaySummary{}, fmt.Errorf("date %s not found", targetMDY)
 }
 
+func WeekDaySummaries(metadata map[string]any) ([]DaySummary, error) {
+	details, ok := anyToSlice(metadata["billingItemDetails"])
+	if !ok {
+		return nil, fmt.Errorf("metadata missing billingItemDetails")
+	}
+	out := make([]DaySummary, 0, len(details))
+	for _, d := range details {
+		detail, ok := anyToMap(d)
+		if !ok {
+			continue
+		}
+		workedDate := strings.TrimSpace(anyToString(detail["workedDate"]))
+		if workedDate == "" {
+			continue
+		}
+		out = append(out, extractDaySummary(detail, workedDate))
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return mdyKey(out[i].WorkedDate) < mdyKey(out[j].WorkedDate)
+	})
+	return out, nil
+}
+
+func WeekStartsBetween(from, to time.Time) []time.Time {
+	var out []time.Time
+	for week := WeekStartMonday(from); !week.After(to); week = week.AddDate(0, 0, 7) {
+		out = append(out, week)
+	}
+	return out
+}
+
 func FormatDaySummaryHuman(d DaySummary) string {
 	if d.DidNotWork {
 		return fmt.Sprintf("%s: did not work", d.WorkedDate)
@@ -290,6 +322,9 @@ func extractDaySummary(detail map[string]any, fallbackDate string) DaySummary {
 	if wd := anyToString(detail["workedDate"]); wd != "" {
 		summary.WorkedDate = wd
 	}
+	if timeEntry, ok := anyToMap(detail["timeEntry"]); ok {
+		summary.Notes = anyToString(timeEntry["notes"])
+	}
 
 	spans, ok := anyToSlice(detail["timeEntrySpanDtos"])
 	if !ok {
@@ -385,6 +420,14 @@ func containsLunch(spans []Span) bool {
 	return false
 }
 
+func mdyKey(s string) string {
+	parts := strings.Split(strings.TrimSpace(s), "/")
+	if len(parts) != 3 {
+		return s
+	}
+	return parts[2] + parts[0] + parts[1]
+}
+
 func tailTime(s string) string {
 	parts := strings.Fields(s)
 	if len(parts) == 0 {
@@ -74,3 +74,69 @@ func TestPatchDayReplacesTargetOnly(t *testing.T) {
 		t.Fatalf("change metadata inconsistent")
 	}
 }
+
+func TestGroupEditsByWeekRejectsDuplicates(t *testing.T) {
+	mon, _ := time.ParseInLocation("2006-01-02", "2026-02-16", time.UTC)
+	nextMon := mon.AddDate(0, 0, 7)
+
+	groups, err := GroupEditsByWeek([]DayEdit{
+		{EngagementID: 1, Date: nextMon, DidNotWork: true, Source: "a:3"},
+		{EngagementID: 1, Date: mon.AddDate(0, 0, 2), DidNotWork: true, Source: "a:2"},
+		{EngagementID: 1, Date: mon, DidNotWork: true, Source: "a:1"},
+	})
+	if err != nil {
+		t.Fatalf("group edits: %v", err)
+	}
+	if len(groups) != 2 || len(groups[0].Edits) != 2 || !groups[0].WeekStart.Equal(mon) {
+		t.Fatalf("unexpected grouping: %+v", groups)
+	}
+
+	_, err = GroupEditsByWeek([]DayEdit{
+		{EngagementID: 1, Date: mon, DidNotWork: true, Source: "a:1"},
+		{EngagementID: 1, Date: mon, DidNotWork: true, Source: "a:5"},
+	})
+	if err == nil {
+		t.Fatalf("expected duplicate day error")
+	}
+}
+
+func TestApplyDayEditSetsNotes(t *testing.T) {
+	metadata := map[string]any{
+		"billingItemDetails": []any{
+			map[string]any{"workedDate": "02/18/2026", "timeEntry": map[string]any{"notes": "old"}},
+		},
+	}
+	target, _ := time.ParseInLocation("2006-01-02", "2026-02-18", time.UTC)
+	notes := "new"
+
+	patched, change, err := ApplyDayEdit(metadata, DayEdit{Date: target, DidNotWork: true, Notes: &notes})
+	if err != nil {
+		t.Fatalf("apply edit: %v", err)
+	}
+	if change.Existing.Notes != "old" || change.Proposed.Notes != "new" {
+		t.Fatalf("unexpected notes change: %+v", change)
+	}
+	detail := patched["billingItemDetails"].([]any)[0].(map[string]any)
+	if detail["timeEntry"].(map[string]any)["notes"] != "new" {
+		t.Fatalf("notes not patched")
+	}
+}
+
+func TestIntervalsToEditsRejectsMidnightCrossing(t *testing.T) {
+	start := time.Date(2026, 2, 18, 22, 0, 0, 0, time.UTC)
+	_, err := IntervalsToEdits([]Interval{{Type: SpanTypeLabor, Start: start, End: start.Add(3 * time.Hour), Source: "x:1"}}, time.UTC, IntervalOptions{})
+	if err == nil {
+		t.Fatalf("expected midnight crossing error")
+	}
+}
+
+func TestIntervalsToEditsEndsAtMidnight(t *testing.T) {
+	start := time.Date(2026, 2, 18, 16, 0, 0, 0, time.UTC)
+	edits, err := IntervalsToEdits([]Interval{{Type: SpanTypeLabor, Start: start, End: start.Add(8 * time.Hour), Source: "x:1"}}, time.UTC, IntervalOptions{})
+	if err != nil {
+		t.Fatalf("expected an interval ending at midnight to be accepted: %v", err)
+	}
+	if len(edits) != 1 || edits[0].Date.Day() != 18 || edits[0].Spans[0].End != "23:59" {
+		t.Fatalf("unexpected edits: %+v", edits)
+	}
+}
@@ -0,0 +1,95 @@
+// Package trace logs HTTP traffic for --verbose and --trace with passwords,
+// cookies and tokens redacted.
+package trace
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/redact"
+)
+
+const maxBody = 4096
+
+// Transport logs the method, redacted URL, status and duration of every
+// request through Logf. With Bodies set it also logs redacted headers and
+// up to 4 KB of each body.
+type Transport struct {
+	Next   http.RoundTripper
+	Logf   func(format string, args ...any)
+	Bodies bool
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	target := redact.URL(req.URL)
+
+	if t.Bodies {
+		t.Logf("> %s %s", req.Method, target)
+		t.logHeaders(">", req.Header)
+		if req.Body != nil && req.Body != http.NoBody {
+			data, err := io.ReadAll(req.Body)
+			req.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			cp := req.Clone(req.Context())
+			cp.Body = io.NopCloser(bytes.NewReader(data))
+			req = cp
+			t.logBody(">", req.Header.Get("Content-Type"), data)
+		}
+	}
+
+	start := time.Now()
+	resp, err := next.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		t.Logf("%s %s -> error: %s (%s)", req.Method, target, redact.Text(err.Error()), elapsed)
+		return nil, err
+	}
+	t.Logf("%s %s -> %s (%s)", req.Method, target, resp.Status, elapsed)
+
+	if t.Bodies {
+		t.logHeaders("<", resp.Header)
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		t.logBody("<", resp.Header.Get("Content-Type"), data)
+	}
+	return resp, nil
+}
+
+func (t *Transport) logHeaders(prefix string, h http.Header) {
+	masked := redact.Header(h)
+	names := make([]string, 0, len(masked))
+	for name := range masked {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range masked[name] {
+			t.Logf("%s %s: %s", prefix, name, v)
+		}
+	}
+}
+
+func (t *Transport) logBody(prefix, contentType string, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	body := redact.ErrorBody(contentType, data)
+	if len(body) > maxBody {
+		body = body[:maxBody] + "... (truncated)"
+	}
+	t.Logf("%s %s", prefix, body)
+}
@@ -0,0 +1,69 @@
+package trace
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestTransportLogsRedactedTraffic(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "productionaccess_token", Value: "tok-123", Path: "/"})
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true,"accessToken":"tok-123"}`))
+	}))
+	defer srv.Close()
+
+	var lines []string
+	logf := func(format string, args ...any) { lines = append(lines, fmt.Sprintf(format, args...)) }
+	client := &http.Client{Transport: &Transport{Next: srv.Client().Transport, Logf: logf, Bodies: true}}
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/login.html", strings.NewReader(url.Values{"username": {"me"}, "password_login": {"hunter2"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer secret-bearer")
+	req.Header.Set("x-xsrf-token", "xsrf-456")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "tok-123") {
+		t.Fatalf("response body must reach the caller unchanged, got %s", body)
+	}
+
+	log := strings.Join(lines, "\n")
+	for _, secret := range []string{"hunter2", "secret-bearer", "xsrf-456", "tok-123"} {
+		if strings.Contains(log, secret) {
+			t.Fatalf("trace leaks %q:\n%s", secret, log)
+		}
+	}
+	if !strings.Contains(log, "POST "+srv.URL+"/login.html -> 200 OK") || !strings.Contains(log, "username=me") {
+		t.Fatalf("unexpected trace:\n%s", log)
+	}
+}
+
+func TestTransportVerboseSkipsBodies(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("missing"))
+	}))
+	defer srv.Close()
+
+	var lines []string
+	logf := func(format string, args ...any) { lines = append(lines, fmt.Sprintf(format, args...)) }
+	client := &http.Client{Transport: &Transport{Next: srv.Client().Transport, Logf: logf}}
+	resp, err := client.Get(srv.URL + "/x?token=abc")
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+
+	if len(lines) != 1 || !strings.Contains(lines[0], "GET "+srv.URL+"/x?token=REDACTED -> 404 Not Found") {
+		t.Fatalf("unexpected log %q", lines)
+	}
+}
@@ -0,0 +1,154 @@
+// Package txn saves several weekly timecards as one unit: weeks are saved in
+// order and, if one fails, the weeks already saved are restored from the
+// snapshots taken before the transaction started.
+package txn
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/api"
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+)
+
+// Client is the part of api.Client a transaction uses.
+type Client interface {
+	GetMetadata(ctx context.Context, engagementID int64, selectedDateMDY string) (map[string]any, error)
+	SaveBillingItems(ctx context.Context, payload map[string]any, xsrfToken string) (api.SaveBillingItemsResponse, error)
+}
+
+// Week is one weekly save. Original is the metadata as fetched before any
+// change and is what a rollback restores.
+type Week struct {
+	EngagementID int64
+	WeekStart    time.Time
+	Original     map[string]any
+	Patched      map[string]any
+}
+
+const (
+	StatusCommitted      = "committed"
+	StatusFailed         = "failed"
+	StatusRolledBack     = "rolled_back"
+	StatusRollbackFailed = "rollback_failed"
+	StatusNotAttempted   = "not_attempted"
+	StatusUnknown        = "unknown"
+)
+
+const rollbackTimeout = 2 * time.Minute
+
+// Outcome reports what happened to one week.
+type Outcome struct {
+	EngagementID  int64  `json:"engagement_id"`
+	WeekStart     string `json:"week_start"`
+	Status        string `json:"status"`
+	BillingItemID int64  `json:"billing_item_id,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// Error is returned when a week fails to save. Outcomes holds the final
+// state of every week in the transaction.
+type Error struct {
+	Outcomes []Outcome
+	Cause    error
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "transaction failed: %v", e.Cause)
+	for _, o := range e.Outcomes {
+		fmt.Fprintf(&b, "\n  week of %s (engagement %d): %s", o.WeekStart, o.EngagementID, strings.ReplaceAll(o.Status, "_", " "))
+		if o.Error != "" {
+			fmt.Fprintf(&b, ": %s", o.Error)
+		}
+	}
+	return b.String()
+}
+
+func (e *Error) Unwrap() error { return e.Cause }
+
+// Save saves weeks in order. On the first failure the failed week and every
+// week committed so far are rolled back in reverse order by refetching them
+// and restoring each changed day from its snapshot, since a save that timed
+// out may still have been applied. The returned *Error lists which weeks were
+// committed, rolled back, failed, left in an unknown state or never attempted.
+func Save(ctx context.Context, client Client, xsrf string, weeks []Week) ([]Outcome, error) {
+	outcomes := make([]Outcome, len(weeks))
+	for i, w := range weeks {
+		outcomes[i] = Outcome{EngagementID: w.EngagementID, WeekStart: timecard.FormatMDY(w.WeekStart), Status: StatusNotAttempted}
+	}
+
+	for i, w := range weeks {
+		id, err := saveWeek(ctx, client, xsrf, w.Patched)
+		if err == nil {
+			outcomes[i].Status = StatusCommitted
+			outcomes[i].BillingItemID = id
+			continue
+		}
+
+		outcomes[i].Status = StatusFailed
+		outcomes[i].Error = err.Error()
+		// The save may have failed because ctx was cancelled or timed out;
+		// the rollback must still run.
+		rbCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+		if restored, rbErr := rollback(rbCtx, client, xsrf, w); rbErr != nil {
+			outcomes[i].Status = StatusUnknown
+			outcomes[i].Error = fmt.Sprintf("%v; could not check whether the save was applied: %v", err, rbErr)
+		} else if restored {
+			outcomes[i].Status = StatusRolledBack
+		}
+		for j := i - 1; j >= 0; j-- {
+			if _, rbErr := rollback(rbCtx, client, xsrf, weeks[j]); rbErr != nil {
+				outcomes[j].Status = StatusRollbackFailed
+				outcomes[j].Error = rbErr.Error()
+				continue
+			}
+			outcomes[j].Status = StatusRolledBack
+		}
+		cancel()
+		cause := fmt.Errorf("save week of %s (engagement %d): %w", outcomes[i].WeekStart, w.EngagementID, err)
+		return outcomes, &Error{Outcomes: outcomes, Cause: cause}
+	}
+	return outcomes, nil
+}
+
+func saveWeek(ctx context.Context, client Client, xsrf string, payload map[string]any) (int64, error) {
+	resp, err := client.SaveBillingItems(ctx, payload, xsrf)
+	if err != nil {
+		return 0, err
+	}
+	if resp.Errors != nil || resp.BillingItemDetailErr != nil {
+		return 0, fmt.Errorf("save API returned validation errors")
+	}
+	return resp.BillingItemID, nil
+}
+
+// rollback restores w.Original on top of the week as it is now, so server
+// IDs assigned by the committed save are kept. It reports whether any day had
+// to be restored.
+func rollback(ctx context.Context, client Client, xsrf string, w Week) (bool, error) {
+	current, err := client.GetMetadata(ctx, w.EngagementID, timecard.FormatMDY(w.WeekStart))
+	if err != nil {
+		return false, fmt.Errorf("refetch for rollback: %w", err)
+	}
+	edits, err := timecard.RestoreEdits(w.Original, current)
+	if err != nil {
+		return false, fmt.Errorf("compute rollback: %w", err)
+	}
+	if len(edits) == 0 {
+		return false, nil
+	}
+	restored := current
+	for _, edit := range edits {
+		restored, _, err = timecard.ApplyDayEdit(restored, edit)
+		if err != nil {
+			return false, fmt.Errorf("compute rollback: %w", err)
+		}
+	}
+	if _, err := saveWeek(ctx, client, xsrf, restored); err != nil {
+		return false, fmt.Errorf("save rollback: %w", err)
+	}
+	return true, nil
+}
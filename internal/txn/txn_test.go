@@ -0,0 +1,194 @@
+package txn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ihildy/magnit-vms-cli/internal/api"
+	"github.com/ihildy/magnit-vms-cli/internal/timecard"
+)
+
+// fakeClient keeps one metadata document per week start and can be told to
+// fail the first save of a given week, optionally after applying it.
+type fakeClient struct {
+	weeks       map[string]map[string]any
+	failOn      string
+	applyOnFail bool
+	failRefetch bool
+	failed      bool
+	onFail      func()
+	saves       []string
+}
+
+func (f *fakeClient) GetMetadata(ctx context.Context, _ int64, selectedDateMDY string) (map[string]any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if f.failRefetch && selectedDateMDY == f.failOn {
+		return nil, errors.New("refetch failed with status 502")
+	}
+	return clone(f.weeks[selectedDateMDY]), nil
+}
+
+func (f *fakeClient) SaveBillingItems(ctx context.Context, payload map[string]any, _ string) (api.SaveBillingItemsResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return api.SaveBillingItemsResponse{}, err
+	}
+	week, _ := payload["selectedDate"].(string)
+	f.saves = append(f.saves, week)
+	if week == f.failOn && !f.failed {
+		f.failed = true
+		if f.applyOnFail {
+			f.weeks[week] = clone(payload)
+		}
+		if f.onFail != nil {
+			f.onFail()
+		}
+		return api.SaveBillingItemsResponse{}, errors.New("save failed with status 500")
+	}
+	f.weeks[week] = clone(payload)
+	return api.SaveBillingItemsResponse{BillingItemID: 7}, nil
+}
+
+func clone(in map[string]any) map[string]any {
+	data, _ := json.Marshal(in)
+	var out map[string]any
+	_ = json.Unmarshal(data, &out)
+	return out
+}
+
+func weekMetadata(start time.Time) map[string]any {
+	details := make([]any, 0, 7)
+	for i := 0; i < 7; i++ {
+		details = append(details, map[string]any{"workedDate": timecard.FormatMDY(start.AddDate(0, 0, i))})
+	}
+	return map[string]any{"selectedDate": timecard.FormatMDY(start), "billingItemDetails": details}
+}
+
+func patchedWeek(t *testing.T, metadata map[string]any, day time.Time) map[string]any {
+	t.Helper()
+	span, err := timecard.ParseSpanArg("labor:09:00-17:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	patched, _, err := timecard.PatchDay(metadata, day, []timecard.Span{span}, false)
+	if err != nil {
+		t.Fatalf("patch day: %v", err)
+	}
+	return patched
+}
+
+func TestSaveRollsBackCommittedWeeksOnFailure(t *testing.T) {
+	w1 := time.Date(2026, 2, 9, 0, 0, 0, 0, time.UTC)
+	w2 := w1.AddDate(0, 0, 7)
+	w3 := w2.AddDate(0, 0, 7)
+	client := &fakeClient{weeks: map[string]map[string]any{}, failOn: timecard.FormatMDY(w2)}
+	var weeks []Week
+	for _, start := range []time.Time{w1, w2, w3} {
+		original := weekMetadata(start)
+		client.weeks[timecard.FormatMDY(start)] = clone(original)
+		weeks = append(weeks, Week{EngagementID: 42, WeekStart: start, Original: original, Patched: patchedWeek(t, original, start)})
+	}
+
+	outcomes, err := Save(context.Background(), client, "xsrf", weeks)
+	var txErr *Error
+	if !errors.As(err, &txErr) {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+	got := []string{outcomes[0].Status, outcomes[1].Status, outcomes[2].Status}
+	want := []string{StatusRolledBack, StatusFailed, StatusNotAttempted}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("statuses = %v, want %v", got, want)
+		}
+	}
+
+	summary, err := timecard.FindDaySummary(client.weeks[timecard.FormatMDY(w1)], w1)
+	if err != nil {
+		t.Fatalf("find day: %v", err)
+	}
+	if len(summary.Spans) != 0 {
+		t.Fatalf("week 1 was not restored: %+v", summary)
+	}
+	if len(client.saves) != 3 {
+		t.Fatalf("expected save, failed save and rollback save, got %v", client.saves)
+	}
+}
+
+func TestSaveCommitsAllWeeks(t *testing.T) {
+	w1 := time.Date(2026, 2, 9, 0, 0, 0, 0, time.UTC)
+	client := &fakeClient{weeks: map[string]map[string]any{}}
+	original := weekMetadata(w1)
+	client.weeks[timecard.FormatMDY(w1)] = clone(original)
+
+	outcomes, err := Save(context.Background(), client, "xsrf", []Week{{EngagementID: 42, WeekStart: w1, Original: original, Patched: patchedWeek(t, original, w1)}})
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if outcomes[0].Status != StatusCommitted || outcomes[0].BillingItemID != 7 {
+		t.Fatalf("unexpected outcome: %+v", outcomes[0])
+	}
+}
+
+func TestSaveRollsBackAfterCancellation(t *testing.T) {
+	w1 := time.Date(2026, 2, 9, 0, 0, 0, 0, time.UTC)
+	w2 := w1.AddDate(0, 0, 7)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := &fakeClient{weeks: map[string]map[string]any{}, failOn: timecard.FormatMDY(w2), onFail: cancel}
+	var weeks []Week
+	for _, start := range []time.Time{w1, w2} {
+		original := weekMetadata(start)
+		client.weeks[timecard.FormatMDY(start)] = clone(original)
+		weeks = append(weeks, Week{EngagementID: 42, WeekStart: start, Original: original, Patched: patchedWeek(t, original, start)})
+	}
+
+	outcomes, err := Save(ctx, client, "xsrf", weeks)
+	if err == nil {
+		t.Fatalf("expected the transaction to fail")
+	}
+	if outcomes[0].Status != StatusRolledBack {
+		t.Fatalf("expected week 1 to be rolled back despite cancellation, got %+v", outcomes[0])
+	}
+}
+
+func TestSaveRestoresFailedWeekThatWasApplied(t *testing.T) {
+	w1 := time.Date(2026, 2, 9, 0, 0, 0, 0, time.UTC)
+	client := &fakeClient{weeks: map[string]map[string]any{}, failOn: timecard.FormatMDY(w1), applyOnFail: true}
+	original := weekMetadata(w1)
+	client.weeks[timecard.FormatMDY(w1)] = clone(original)
+
+	outcomes, err := Save(context.Background(), client, "xsrf", []Week{{EngagementID: 42, WeekStart: w1, Original: original, Patched: patchedWeek(t, original, w1)}})
+	if err == nil {
+		t.Fatalf("expected the transaction to fail")
+	}
+	if outcomes[0].Status != StatusRolledBack {
+		t.Fatalf("expected the applied week to be rolled back, got %+v", outcomes[0])
+	}
+	summary, err := timecard.FindDaySummary(client.weeks[timecard.FormatMDY(w1)], w1)
+	if err != nil {
+		t.Fatalf("find day: %v", err)
+	}
+	if len(summary.Spans) != 0 {
+		t.Fatalf("failed week was not restored: %+v", summary)
+	}
+}
+
+func TestSaveReportsUnknownStateWhenFailedWeekCannotBeChecked(t *testing.T) {
+	w1 := time.Date(2026, 2, 9, 0, 0, 0, 0, time.UTC)
+	client := &fakeClient{weeks: map[string]map[string]any{}, failOn: timecard.FormatMDY(w1), failRefetch: true}
+	original := weekMetadata(w1)
+	client.weeks[timecard.FormatMDY(w1)] = clone(original)
+
+	outcomes, err := Save(context.Background(), client, "xsrf", []Week{{EngagementID: 42, WeekStart: w1, Original: original, Patched: patchedWeek(t, original, w1)}})
+	if err == nil || !strings.Contains(err.Error(), "unknown") {
+		t.Fatalf("expected the error to report an unknown state, got %v", err)
+	}
+	if outcomes[0].Status != StatusUnknown {
+		t.Fatalf("expected an unknown state, got %+v", outcomes[0])
+	}
+}